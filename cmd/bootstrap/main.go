@@ -0,0 +1,62 @@
+// Command bootstrap writes a starter packages/{name}/config.yaml from PyPI
+// metadata, upstream tags and the package's build backend.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/dlorenc/superwheelie/pkg/bootstrap"
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/pypi"
+)
+
+func main() {
+	packagesDir := flag.String("packages-dir", "packages", "directory holding package configs")
+	repo := flag.String("repo", "", "source repository (default: discovered from PyPI metadata)")
+	count := flag.Int("version-count", config.DefaultVersionCount, "number of versions to include")
+	cacheDir := flag.String("pypi-cache", "", "directory to cache PyPI responses in")
+	force := flag.Bool("force", false, "overwrite an existing config")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bootstrap [flags] <package>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	name := flag.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	res, err := bootstrap.Generate(ctx, name, bootstrap.Options{
+		PyPI:         &pypi.Client{CacheDir: *cacheDir},
+		Repo:         *repo,
+		VersionCount: *count,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bootstrap: %v\n", err)
+		os.Exit(1)
+	}
+
+	path, err := bootstrap.Write(*packagesDir, name, res.Config, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bootstrap: %v\n", err)
+		os.Exit(1)
+	}
+
+	backend := res.Backend
+	if res.BackendErr != nil {
+		fmt.Fprintf(os.Stderr, "bootstrap: detecting build backend: %v\n", res.BackendErr)
+	}
+	if backend == "" {
+		backend = "unknown"
+	}
+	fmt.Printf("wrote %s: %d versions, backend %s\n", path, len(res.Config.Versions), backend)
+}
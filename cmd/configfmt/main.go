@@ -0,0 +1,139 @@
+// Command configfmt formats and lints package configs.
+//
+//	configfmt fmt [-check] [path...]
+//	configfmt lint [-check] [-dockerfile Dockerfile] [path...]
+//
+// Each path is a package directory, a packages directory, or a
+// config.yaml or skips.yaml file (default: packages). With -check, fmt
+// lists files that aren't canonical without rewriting them, and both
+// exit with status 1 if anything is found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/configfmt"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var found bool
+	var err error
+	switch os.Args[1] {
+	case "fmt":
+		found, err = runFmt(os.Args[2:])
+	case "lint":
+		found, err = runLint(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "configfmt: %v\n", err)
+		os.Exit(1)
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: configfmt fmt [-check] [path...]")
+	fmt.Fprintln(os.Stderr, "       configfmt lint [-check] [-dockerfile Dockerfile] [path...]")
+	os.Exit(2)
+}
+
+// runFmt formats files and reports whether check mode found any that
+// aren't canonical.
+func runFmt(args []string) (bool, error) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := fs.Bool("check", false, "list files that aren't canonical instead of rewriting them")
+	fs.Parse(args)
+
+	files, err := packageFiles(fs.Args(), configfmt.ConfigFile, configfmt.SkipsFile)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, path := range files {
+		changed, err := configfmt.FormatFile(path, *check)
+		if err != nil {
+			return false, err
+		}
+		if changed {
+			fmt.Println(path)
+			found = found || *check
+		}
+	}
+	return found, nil
+}
+
+// runLint lints configs and reports whether check mode found anything.
+func runLint(args []string) (bool, error) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	check := fs.Bool("check", false, "exit with status 1 if there are findings")
+	dockerfile := fs.String("dockerfile", "Dockerfile", "build image Dockerfile listing the base image packages")
+	fs.Parse(args)
+
+	var baseImage []string
+	if *dockerfile != "" {
+		data, err := os.ReadFile(*dockerfile)
+		if err != nil {
+			return false, fmt.Errorf("reading Dockerfile: %w", err)
+		}
+		baseImage = configfmt.DockerfilePackages(data)
+	}
+
+	files, err := packageFiles(fs.Args(), configfmt.ConfigFile)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, path := range files {
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			return false, err
+		}
+		for _, f := range configfmt.Lint(cfg, baseImage) {
+			fmt.Printf("%s: %s\n", path, f)
+			found = found || *check
+		}
+	}
+	return found, nil
+}
+
+// packageFiles expands paths into the named package files they contain.
+func packageFiles(paths []string, names ...string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"packages"}
+	}
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		for _, name := range names {
+			for _, pattern := range []string{filepath.Join(p, name), filepath.Join(p, "*", name)} {
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, matches...)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,62 @@
+// Command gc garbage collects stale claims on the claims branch and
+// requeues packages that were never built.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/claims"
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/gc"
+)
+
+func main() {
+	remote := flag.String("remote", "", "superwheelie repository URL (required)")
+	branch := flag.String("branch", gc.DefaultMainBranch, "main branch holding packages/ and queue.txt")
+	workDir := flag.String("work-dir", filepath.Join(os.TempDir(), "superwheelie-gc"), "scratch directory for the claims repository")
+	agent := flag.String("agent", "superwheelie-gc", "name recorded in commits")
+	dryRun := flag.Bool("dry-run", false, "report expired claims without deleting or requeuing anything")
+	pr := flag.Bool("pr", false, "requeue packages via a pull request instead of a direct commit")
+	noRequeue := flag.Bool("no-requeue", false, "only delete claims; don't touch queue.txt")
+	buildTTL := flag.Duration("ttl-build", gc.DefaultTTLs[config.ClaimTypeBuild], "TTL for build claims")
+	versionTTL := flag.Duration("ttl-version", gc.DefaultTTLs[config.ClaimTypeVersion], "TTL for version claims")
+	fixerTTL := flag.Duration("ttl-fixer", gc.DefaultTTLs[config.ClaimTypeFixer], "TTL for fixer claims")
+	flag.Parse()
+
+	if *remote == "" {
+		fmt.Fprintln(os.Stderr, "gc: --remote is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	collector := &gc.Collector{
+		Claims: claims.New(*remote, *workDir, *agent),
+		TTLs: map[string]time.Duration{
+			config.ClaimTypeBuild:   *buildTTL,
+			config.ClaimTypeVersion: *versionTTL,
+			config.ClaimTypeFixer:   *fixerTTL,
+		},
+		DryRun: *dryRun,
+	}
+	if !*noRequeue {
+		collector.Queue = &gc.Requeuer{Remote: *remote, Branch: *branch, Author: *agent, PR: *pr}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := collector.Run(ctx)
+	if report != nil {
+		report.Write(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,212 @@
+// Package bootstrap generates a starter config for a new package from its
+// PyPI metadata, upstream tags and build backend, so agents begin from a
+// sensible baseline instead of an empty file.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/builder"
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/pypi"
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// ErrExists is returned by Write when the package already has a config.
+var ErrExists = errors.New("config already exists")
+
+// extraTagPatterns are tried after versions.DefaultTagPatterns; "{name}"
+// is replaced by the project name.
+var extraTagPatterns = []string{
+	"release-{version}",
+	"{name}-{version}",
+	"{name}-v{version}",
+	"{name}_{version}",
+}
+
+// Options configure Generate.
+type Options struct {
+	// PyPI fetches project metadata (default: a client for pypi.org).
+	PyPI *pypi.Client
+
+	// Repo overrides the source repository found in the PyPI metadata.
+	Repo string
+
+	// VersionCount is the number of versions to include
+	// (default: config.DefaultVersionCount).
+	VersionCount int
+}
+
+// Result is a generated config and how it was derived.
+type Result struct {
+	Config *config.Config
+
+	// Backend is the detected build backend of the newest version, or ""
+	// if it isn't a known one or couldn't be detected.
+	Backend string
+
+	// BackendErr is why the backend couldn't be detected, if it wasn't.
+	BackendErr error
+}
+
+// Generate builds a starter config for a package: the first source
+// repository from PyPI whose tags match released versions and the newest
+// VersionCount of those versions. If the newest version's build backend
+// has a profile (builder.BackendProfiles), the config selects it with
+// backend; the builder applies the profile's system deps and env at build
+// time, so they aren't copied into the config.
+func Generate(ctx context.Context, name string, opts Options) (*Result, error) {
+	client := opts.PyPI
+	if client == nil {
+		client = &pypi.Client{}
+	}
+	project, err := client.Project(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := project.SourceRepos()
+	if opts.Repo != "" {
+		repos = []string{opts.Repo}
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("%s: no source repository in PyPI metadata", name)
+	}
+
+	released := project.Versions()
+	var errs []error
+	for _, repo := range repos {
+		cfg, err := configFor(ctx, repo, project.Info.Name, released, opts.VersionCount)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// The config is usable without knowing the backend
+		backend, err := detectBackend(ctx, repo, cfg.Versions[0].Tag)
+		if _, ok := builder.BackendProfiles[backend]; ok {
+			cfg.Backend = backend
+		}
+		return &Result{Config: cfg, Backend: backend, BackendErr: err}, nil
+	}
+	return nil, fmt.Errorf("%s: no usable source repository: %w", name, errors.Join(errs...))
+}
+
+// configFor returns a config for repo with the tag pattern that matches
+// the most released versions.
+func configFor(ctx context.Context, repo, name string, released []string, count int) (*config.Config, error) {
+	tags, err := versions.ListTags(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *config.Config
+	bestMatches := 0
+	for _, pattern := range tagPatterns(name) {
+		cfg := &config.Config{Repo: repo, TagPattern: pattern, VersionCount: count}
+		matches := 0
+		for _, r := range versions.Releases(tags, cfg) {
+			if isReleased(r.Version, released) {
+				matches++
+			}
+		}
+		if matches > bestMatches {
+			best, bestMatches = cfg, matches
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%s: no tags match released versions", repo)
+	}
+
+	// Keep only versions published to PyPI; other tags may mark unreleased
+	// branch points or other components.
+	var releases []versions.Release
+	for _, r := range versions.Releases(tags, best) {
+		if isReleased(r.Version, released) {
+			releases = append(releases, r)
+		}
+	}
+
+	best.Versions = versions.New(releases, best)
+	for _, p := range versions.DefaultTagPatterns {
+		if best.TagPattern == p {
+			best.TagPattern = ""
+		}
+	}
+	if best.VersionCount == config.DefaultVersionCount {
+		best.VersionCount = 0
+	}
+	return best, nil
+}
+
+// tagPatterns returns the tag patterns to try for a project.
+func tagPatterns(name string) []string {
+	patterns := append([]string{}, versions.DefaultTagPatterns...)
+	names := []string{name}
+	if n := config.NormalizeName(name); n != name {
+		names = append(names, n)
+	}
+	for _, p := range extraTagPatterns {
+		for _, n := range names {
+			patterns = append(patterns, strings.ReplaceAll(p, "{name}", n))
+		}
+	}
+	return patterns
+}
+
+// isReleased reports whether v is one of the released versions.
+func isReleased(v versions.Version, released []string) bool {
+	for _, r := range released {
+		if versions.Compare(r, v.String()) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// detectBackend checks out tag and detects its build backend.
+func detectBackend(ctx context.Context, repo, tag string) (string, error) {
+	dir, err := os.MkdirTemp("", "superwheelie-bootstrap-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	cmd := exec.CommandContext(ctx, "git", "clone", "-q", "--depth", "1", "--branch", tag, repo, src)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cloning %s at %s: %w\n%s", repo, tag, err, out)
+	}
+
+	pp, err := pep517.LoadPyProject(src)
+	if err != nil {
+		return "", err
+	}
+	return pep517.DetectBackend(pp), nil
+}
+
+// Write saves a generated config as packages/{name}/config.yaml. It
+// returns ErrExists rather than overwrite an existing config unless force
+// is set.
+func Write(packagesDir, name string, cfg *config.Config, force bool) (string, error) {
+	path := filepath.Join(packagesDir, config.NormalizeName(name), "config.yaml")
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("%s: %w", path, ErrExists)
+		}
+	}
+	if err := config.ValidateConfig(cfg, ""); err != nil {
+		return "", fmt.Errorf("generated config is invalid: %w", err)
+	}
+	if err := config.SaveConfig(cfg, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,212 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/pypi"
+)
+
+const mesonPyProject = `[build-system]
+requires = ["meson-python"]
+build-backend = "mesonpy"
+`
+
+// newRepo creates a repository with a commit per tag, each writing
+// pyproject to pyproject.toml.
+func newRepo(t *testing.T, pyproject string, tags ...string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "repo")
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	for _, tag := range tags {
+		if err := os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(pyproject+"# "+tag+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "pyproject.toml")
+		run("commit", "-q", "-m", tag)
+		run("tag", tag)
+	}
+	return dir
+}
+
+// newPyPI returns a client serving a fixture for a project with the given
+// released versions.
+func newPyPI(t *testing.T, name, repo string, released ...string) *pypi.Client {
+	t.Helper()
+	p := pypi.Project{
+		Info: pypi.Info{
+			Name:        name,
+			ProjectURLs: map[string]string{"Source": repo},
+		},
+		Releases: make(map[string][]pypi.File),
+	}
+	for _, v := range released {
+		p.Releases[v] = []pypi.File{{Filename: name + "-" + v + ".tar.gz", PackageType: pypi.PackageTypeSdist}}
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, config.NormalizeName(name)+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &pypi.Client{FixtureDir: dir}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		pyproject   string
+		tags        []string
+		released    []string
+		count       int
+		wantPattern string
+		wantTags    []string
+		wantBackend string
+		wantProfile string
+	}{
+		{
+			name:        "default pattern",
+			project:     "demo",
+			pyproject:   mesonPyProject,
+			tags:        []string{"v1.0.0", "v1.1.0", "v2.0.0"},
+			released:    []string{"1.0.0", "1.1.0", "2.0.0"},
+			count:       2,
+			wantTags:    []string{"v2.0.0", "v1.1.0"},
+			wantBackend: pep517.BackendMesonPython,
+			wantProfile: pep517.BackendMesonPython,
+		},
+		{
+			name:        "project-prefixed tags",
+			project:     "demo",
+			pyproject:   "",
+			tags:        []string{"other-3.0.0", "demo-1.0.0", "demo-1.1.0"},
+			released:    []string{"1.0.0", "1.1.0"},
+			wantPattern: "demo-{version}",
+			wantTags:    []string{"demo-1.1.0", "demo-1.0.0"},
+			wantBackend: pep517.BackendSetuptools,
+		},
+		{
+			name:        "unreleased tags skipped",
+			project:     "demo",
+			pyproject:   mesonPyProject,
+			tags:        []string{"v1.0.0", "v1.1.0", "v9.0.0"},
+			released:    []string{"1.0.0", "1.1.0"},
+			wantTags:    []string{"v1.1.0", "v1.0.0"},
+			wantBackend: pep517.BackendMesonPython,
+			wantProfile: pep517.BackendMesonPython,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t, tt.pyproject, tt.tags...)
+			client := newPyPI(t, tt.project, "https://github.com/example/demo", tt.released...)
+
+			res, err := Generate(context.Background(), tt.project, Options{PyPI: client, Repo: repo, VersionCount: tt.count})
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			cfg := res.Config
+			if cfg.Repo != repo {
+				t.Errorf("Repo = %q, want %q", cfg.Repo, repo)
+			}
+			if cfg.TagPattern != tt.wantPattern {
+				t.Errorf("TagPattern = %q, want %q", cfg.TagPattern, tt.wantPattern)
+			}
+			var tags []string
+			for _, v := range cfg.Versions {
+				tags = append(tags, v.Tag)
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("version tags = %v, want %v", tags, tt.wantTags)
+			}
+			if res.Backend != tt.wantBackend {
+				t.Errorf("Backend = %q, want %q", res.Backend, tt.wantBackend)
+			}
+			if cfg.Backend != tt.wantProfile {
+				t.Errorf("config backend = %q, want %q", cfg.Backend, tt.wantProfile)
+			}
+			// Backend defaults are applied at build time, not copied in
+			if cfg.SystemDeps != nil || cfg.Env != nil || cfg.ConfigSettings != nil {
+				t.Errorf("config has backend defaults: system_deps %v, env %v, config_settings %v",
+					cfg.SystemDeps, cfg.Env, cfg.ConfigSettings)
+			}
+		})
+	}
+}
+
+func TestGenerateUnknownBackend(t *testing.T) {
+	repo := newRepo(t, "[build-system\n", "v1.0.0")
+	client := newPyPI(t, "demo", "https://github.com/example/demo", "1.0.0")
+
+	res, err := Generate(context.Background(), "demo", Options{PyPI: client, Repo: repo})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if res.Backend != "" || res.BackendErr == nil {
+		t.Errorf("Backend = %q, BackendErr = %v; want no backend and an error", res.Backend, res.BackendErr)
+	}
+	if _, err := Write(t.TempDir(), "demo", res.Config, false); err != nil {
+		t.Errorf("Write() error = %v", err)
+	}
+}
+
+func TestGenerateNoMatchingTags(t *testing.T) {
+	repo := newRepo(t, "", "nightly")
+	client := newPyPI(t, "demo", "https://github.com/example/demo", "1.0.0")
+
+	if _, err := Generate(context.Background(), "demo", Options{PyPI: client, Repo: repo}); err == nil {
+		t.Fatal("Generate() error = nil, want error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:     "https://github.com/example/demo",
+		Versions: []config.Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+	}
+
+	path, err := Write(dir, "Demo_Pkg", cfg, false)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := filepath.Join(dir, "demo-pkg", "config.yaml"); path != want {
+		t.Errorf("Write() path = %q, want %q", path, want)
+	}
+	got, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Repo != cfg.Repo || len(got.Versions) != 1 {
+		t.Errorf("LoadConfig() = %+v", got)
+	}
+
+	if _, err := Write(dir, "demo-pkg", cfg, false); !errors.Is(err, ErrExists) {
+		t.Errorf("Write() existing error = %v, want ErrExists", err)
+	}
+	if _, err := Write(dir, "demo-pkg", cfg, true); err != nil {
+		t.Errorf("Write(force) error = %v", err)
+	}
+}
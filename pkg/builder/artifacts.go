@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ArtifactStore holds wheels built for a cell hash, so unchanged cells
+// aren't rebuilt.
+type ArtifactStore interface {
+	// Lookup returns the location of the wheel built for hash, or "" if
+	// there is none.
+	Lookup(hash string) (string, error)
+
+	// Put stores the wheel built for hash and returns its location.
+	Put(hash, wheelPath string) (string, error)
+}
+
+// LocalArtifactStore is an ArtifactStore in a local directory, laid out
+// as {Dir}/{hash[:2]}/{hash}/{wheel}.
+type LocalArtifactStore struct {
+	// Dir is the store root.
+	Dir string
+}
+
+// Lookup returns the wheel stored for hash, or "".
+func (s *LocalArtifactStore) Lookup(hash string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir(hash), "*.whl"))
+	if err != nil {
+		return "", fmt.Errorf("looking up artifact: %w", err)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0], nil
+}
+
+// Put copies a wheel into the store. The copy is renamed into place so
+// concurrent lookups never see a partial file.
+func (s *LocalArtifactStore) Put(hash, wheelPath string) (string, error) {
+	dir := s.dir(hash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating artifact directory: %w", err)
+	}
+
+	src, err := os.Open(wheelPath)
+	if err != nil {
+		return "", fmt.Errorf("storing artifact: %w", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("storing artifact: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("storing artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("storing artifact: %w", err)
+	}
+
+	dest := filepath.Join(dir, filepath.Base(wheelPath))
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", fmt.Errorf("storing artifact: %w", err)
+	}
+	return dest, nil
+}
+
+func (s *LocalArtifactStore) dir(hash string) string {
+	return filepath.Join(s.Dir, hash[:2], hash)
+}
@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+)
+
+// BackendProfile is the build environment a build backend usually needs.
+type BackendProfile struct {
+	// SystemDeps are APK packages providing the backend's toolchain.
+	SystemDeps []string
+
+	// Env contains environment variables for the backend's tools.
+	Env map[string]string
+
+	// ConfigSettings are PEP 517 config_settings for the backend.
+	ConfigSettings map[string]string
+}
+
+// BackendProfiles are the known profiles by backend (see pep517.DetectBackend).
+// Backends that need nothing beyond the base image have no entry. The
+// applied profile is part of the cell hash, so changing one invalidates
+// the cells it applies to.
+var BackendProfiles = map[string]BackendProfile{
+	pep517.BackendMesonPython: {
+		SystemDeps: []string{"meson", "ninja-build", "pkgconf"},
+		// Log compiler commands, as the other backends do
+		ConfigSettings: map[string]string{"compile-args": "-v"},
+	},
+	pep517.BackendScikitBuildCore: {
+		SystemDeps: []string{"cmake", "ninja-build"},
+	},
+	pep517.BackendMaturin: {
+		SystemDeps: []string{"rust"},
+		Env:        map[string]string{"CARGO_INCREMENTAL": "0", "CARGO_TERM_COLOR": "never"},
+	},
+	pep517.BackendSetuptoolsRust: {
+		SystemDeps: []string{"rust"},
+		Env:        map[string]string{"CARGO_INCREMENTAL": "0", "CARGO_TERM_COLOR": "never"},
+	},
+}
+
+// detectBackend returns the build backend of the checked-out source, or ""
+// if it isn't a known one or pyproject.toml can't be read. A backend set
+// in the config is used without detection.
+func (b *Builder) detectBackend() string {
+	if b.Config.Backend != "" {
+		return b.Config.Backend
+	}
+	pp, err := pep517.LoadPyProject(b.SourceDir)
+	if err != nil {
+		return ""
+	}
+	return pep517.DetectBackend(pp)
+}
+
+// backendAt returns the build backend of commit, the resolution of ref,
+// without checking it out. Like detectBackend, an unreadable
+// pyproject.toml has no known backend.
+func (b *Builder) backendAt(ref, commit string) (string, error) {
+	if b.Config.Backend != "" {
+		return b.Config.Backend, nil
+	}
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = b.SourceDir
+		return cmd.Output()
+	}
+
+	// A shallow clone may not have fetched the commit yet
+	if _, err := git("cat-file", "-e", commit+"^{commit}"); err != nil {
+		if err := b.fetchRef(ref); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := git("ls-tree", "--name-only", commit, "--", "pyproject.toml")
+	if err != nil {
+		return "", fmt.Errorf("listing %s: %w", ref, err)
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return pep517.DetectBackend(nil), nil
+	}
+	data, err := git("cat-file", "blob", commit+":pyproject.toml")
+	if err != nil {
+		return "", fmt.Errorf("reading pyproject.toml at %s: %w", ref, err)
+	}
+	pp, err := pep517.ParsePyProject(data)
+	if err != nil {
+		return "", nil
+	}
+	return pep517.DetectBackend(pp), nil
+}
+
+// applyBackendProfile adds a backend's defaults to an effective config.
+// The config wins: env vars and config settings it sets are kept, and a
+// system dep it declares (e.g., pinned "rust=1.75.0-r0") replaces the
+// profile's entry for the same package.
+func applyBackendProfile(cfg *effectiveConfig, backend string) {
+	cfg.Backend = backend
+	profile, ok := BackendProfiles[backend]
+	if !ok {
+		return
+	}
+
+	declared := make(map[string]bool, len(cfg.SystemDeps))
+	for _, dep := range cfg.SystemDeps {
+		declared[apkPackageName(dep)] = true
+	}
+	var deps []string
+	for _, dep := range profile.SystemDeps {
+		if !declared[apkPackageName(dep)] {
+			deps = append(deps, dep)
+		}
+	}
+	cfg.SystemDeps = append(deps, cfg.SystemDeps...)
+
+	for k, v := range profile.Env {
+		if _, ok := cfg.Env[k]; !ok {
+			cfg.Env[k] = v
+		}
+	}
+	for k, v := range profile.ConfigSettings {
+		if _, ok := cfg.ConfigSettings[k]; !ok {
+			cfg.ConfigSettings[k] = v
+		}
+	}
+}
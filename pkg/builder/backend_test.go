@@ -0,0 +1,126 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestApplyBackendProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		backend      string
+		cfg          *config.Config
+		wantDeps     []string
+		wantEnv      map[string]string
+		wantSettings map[string]string
+	}{
+		{
+			name:         "no profile",
+			backend:      pep517.BackendHatchling,
+			cfg:          &config.Config{SystemDeps: []string{"libffi-dev"}},
+			wantDeps:     []string{"libffi-dev"},
+			wantEnv:      map[string]string{},
+			wantSettings: map[string]string{},
+		},
+		{
+			name:         "defaults added",
+			backend:      pep517.BackendMaturin,
+			cfg:          &config.Config{SystemDeps: []string{"openssl-dev"}},
+			wantDeps:     []string{"rust", "openssl-dev"},
+			wantEnv:      map[string]string{"CARGO_INCREMENTAL": "0", "CARGO_TERM_COLOR": "never"},
+			wantSettings: map[string]string{},
+		},
+		{
+			name:    "config wins",
+			backend: pep517.BackendMesonPython,
+			cfg: &config.Config{
+				SystemDeps:     []string{"meson=1.3.0-r0"},
+				ConfigSettings: map[string]string{"compile-args": "-j4"},
+			},
+			wantDeps:     []string{"ninja-build", "pkgconf", "meson=1.3.0-r0"},
+			wantEnv:      map[string]string{},
+			wantSettings: map[string]string{"compile-args": "-j4"},
+		},
+		{
+			name:    "override wins",
+			backend: pep517.BackendSetuptoolsRust,
+			cfg: &config.Config{
+				Overrides: []config.Override{{Match: ">=1.0", Env: map[string]string{"CARGO_INCREMENTAL": "1"}}},
+			},
+			wantDeps:     []string{"rust"},
+			wantEnv:      map[string]string{"CARGO_INCREMENTAL": "1", "CARGO_TERM_COLOR": "never"},
+			wantSettings: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eff, err := New("/tmp/build", "testpkg", tt.cfg).getEffectiveConfig("1.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			applyBackendProfile(eff, tt.backend)
+			if !reflect.DeepEqual(eff.SystemDeps, tt.wantDeps) {
+				t.Errorf("SystemDeps = %v, want %v", eff.SystemDeps, tt.wantDeps)
+			}
+			if !reflect.DeepEqual(eff.Env, tt.wantEnv) {
+				t.Errorf("Env = %v, want %v", eff.Env, tt.wantEnv)
+			}
+			if !reflect.DeepEqual(eff.ConfigSettings, tt.wantSettings) {
+				t.Errorf("ConfigSettings = %v, want %v", eff.ConfigSettings, tt.wantSettings)
+			}
+		})
+	}
+
+	// The profile itself isn't modified
+	if deps := BackendProfiles[pep517.BackendMesonPython].SystemDeps; len(deps) != 3 {
+		t.Errorf("meson-python profile deps = %v", deps)
+	}
+}
+
+func TestBuildReportsBackend(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "init", "-q")
+	pyproject := "[build-system]\nrequires = [\"hatchling\"]\nbuild-backend = \"hatchling.build\"\n"
+	if err := os.WriteFile(filepath.Join(upstream, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "-q", "-m", "v1.0.0")
+	runGit(t, upstream, "tag", "v1.0.0")
+
+	cfg := &config.Config{
+		Repo:   "file://" + upstream,
+		Script: `touch ../dist/testpkg-1.0.0-cp312-cp312-linux_x86_64.whl`,
+	}
+	b := New(t.TempDir(), "testpkg", cfg)
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Build() = %+v, want one success", results)
+	}
+	if got := results[0].Backend; got != pep517.BackendHatchling {
+		t.Errorf("Backend = %q, want %q", got, pep517.BackendHatchling)
+	}
+
+	// A backend set in the config replaces detection
+	cfg.Backend = pep517.BackendFlit
+	results = b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || results[0].Backend != pep517.BackendFlit {
+		t.Errorf("Build() with backend set = %+v, want backend %q", results, pep517.BackendFlit)
+	}
+}
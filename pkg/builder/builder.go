@@ -3,13 +3,29 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
 	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/gitcache"
+	"github.com/dlorenc/superwheelie/pkg/pypi"
+)
+
+// Build frontends.
+const (
+	// FrontendPEP517 builds with the native PEP 517 frontend (default).
+	FrontendPEP517 = "pep517"
+
+	// FrontendPip builds with "pip wheel".
+	FrontendPip = "pip"
 )
 
 // Builder orchestrates wheel builds for a package.
@@ -28,6 +44,65 @@ type Builder struct {
 
 	// DistDir is the directory where wheels are output.
 	DistDir string
+
+	// Cache is an optional shared mirror cache. When set, the source is
+	// checked out as a worktree of a cached mirror instead of a fresh clone.
+	Cache *gitcache.Cache
+
+	// APKRoot is the root filesystem that system deps are installed into
+	// (default: "/").
+	APKRoot string
+
+	// CleanRoom resets system deps to BaseWorld before each build and fails
+	// builds that install undeclared system packages.
+	CleanRoom bool
+
+	// BaseWorld is the apk world of the pristine build environment.
+	// If nil, it is captured before the first build.
+	BaseWorld []string
+
+	// Executor runs build commands (default: DefaultExecutor).
+	Executor Executor
+
+	// Network controls network access during builds (default: unrestricted).
+	Network *NetworkPolicy
+
+	// RecordBuildRequires captures the build requirement versions installed
+	// by pip into BuildResult.BuildRequires.
+	RecordBuildRequires bool
+
+	// Frontend selects how wheels are built: FrontendPEP517 (default) or
+	// FrontendPip. Ignored when the config sets a script.
+	Frontend string
+
+	// Resources are the default per-build resource limits. Package config
+	// and overrides replace individual fields.
+	Resources config.Resources
+
+	// Artifacts is an optional store of previously built wheels. When set,
+	// BuildAll skips cells whose CellHash already has a wheel.
+	Artifacts ArtifactStore
+
+	// Platform is the platform builds run on, part of the cell hash
+	// (default: the host, e.g., "linux_aarch64").
+	Platform string
+
+	// ImageDigest identifies the build image, part of the cell hash.
+	ImageDigest string
+
+	// PyPI supplies Requires-Python for versions whose source doesn't
+	// declare it (optional).
+	PyPI *pypi.Client
+
+	// CargoHome is a local cargo home whose registry cache Rust builds
+	// resolve crates from (default for vendoring: $CARGO_HOME).
+	CargoHome string
+
+	// CargoVendorDir holds vendored crate tarballs named
+	// {package}-{version}.tar.gz, each the contents of a cargo vendor
+	// directory. With rust.cargo_vendor, they are used instead of
+	// running cargo vendor.
+	CargoVendorDir string
 }
 
 // BuildResult contains the result of building a single version/Python combination.
@@ -49,6 +124,45 @@ type BuildResult struct {
 
 	// Error contains any error that occurred.
 	Error error
+
+	// SystemDeps are the resolved system deps (e.g., "openssl-dev=3.1.0-r0").
+	SystemDeps []string
+
+	// FailureClass categorizes a failed build (e.g., FailureNetwork).
+	FailureClass string
+
+	// BuildRequires are the exact build requirements installed by pip
+	// (e.g., "setuptools==69.0.3"), if recording was enabled.
+	BuildRequires []string
+
+	// PeakRSS is the peak resident memory of the build in bytes.
+	PeakRSS int64
+
+	// CPUTime is the user plus system CPU time of the build.
+	CPUTime time.Duration
+
+	// DiskUsage is the size of the build directories after the build, in bytes.
+	DiskUsage int64
+
+	// Duration is the wall-clock time of the build.
+	Duration time.Duration
+
+	// CellHash is the hash of the build inputs, if it could be computed.
+	CellHash string
+
+	// Cached indicates the wheel was reused from the artifact store.
+	Cached bool
+
+	// NotApplicable indicates the version's Requires-Python excludes the
+	// Python version, so the cell wasn't built. It is not a failure.
+	NotApplicable bool
+
+	// RequiresPython is the version's Requires-Python specifier, if known.
+	RequiresPython string
+
+	// Backend is the detected build backend (e.g., "maturin"), or "" if
+	// it isn't a known one.
+	Backend string
 }
 
 // New creates a new Builder for a package.
@@ -78,6 +192,10 @@ func (b *Builder) CloneSource() error {
 		return fmt.Errorf("no repo URL configured")
 	}
 
+	if b.Cache != nil {
+		return b.Cache.AddWorktree(b.Config.Repo, b.SourceDir, "HEAD")
+	}
+
 	cmd := exec.Command("git", "clone", "--depth", "1", b.Config.Repo, b.SourceDir)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -88,32 +206,73 @@ func (b *Builder) CloneSource() error {
 
 // Checkout checks out a specific tag/ref in the source directory.
 func (b *Builder) Checkout(ref string) error {
-	cmd := exec.Command("git", "fetch", "--depth", "1", "origin", "tag", ref)
+	if b.Cache != nil {
+		return b.checkoutCached(ref)
+	}
+
+	if err := b.fetchRef(ref); err != nil {
+		return err
+	}
+
+	// Force discards patches applied for the previous version
+	cmd := exec.Command("git", "checkout", "--force", "FETCH_HEAD")
 	cmd.Dir = b.SourceDir
 	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("checking out %s: %w\n%s", ref, err, output)
+	}
+
+	if err := b.UpdateSubmodules(); err != nil {
+		return err
+	}
+
+	// Clean any untracked files from previous builds
+	b.cleanSource()
+
+	return nil
+}
+
+// fetchRef fetches a tag or ref into the shallow clone as FETCH_HEAD.
+func (b *Builder) fetchRef(ref string) error {
+	cmd := exec.Command("git", "fetch", "--depth", "1", "origin", "tag", ref)
+	cmd.Dir = b.SourceDir
+	if _, err := cmd.CombinedOutput(); err != nil {
 		// Try fetching as a regular ref if tag fetch fails
 		cmd = exec.Command("git", "fetch", "--depth", "1", "origin", ref)
 		cmd.Dir = b.SourceDir
 		if output, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("fetching ref %s: %w\n%s", ref, err, output)
 		}
-		_ = output
 	}
+	return nil
+}
 
-	cmd = exec.Command("git", "checkout", "FETCH_HEAD")
+// checkoutCached checks out a ref in a worktree of the cached mirror.
+// The mirror already contains every tag, so no fetch is needed.
+func (b *Builder) checkoutCached(ref string) error {
+	cmd := exec.Command("git", "checkout", "--force", "--detach", ref)
 	cmd.Dir = b.SourceDir
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("checking out %s: %w\n%s", ref, err, output)
 	}
 
+	if err := b.UpdateSubmodules(); err != nil {
+		return err
+	}
+
 	// Clean any untracked files from previous builds
-	cmd = exec.Command("git", "clean", "-fdx")
-	cmd.Dir = b.SourceDir
-	cmd.Run() // Ignore errors
+	b.cleanSource()
 
 	return nil
 }
 
+// Cleanup releases the source worktree when a mirror cache is in use.
+func (b *Builder) Cleanup() error {
+	if b.Cache == nil {
+		return nil
+	}
+	return b.Cache.RemoveWorktree(b.Config.Repo, b.SourceDir)
+}
+
 // InstallSystemDeps installs system dependencies via apk.
 func (b *Builder) InstallSystemDeps(deps []string) error {
 	if len(deps) == 0 {
@@ -121,87 +280,183 @@ func (b *Builder) InstallSystemDeps(deps []string) error {
 	}
 
 	args := append([]string{"add", "--no-cache"}, deps...)
-	cmd := exec.Command("apk", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := b.apk(args...)
 	if err != nil {
 		return fmt.Errorf("installing system deps: %w\n%s", err, output)
 	}
 	return nil
 }
 
-// ApplyPatches applies patch files in order.
-func (b *Builder) ApplyPatches(patches []string) error {
-	for _, patch := range patches {
-		patchPath := filepath.Join(b.WorkDir, patch)
-		cmd := exec.Command("git", "apply", patchPath)
-		cmd.Dir = b.SourceDir
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("applying patch %s: %w\n%s", patch, err, output)
-		}
-	}
-	return nil
-}
-
 // Build builds wheels for a specific version across all Python versions.
-func (b *Builder) Build(version config.Version, pythonVersions []string) []BuildResult {
-	results := make([]BuildResult, 0, len(pythonVersions))
+func (b *Builder) Build(version config.Version, pythonVersions []string) (results []BuildResult) {
+	// Resolve the executor and check the mirror is reachable from it
+	executor, err := b.executor()
+	if err == nil {
+		err = b.Network.check(executor)
+	}
+	if err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
 
 	// Checkout the tag
 	if err := b.Checkout(version.Tag); err != nil {
-		// Return failure for all Python versions
-		for _, py := range pythonVersions {
-			results = append(results, BuildResult{
-				Version: version.Version,
-				Python:  py,
-				Success: false,
-				Log:     err.Error(),
-				Error:   err,
-			})
-		}
-		return results
+		return failedResults(version.Version, pythonVersions, err)
 	}
 
-	// Get effective config for this version (apply overrides)
-	effectiveCfg := b.getEffectiveConfig(version.Version)
+	// Get effective config for this version (apply overrides), on top of
+	// the defaults for its build backend
+	effectiveCfg, err := b.getEffectiveConfig(version.Version)
+	if err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
+	backend := b.detectBackend()
+	applyBackendProfile(effectiveCfg, backend)
+	if err := b.Network.checkSystemDeps(executor, effectiveCfg.SystemDeps); err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
 
-	// Install system dependencies
-	if err := b.InstallSystemDeps(effectiveCfg.SystemDeps); err != nil {
-		for _, py := range pythonVersions {
-			results = append(results, BuildResult{
-				Version: version.Version,
-				Python:  py,
-				Success: false,
-				Log:     err.Error(),
-				Error:   err,
-			})
+	// Install system dependencies on the host, snapshotting them so this
+	// build's packages don't leak into later builds. Executors that install
+	// them in their sandbox get them from the ExecSpec instead.
+	var snapshot, resolvedDeps []string
+	hostDeps := !installsSystemDeps(executor)
+	if hostDeps {
+		snapshot, err = b.snapshotSystemDeps()
+		if err != nil {
+			return failedResults(version.Version, pythonVersions, err)
+		}
+		defer func() {
+			if err := b.restoreSystemDeps(snapshot); err != nil {
+				for i := range results {
+					results[i].Log += "\n" + err.Error()
+				}
+			}
+		}()
+
+		if err := b.InstallSystemDeps(effectiveCfg.SystemDeps); err != nil {
+			return failedResults(version.Version, pythonVersions, err)
+		}
+		resolvedDeps, err = b.resolveSystemDeps(effectiveCfg.SystemDeps)
+		if err != nil {
+			return failedResults(version.Version, pythonVersions, err)
 		}
-		return results
 	}
 
 	// Apply patches
 	if err := b.ApplyPatches(effectiveCfg.Patches); err != nil {
-		for _, py := range pythonVersions {
-			results = append(results, BuildResult{
-				Version: version.Version,
-				Python:  py,
-				Success: false,
-				Log:     err.Error(),
-				Error:   err,
-			})
+		return failedResults(version.Version, pythonVersions, err)
+	}
+
+	// Drop Pythons the patched version doesn't support
+	requiresPython, err := b.requiresPython(version.Version)
+	if err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
+	var applicable []string
+	for _, py := range pythonVersions {
+		if PythonApplicable(py, requiresPython) {
+			applicable = append(applicable, py)
+		}
+	}
+	if len(applicable) < len(pythonVersions) {
+		all := pythonVersions
+		defer func() {
+			results = withNotApplicable(results, version.Version, all, requiresPython)
+		}()
+		pythonVersions = applicable
+		if len(pythonVersions) == 0 {
+			return nil
+		}
+	}
+
+	// Resolve resource limits
+	if err := b.resolveResources(effectiveCfg); err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
+
+	// Vendor Rust crates for an offline build
+	if err := b.vendorCrates(effectiveCfg); err != nil {
+		results := failedResults(version.Version, pythonVersions, err)
+		if ClassifyFailure(err.Error()) == FailureMissingCrate {
+			for i := range results {
+				results[i].FailureClass = FailureMissingCrate
+			}
 		}
 		return results
 	}
 
+	// Pin build requirements
+	if err := b.writeBuildConstraints(effectiveCfg); err != nil {
+		return failedResults(version.Version, pythonVersions, err)
+	}
+
 	// Build for each Python version
+	results = make([]BuildResult, 0, len(pythonVersions))
 	for _, py := range pythonVersions {
+		start := time.Now()
 		result := b.buildForPython(version.Version, py, effectiveCfg)
+		result.Duration = time.Since(start)
+		result.SystemDeps = resolvedDeps
+		result.DiskUsage = b.diskUsage(py)
+		if b.CleanRoom && hostDeps && result.Success {
+			b.checkCleanRoom(&result, snapshot, effectiveCfg.SystemDeps)
+		}
+		result.RequiresPython = requiresPython
+		result.Backend = backend
 		results = append(results, result)
 	}
 
 	return results
 }
 
+// withNotApplicable merges the results of the built cells with
+// not-applicable results for the rest, in pythonVersions order.
+func withNotApplicable(built []BuildResult, version string, pythonVersions []string, requiresPython string) []BuildResult {
+	byPython := make(map[string]BuildResult, len(built))
+	for _, r := range built {
+		byPython[r.Python] = r
+	}
+	results := make([]BuildResult, 0, len(pythonVersions))
+	for _, py := range pythonVersions {
+		if r, ok := byPython[py]; ok {
+			results = append(results, r)
+		} else {
+			results = append(results, notApplicableResult(version, py, requiresPython))
+		}
+	}
+	return results
+}
+
+// failedResults returns a failed result for every Python version.
+func failedResults(version string, pythonVersions []string, err error) []BuildResult {
+	results := make([]BuildResult, 0, len(pythonVersions))
+	for _, py := range pythonVersions {
+		results = append(results, BuildResult{
+			Version:      version,
+			Python:       py,
+			Success:      false,
+			Log:          err.Error(),
+			Error:        err,
+			FailureClass: FailureSetup,
+		})
+	}
+	return results
+}
+
+// checkCleanRoom fails a result if the build installed undeclared system packages.
+func (b *Builder) checkCleanRoom(result *BuildResult, snapshot, declared []string) {
+	undeclared, err := b.undeclaredSystemDeps(snapshot, declared)
+	if err == nil && len(undeclared) > 0 {
+		err = fmt.Errorf("clean room: build installed undeclared system deps %v", undeclared)
+	}
+	if err != nil {
+		result.Success = false
+		result.Error = err
+		result.FailureClass = FailureBuild
+		result.Log += "\n" + err.Error()
+	}
+}
+
 // buildForPython builds a wheel for a specific Python version.
 func (b *Builder) buildForPython(version, python string, cfg *effectiveConfig) BuildResult {
 	result := BuildResult{
@@ -210,33 +465,54 @@ func (b *Builder) buildForPython(version, python string, cfg *effectiveConfig) B
 	}
 
 	var logBuf bytes.Buffer
-	var cmd *exec.Cmd
+	var args []string
 
 	pythonBin := PythonBinary(python)
 
+	if cfg.Script == "" && b.frontend() == FrontendPEP517 {
+		return b.buildPEP517(version, python, cfg)
+	}
+
 	if cfg.Script != "" {
 		// Use custom script
-		cmd = exec.Command("sh", "-c", cfg.Script)
+		args = []string{"sh", "-c", cfg.Script}
 	} else {
-		// Default pip wheel command
-		cmd = exec.Command(pythonBin, "-m", "pip", "wheel",
+		// pip wheel command
+		args = []string{pythonBin, "-m", "pip", "wheel",
 			"--no-deps",
 			"--no-binary", ":all:",
-			"-w", b.DistDir,
-			".")
+			"-w", b.DistDir}
+		for _, k := range sortedKeys(cfg.ConfigSettings) {
+			args = append(args, "--config-settings", k+"="+cfg.ConfigSettings[k])
+		}
+		args = append(args, ".")
+		if b.RecordBuildRequires {
+			// Verbose output includes the build dependency installs
+			args = append(args, "-v")
+		}
 	}
 
-	cmd.Dir = b.SourceDir
-	cmd.Env = b.buildEnv(cfg.Env, python)
-	cmd.Stdout = &logBuf
-	cmd.Stderr = &logBuf
+	spec := b.execSpec(args, cfg, python, &logBuf)
 
-	err := cmd.Run()
+	executor, err := b.executor()
+	if err != nil {
+		result.Error = err
+		result.FailureClass = FailureSetup
+		result.Log = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+	defer cancel()
+	execResult := executor.Run(ctx, spec)
 	result.Log = logBuf.String()
+	result.PeakRSS = execResult.Usage.PeakRSS
+	result.CPUTime = execResult.Usage.CPUTime
 
-	if err != nil {
+	if execResult.Error != nil {
 		result.Success = false
-		result.Error = fmt.Errorf("build failed: %w", err)
+		result.FailureClass = classifyExecFailure(ctx, execResult.Usage, result.Log)
+		result.Error = fmt.Errorf("build failed (%s): %w", result.FailureClass, execResult.Error)
 		return result
 	}
 
@@ -245,16 +521,127 @@ func (b *Builder) buildForPython(version, python string, cfg *effectiveConfig) B
 	if err != nil {
 		result.Success = false
 		result.Error = err
+		result.FailureClass = FailureBuild
+		result.Log += "\n" + err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.WheelPath = wheelPath
+	if b.RecordBuildRequires {
+		result.BuildRequires = parseInstalledBuildRequires(result.Log)
+	}
+	return result
+}
+
+// buildPEP517 builds a wheel with the native PEP 517 frontend.
+func (b *Builder) buildPEP517(version, python string, cfg *effectiveConfig) BuildResult {
+	result := BuildResult{
+		Version: version,
+		Python:  python,
+	}
+
+	executor, err := b.executor()
+	if err != nil {
+		result.Error = err
+		result.FailureClass = FailureSetup
+		result.Log = err.Error()
+		return result
+	}
+
+	var logBuf bytes.Buffer
+	var usage ResourceUsage
+	workDir := filepath.Join(b.WorkDir, "pep517", python)
+	frontend := &pep517.Frontend{
+		Python:         PythonBinary(python),
+		SourceDir:      b.SourceDir,
+		OutDir:         b.DistDir,
+		WorkDir:        workDir,
+		ConfigSettings: cfg.ConfigSettings,
+		Env:            b.buildEnv(cfg.Env, python),
+		Log:            &logBuf,
+		Run: func(ctx context.Context, args []string, dir string, env []string, out io.Writer) error {
+			spec := b.execSpec(args, cfg, python, out)
+			spec.Dir = dir
+			spec.Env = env
+			spec.WritableDirs = append(spec.WritableDirs, workDir)
+			execResult := executor.Run(ctx, spec)
+			usage.add(execResult.Usage)
+			return execResult.Error
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+	defer cancel()
+	wheelPath, err := frontend.Build(ctx)
+	result.Log = logBuf.String()
+	result.PeakRSS = usage.PeakRSS
+	result.CPUTime = usage.CPUTime
+
+	if err != nil {
+		result.FailureClass = classifyExecFailure(ctx, usage, result.Log)
+		result.Error = fmt.Errorf("build failed (%s): %w", result.FailureClass, err)
 		result.Log += "\n" + err.Error()
 		return result
 	}
 
 	result.Success = true
 	result.WheelPath = wheelPath
+	if b.RecordBuildRequires {
+		result.BuildRequires = parseInstalledBuildRequires(result.Log)
+	}
 	return result
 }
 
-// buildEnv constructs the environment for a build.
+// execSpec returns the ExecSpec for a build command.
+func (b *Builder) execSpec(args []string, cfg *effectiveConfig, python string, out io.Writer) *ExecSpec {
+	spec := &ExecSpec{
+		Args:         args,
+		Dir:          b.SourceDir,
+		Env:          b.buildEnv(cfg.Env, python),
+		WritableDirs: []string{b.SourceDir, b.DistDir},
+		SystemDeps:   cfg.SystemDeps,
+		Limits:       cfg.Limits,
+		Stdout:       out,
+		Stderr:       out,
+	}
+	if b.Network != nil && b.Network.Isolated {
+		spec.IsolateNetwork = true
+		spec.ReadOnlyDirs = b.Network.mirrorDirs()
+	}
+	if cfg.ConstraintsDir != "" {
+		spec.ReadOnlyDirs = append(spec.ReadOnlyDirs, cfg.ConstraintsDir)
+	}
+	readOnly, writable := b.cargoDirs()
+	spec.ReadOnlyDirs = append(spec.ReadOnlyDirs, readOnly...)
+	spec.WritableDirs = append(spec.WritableDirs, writable...)
+	return spec
+}
+
+// frontend returns the configured build frontend, defaulting to PEP 517.
+func (b *Builder) frontend() string {
+	if b.Frontend == "" {
+		return FrontendPEP517
+	}
+	return b.Frontend
+}
+
+// executor returns the configured Executor, resolving an unset one with
+// DefaultExecutor. An executor that isn't available is an error rather
+// than a fallback to the host.
+func (b *Builder) executor() (Executor, error) {
+	if b.Executor == nil {
+		e, err := DefaultExecutor()
+		if err != nil {
+			return nil, err
+		}
+		b.Executor = e
+	}
+	return b.Executor, nil
+}
+
+// buildEnv constructs the environment for a build. The Python's directory
+// leads PATH unless python is "" (for commands that don't run Python).
 func (b *Builder) buildEnv(env map[string]string, python string) []string {
 	// Start with current environment
 	result := os.Environ()
@@ -264,7 +651,20 @@ func (b *Builder) buildEnv(env map[string]string, python string) []string {
 		result = append(result, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// Cargo settings follow so offline vendored builds stay offline
+	for k, v := range b.cargoEnv() {
+		result = append(result, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Network policy comes last so package config can't override it
+	for k, v := range b.Network.env() {
+		result = append(result, fmt.Sprintf("%s=%s", k, v))
+	}
+
 	// Ensure the correct Python is used
+	if python == "" {
+		return result
+	}
 	pythonBin := PythonBinary(python)
 	pythonDir := filepath.Dir(pythonBin)
 	for i, e := range result {
@@ -304,27 +704,78 @@ func (b *Builder) findWheel(version, python string) (string, error) {
 	return "", fmt.Errorf("no wheel found for Python %s", python)
 }
 
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // effectiveConfig holds the merged configuration for a specific version.
 type effectiveConfig struct {
-	SystemDeps []string
-	Env        map[string]string
-	Patches    []string
-	Script     string
+	SystemDeps       []string
+	Env              map[string]string
+	Patches          []config.Patch
+	Script           string
+	ConfigSettings   map[string]string
+	BuildRequires    []string
+	BuildConstraints string
+	Resources        config.Resources
+	Rust             config.Rust
+
+	// Backend is the build backend whose profile was applied, if any. Set
+	// by applyBackendProfile.
+	Backend string
+
+	// CrateTarball is the vendored crate tarball for the version, if
+	// rust.cargo_vendor is set and CargoVendorDir has one.
+	CrateTarball string
+
+	// ConstraintsDir holds the generated constraints file PIP_CONSTRAINT
+	// points at, if any. Set by writeBuildConstraints.
+	ConstraintsDir string
+
+	// Limits and Timeout are parsed from Resources by resolveResources.
+	Limits  ResourceLimits
+	Timeout time.Duration
+}
+
+// timeout returns the wall-clock limit for a build.
+func (cfg *effectiveConfig) timeout() time.Duration {
+	if cfg.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return cfg.Timeout
 }
 
 // getEffectiveConfig merges base config with version-specific overrides.
-func (b *Builder) getEffectiveConfig(version string) *effectiveConfig {
+func (b *Builder) getEffectiveConfig(version string) (*effectiveConfig, error) {
+	patches, err := patchesFor(version, b.Config.Patches)
+	if err != nil {
+		return nil, err
+	}
 	cfg := &effectiveConfig{
-		SystemDeps: append([]string{}, b.Config.SystemDeps...),
-		Env:        make(map[string]string),
-		Patches:    append([]string{}, b.Config.Patches...),
-		Script:     b.Config.Script,
+		SystemDeps:       append([]string{}, b.Config.SystemDeps...),
+		Env:              make(map[string]string),
+		ConfigSettings:   make(map[string]string),
+		Patches:          patches,
+		Script:           b.Config.Script,
+		BuildRequires:    append([]string{}, b.Config.BuildRequires...),
+		BuildConstraints: b.Config.BuildConstraints,
+		Resources:        b.Resources.Merge(b.Config.Resources),
+		Rust:             b.Config.Rust,
 	}
 
 	// Copy base env
 	for k, v := range b.Config.Env {
 		cfg.Env[k] = v
 	}
+	for k, v := range b.Config.ConfigSettings {
+		cfg.ConfigSettings[k] = v
+	}
 
 	// Apply overrides
 	for _, override := range b.Config.Overrides {
@@ -335,32 +786,116 @@ func (b *Builder) getEffectiveConfig(version string) *effectiveConfig {
 
 		// Merge lists
 		cfg.SystemDeps = append(cfg.SystemDeps, override.SystemDeps...)
-		cfg.Patches = append(cfg.Patches, override.Patches...)
+		patches, err := patchesFor(version, override.Patches)
+		if err != nil {
+			return nil, fmt.Errorf("override %q: %w", override.Match, err)
+		}
+		cfg.Patches = append(cfg.Patches, patches...)
 
 		// Merge env (override wins)
 		for k, v := range override.Env {
 			cfg.Env[k] = v
 		}
+		for k, v := range override.ConfigSettings {
+			cfg.ConfigSettings[k] = v
+		}
 
 		// Replace script
 		if override.Script != "" {
 			cfg.Script = override.Script
 		}
 
+		// Merge build requirements (override wins per package)
+		cfg.BuildRequires = config.MergeRequirements(cfg.BuildRequires, override.BuildRequires)
+		if override.BuildConstraints != "" {
+			cfg.BuildConstraints = override.BuildConstraints
+		}
+
+		// Replace individual resource limits
+		cfg.Resources = cfg.Resources.Merge(override.Resources)
+
 		// First match wins
 		break
 	}
 
-	return cfg
+	// Recorded pins for this exact version win over everything
+	if v := b.Config.FindVersion(version); v != nil {
+		cfg.BuildRequires = config.MergeRequirements(cfg.BuildRequires, v.BuildRequires)
+	}
+
+	applyRustToolchain(cfg)
+	if cfg.Rust.CargoVendor {
+		cfg.CrateTarball = b.crateTarball(version)
+	}
+	return cfg, nil
 }
 
 // BuildAll builds all configured versions for all Python versions.
+// With an artifact store, cells whose hash already has a wheel are not
+// rebuilt, and newly built wheels are stored.
 func (b *Builder) BuildAll(pythonVersions []string) map[string][]BuildResult {
 	results := make(map[string][]BuildResult)
 
 	for _, v := range b.Config.Versions {
-		results[v.Version] = b.Build(v, pythonVersions)
+		results[v.Version] = b.buildIncremental(v, pythonVersions)
+	}
+
+	return results
+}
+
+// buildIncremental builds the cells of a version that aren't in the
+// artifact store.
+func (b *Builder) buildIncremental(version config.Version, pythonVersions []string) []BuildResult {
+	hashes, err := b.cellHashes(version, pythonVersions)
+	if err != nil {
+		// Without hashes nothing can be looked up or stored: build every cell
+		results := b.Build(version, pythonVersions)
+		for i := range results {
+			results[i].Log += "\ncell hash: " + err.Error() + "; built without the artifact store"
+		}
+		return results
 	}
+	cached := make(map[string]BuildResult)
+	var pending []string
 
+	for _, py := range pythonVersions {
+		hash := hashes[py]
+		if b.Artifacts != nil && hash != "" {
+			if wheel, err := b.Artifacts.Lookup(hash); err == nil && wheel != "" {
+				cached[py] = BuildResult{
+					Version:   version.Version,
+					Python:    py,
+					WheelPath: wheel,
+					Success:   true,
+					CellHash:  hash,
+					Cached:    true,
+				}
+				continue
+			}
+		}
+		pending = append(pending, py)
+	}
+
+	built := make(map[string]BuildResult)
+	if len(pending) > 0 {
+		for _, r := range b.Build(version, pending) {
+			r.CellHash = hashes[r.Python]
+			if b.Artifacts != nil && r.Success && r.CellHash != "" {
+				if _, err := b.Artifacts.Put(r.CellHash, r.WheelPath); err != nil {
+					r.Log += "\n" + err.Error()
+				}
+			}
+			built[r.Python] = r
+		}
+	}
+
+	results := make([]BuildResult, 0, len(pythonVersions))
+	for _, py := range pythonVersions {
+		if r, ok := cached[py]; ok {
+			results = append(results, r)
+		} else {
+			results = append(results, built[py])
+		}
+	}
 	return results
 }
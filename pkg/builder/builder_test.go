@@ -2,10 +2,13 @@ package builder
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/gitcache"
 )
 
 func TestNew(t *testing.T) {
@@ -54,13 +57,13 @@ func TestGetEffectiveConfig(t *testing.T) {
 		Repo:       "https://github.com/test/pkg",
 		SystemDeps: []string{"libfoo"},
 		Env:        map[string]string{"FOO": "bar"},
-		Patches:    []string{"base.patch"},
+		Patches:    []config.Patch{{Path: "base.patch"}},
 		Overrides: []config.Override{
 			{
 				Match:      ">=2.0",
 				SystemDeps: []string{"libfoo-new"},
 				Env:        map[string]string{"FOO": "baz", "NEW": "val"},
-				Patches:    []string{"new.patch"},
+				Patches:    []config.Patch{{Path: "new.patch"}},
 			},
 			{
 				Match:  "<1.5",
@@ -107,7 +110,10 @@ func TestGetEffectiveConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			eff := b.getEffectiveConfig(tt.version)
+			eff, err := b.getEffectiveConfig(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			// Check system deps
 			if len(eff.SystemDeps) != len(tt.wantDeps) {
@@ -210,3 +216,138 @@ func TestBuildEnv(t *testing.T) {
 		t.Error("BAZ=qux not found in env")
 	}
 }
+
+// runGit runs a git command in dir with a fixed identity.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newTestRepo creates a local repository with one tagged commit per tag.
+// Each commit writes the tag name to a VERSION file.
+func newTestRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "init", "-q")
+	for _, tag := range tags {
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(tag), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, dir, "add", "VERSION")
+		runGit(t, dir, "commit", "-q", "-m", tag)
+		runGit(t, dir, "tag", tag)
+	}
+	return dir
+}
+
+func TestCheckoutCached(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0", "v2.0.0")
+
+	dir := t.TempDir()
+	cfg := &config.Config{Repo: "file://" + upstream}
+	b := New(dir, "testpkg", cfg)
+	b.Cache = gitcache.New(filepath.Join(dir, "cache"))
+
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatalf("CloneSource() failed: %v", err)
+	}
+	defer b.Cleanup()
+
+	for _, tag := range []string{"v1.0.0", "v2.0.0", "v1.0.0"} {
+		if err := b.Checkout(tag); err != nil {
+			t.Fatalf("Checkout(%q) failed: %v", tag, err)
+		}
+		data, err := os.ReadFile(filepath.Join(b.SourceDir, "VERSION"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tag {
+			t.Errorf("VERSION = %q, want %q", data, tag)
+		}
+	}
+}
+
+func TestGetEffectiveConfigSettings(t *testing.T) {
+	cfg := &config.Config{
+		ConfigSettings: map[string]string{"setup-args": "-Dblas=openblas", "builddir": "build"},
+		Overrides: []config.Override{
+			{Match: "<2.0", ConfigSettings: map[string]string{"setup-args": "-Dblas=none"}},
+		},
+	}
+	b := New("/tmp/build", "testpkg", cfg)
+
+	eff, err := b.getEffectiveConfig("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"setup-args": "-Dblas=none", "builddir": "build"}
+	if len(eff.ConfigSettings) != len(want) {
+		t.Errorf("ConfigSettings = %v, want %v", eff.ConfigSettings, want)
+	}
+	for k, v := range want {
+		if eff.ConfigSettings[k] != v {
+			t.Errorf("ConfigSettings[%q] = %q, want %q", k, eff.ConfigSettings[k], v)
+		}
+	}
+}
+
+func TestBuildForPythonPEP517(t *testing.T) {
+	if _, err := os.Stat(PythonBinary("3.11")); err != nil {
+		t.Skip("python3.11 not available")
+	}
+
+	dir := t.TempDir()
+	b := New(dir, "demo", &config.Config{})
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"pyproject.toml": "[build-system]\nrequires = []\nbuild-backend = \"backend\"\nbackend-path = [\".\"]\n",
+		"backend.py": `import os, zipfile
+
+def build_wheel(wheel_directory, config_settings=None, metadata_directory=None):
+    name = "demo-1.0-cp311-cp311-linux_x86_64.whl"
+    zipfile.ZipFile(os.path.join(wheel_directory, name), "w").close()
+    return name
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(b.SourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eff, err := b.getEffectiveConfig("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := b.buildForPython("1.0", "3.11", eff)
+	if !result.Success {
+		t.Fatalf("build failed: %v\n%s", result.Error, result.Log)
+	}
+	if want := filepath.Join(b.DistDir, "demo-1.0-cp311-cp311-linux_x86_64.whl"); result.WheelPath != want {
+		t.Errorf("WheelPath = %q, want %q", result.WheelPath, want)
+	}
+}
@@ -1,10 +1,8 @@
 package builder
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
 	"time"
 )
 
@@ -18,41 +16,13 @@ type ExecResult struct {
 	Stdout   string
 	Stderr   string
 	Duration time.Duration
+	Usage    ResourceUsage
 	Error    error
 }
 
 // Exec runs a command and returns the result.
 func Exec(ctx context.Context, dir string, env []string, name string, args ...string) *ExecResult {
-	start := time.Now()
-	result := &ExecResult{
-		Command: fmt.Sprintf("%s %v", name, args),
-	}
-
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Dir = dir
-	if env != nil {
-		cmd.Env = env
-	}
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	result.Duration = time.Since(start)
-	result.Stdout = stdout.String()
-	result.Stderr = stderr.String()
-
-	if err != nil {
-		result.Error = err
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitErr.ExitCode()
-		} else {
-			result.ExitCode = -1
-		}
-	}
-
-	return result
+	return runCommand(ctx, dir, env, &ExecSpec{}, append([]string{name}, args...))
 }
 
 // ExecWithTimeout runs a command with a timeout.
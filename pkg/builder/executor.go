@@ -0,0 +1,533 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Executor names.
+const (
+	ExecutorHost       = "host"
+	ExecutorBubblewrap = "bwrap"
+	ExecutorOCI        = "oci"
+)
+
+// ExecutorEnvVar selects the executor used by DefaultExecutor.
+const ExecutorEnvVar = "SUPERWHEELIE_EXECUTOR"
+
+// Executor runs build commands, optionally inside a sandbox.
+type Executor interface {
+	// Name returns the executor name (e.g., "host").
+	Name() string
+
+	// Run executes a command described by spec.
+	Run(ctx context.Context, spec *ExecSpec) *ExecResult
+}
+
+// ExecSpec describes a command to run in an Executor.
+type ExecSpec struct {
+	// Args is the command and its arguments.
+	Args []string
+
+	// Dir is the working directory.
+	Dir string
+
+	// Env is the environment, in "KEY=value" form.
+	Env []string
+
+	// WritableDirs are directories the command may write to. Sandboxed
+	// executors expose everything else read-only.
+	WritableDirs []string
+
+	// ReadOnlyDirs are host directories the command must be able to read.
+	// Executors that don't share the host filesystem mount them read-only.
+	ReadOnlyDirs []string
+
+	// IsolateNetwork runs the command without network access where the
+	// executor supports it.
+	IsolateNetwork bool
+
+	// SystemDeps are the APK packages the command needs. Executors that do
+	// not share the host filesystem install them before running.
+	SystemDeps []string
+
+	// Limits are the resource limits for the command's process tree.
+	Limits ResourceLimits
+
+	// Stdout and Stderr receive the command output if set. Otherwise the
+	// output is captured in the ExecResult.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewExecutor returns the executor with the given name.
+func NewExecutor(name string) (Executor, error) {
+	switch name {
+	case "", ExecutorHost:
+		return &HostExecutor{}, nil
+	case ExecutorBubblewrap:
+		return &BubblewrapExecutor{}, nil
+	case ExecutorOCI:
+		return &OCIExecutor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown executor %q", name)
+	}
+}
+
+// DefaultExecutor returns the executor named by SUPERWHEELIE_EXECUTOR.
+// If unset, builds are sandboxed with bubblewrap in CI and run on the host
+// otherwise. A sandboxed executor whose tool is missing is an error rather
+// than a silent fallback to the host.
+func DefaultExecutor() (Executor, error) {
+	name := os.Getenv(ExecutorEnvVar)
+	if name == "" {
+		name = ExecutorHost
+		if os.Getenv("CI") != "" {
+			name = ExecutorBubblewrap
+		}
+	}
+
+	e, err := NewExecutor(name)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := e.(interface{ Available() error }); ok {
+		if err := c.Available(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// HostExecutor runs commands directly on the host with no isolation. Network
+// isolation on the host is advisory; see NetworkPolicy.
+type HostExecutor struct{}
+
+// Name returns "host".
+func (e *HostExecutor) Name() string { return ExecutorHost }
+
+// Run executes spec on the host.
+func (e *HostExecutor) Run(ctx context.Context, spec *ExecSpec) *ExecResult {
+	return runCommand(ctx, spec.Dir, spec.Env, spec, spec.Args)
+}
+
+// BubblewrapExecutor runs commands in a bubblewrap sandbox: the root
+// filesystem is mounted read-only except WritableDirs, /tmp and the home
+// directories are private tmpfs mounts, credential files named by the
+// environment are hidden, all namespaces but the network are unshared, and
+// all capabilities are dropped.
+type BubblewrapExecutor struct {
+	// Binary is the bwrap binary (default: "bwrap").
+	Binary string
+
+	// IsolateNetwork also unshares the network namespace, leaving only loopback.
+	IsolateNetwork bool
+}
+
+// Name returns "bwrap".
+func (e *BubblewrapExecutor) Name() string { return ExecutorBubblewrap }
+
+// Available returns an error if bwrap is not installed.
+func (e *BubblewrapExecutor) Available() error {
+	if _, err := exec.LookPath(e.binary()); err != nil {
+		return fmt.Errorf("bubblewrap executor: %w", err)
+	}
+	return nil
+}
+
+// Run executes spec inside bubblewrap.
+func (e *BubblewrapExecutor) Run(ctx context.Context, spec *ExecSpec) *ExecResult {
+	args := append(e.args(spec), spec.Args...)
+	return runCommand(ctx, "", nil, spec, append([]string{e.binary()}, args...))
+}
+
+// IsolatesNetwork reports that isolated builds run without a network.
+func (e *BubblewrapExecutor) IsolatesNetwork() bool { return true }
+
+func (e *BubblewrapExecutor) binary() string {
+	if e.Binary != "" {
+		return e.Binary
+	}
+	return "bwrap"
+}
+
+// args returns the bwrap arguments preceding the command.
+func (e *BubblewrapExecutor) args(spec *ExecSpec) []string {
+	args := []string{
+		"--die-with-parent",
+		"--new-session",
+		"--unshare-user",
+		"--unshare-pid",
+		"--unshare-ipc",
+		"--unshare-uts",
+		"--unshare-cgroup-try",
+	}
+	if e.IsolateNetwork || spec.IsolateNetwork {
+		args = append(args, "--unshare-net")
+	}
+	args = append(args,
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+	)
+	homes, files := credentialPaths(spec.Env)
+	for _, d := range homes {
+		args = append(args, "--tmpfs", d)
+	}
+	for _, f := range files {
+		args = append(args, "--ro-bind", os.DevNull, f)
+	}
+	for _, d := range spec.ReadOnlyDirs {
+		// Directories under a masked home must be mounted again
+		args = append(args, "--ro-bind", d, d)
+	}
+	for _, d := range spec.WritableDirs {
+		args = append(args, "--bind", d, d)
+	}
+	args = append(args, "--cap-drop", "ALL", "--clearenv")
+	for _, kv := range sanitizeEnv(spec.Env) {
+		k, v, _ := strings.Cut(kv, "=")
+		args = append(args, "--setenv", k, v)
+	}
+	if spec.Dir != "" {
+		args = append(args, "--chdir", spec.Dir)
+	}
+	return append(args, "--")
+}
+
+// OCIExecutor runs commands in a container through a podman-compatible CLI,
+// optionally talking to a local podman or containerd socket. The container
+// has a private /tmp, no capabilities, and only WritableDirs bind-mounted
+// from the host. Its root filesystem is read-only unless system deps must
+// be installed first.
+type OCIExecutor struct {
+	// Runtime is the container CLI (default: "podman"; "nerdctl" for containerd).
+	Runtime string
+
+	// Socket is the runtime socket address (e.g., "unix:///run/podman/podman.sock").
+	Socket string
+
+	// Image is the build image (default: DefaultBuildImage).
+	Image string
+
+	// Network is the container network mode (default: the runtime's default).
+	Network string
+}
+
+// DefaultBuildImage is the container image builds run in.
+const DefaultBuildImage = "ghcr.io/dlorenc/superwheelie:latest"
+
+// Name returns "oci".
+func (e *OCIExecutor) Name() string { return ExecutorOCI }
+
+// Available returns an error if the container runtime is not installed.
+func (e *OCIExecutor) Available() error {
+	if _, err := exec.LookPath(e.runtime()); err != nil {
+		return fmt.Errorf("oci executor: %w", err)
+	}
+	return nil
+}
+
+// Run executes spec in a new container. Limits are enforced by the
+// container runtime rather than on the runtime client process.
+func (e *OCIExecutor) Run(ctx context.Context, spec *ExecSpec) *ExecResult {
+	argv := append([]string{e.runtime()}, e.args(spec)...)
+	client := *spec
+	client.Limits = ResourceLimits{}
+	return runCommand(ctx, "", nil, &client, argv)
+}
+
+// IsolatesNetwork reports that isolated builds run without a network.
+func (e *OCIExecutor) IsolatesNetwork() bool { return true }
+
+// InstallsSystemDeps reports that system deps are installed in the
+// container rather than on the host.
+func (e *OCIExecutor) InstallsSystemDeps() bool { return true }
+
+// installsSystemDeps reports whether executor installs ExecSpec.SystemDeps
+// itself, so the builder must not install them on the host.
+func installsSystemDeps(executor Executor) bool {
+	i, ok := executor.(interface{ InstallsSystemDeps() bool })
+	return ok && i.InstallsSystemDeps()
+}
+
+func (e *OCIExecutor) runtime() string {
+	if e.Runtime != "" {
+		return e.Runtime
+	}
+	return "podman"
+}
+
+// args returns the full container runtime arguments for spec.
+func (e *OCIExecutor) args(spec *ExecSpec) []string {
+	var args []string
+	if e.Socket != "" {
+		if e.runtime() == "nerdctl" {
+			args = append(args, "--address", strings.TrimPrefix(e.Socket, "unix://"))
+		} else {
+			args = append(args, "--url", e.Socket)
+		}
+	}
+
+	image := e.Image
+	if image == "" {
+		image = DefaultBuildImage
+	}
+
+	args = append(args, "run", "--rm",
+		"--tmpfs", "/tmp",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+	)
+	if spec.Limits.MemoryBytes > 0 {
+		args = append(args, "--memory", strconv.FormatInt(spec.Limits.MemoryBytes, 10), "--memory-swap", strconv.FormatInt(spec.Limits.MemoryBytes, 10))
+	}
+	if spec.Limits.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(spec.Limits.CPUs, 'f', -1, 64))
+	}
+	if len(spec.SystemDeps) > 0 {
+		// apk needs a writable root and file ownership capabilities.
+		// The root filesystem is still discarded with the container.
+		args = append(args, "--cap-add", "CHOWN,DAC_OVERRIDE,FOWNER,SETGID,SETUID")
+	} else {
+		args = append(args, "--read-only")
+	}
+	if spec.IsolateNetwork {
+		args = append(args, "--network", "none")
+	} else if e.Network != "" {
+		args = append(args, "--network", e.Network)
+	}
+	for _, d := range spec.ReadOnlyDirs {
+		args = append(args, "-v", d+":"+d+":ro")
+	}
+	for _, d := range spec.WritableDirs {
+		args = append(args, "-v", d+":"+d+":rw")
+	}
+	for _, kv := range sanitizeEnv(spec.Env) {
+		k, _, _ := strings.Cut(kv, "=")
+		// Host paths and identity don't apply inside the container
+		if k == "PATH" || k == "HOME" || k == "HOSTNAME" {
+			continue
+		}
+		args = append(args, "-e", kv)
+	}
+	if spec.Dir != "" {
+		args = append(args, "-w", spec.Dir)
+	}
+	args = append(args, image)
+
+	if len(spec.SystemDeps) > 0 {
+		// The container doesn't share the host's installed packages
+		quoted := make([]string, len(spec.SystemDeps))
+		for i, d := range spec.SystemDeps {
+			quoted[i] = shellQuote(d)
+		}
+		script := fmt.Sprintf(`apk add --no-cache %s >&2 && exec "$0" "$@"`, strings.Join(quoted, " "))
+		return append(append(args, "sh", "-c", script), spec.Args...)
+	}
+	return append(args, spec.Args...)
+}
+
+// shellQuote quotes s for use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// secretEnvSuffixes mark environment variables that hold credentials.
+var secretEnvSuffixes = []string{"_TOKEN", "_SECRET", "_PASSWORD", "_API_KEY", "_CREDENTIALS"}
+
+// secretEnvNames are credential variables without a recognizable suffix.
+var secretEnvNames = map[string]bool{
+	"GOOGLE_APPLICATION_CREDENTIALS": true,
+	"SSH_AUTH_SOCK":                  true,
+	"ACTIONS_ID_TOKEN_REQUEST_URL":   true,
+}
+
+// sanitizeEnv drops credential variables from env so sandboxed builds
+// cannot read the agent's secrets.
+func sanitizeEnv(env []string) []string {
+	result := make([]string, 0, len(env))
+	for _, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		if isSecretEnv(k) {
+			continue
+		}
+		result = append(result, kv)
+	}
+	return result
+}
+
+func isSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	if secretEnvNames[upper] {
+		return true
+	}
+	for _, s := range secretEnvSuffixes {
+		if strings.HasSuffix(upper, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialPathEnv are variables naming credential files or directories
+// that may live outside the home directory.
+var credentialPathEnv = []string{
+	"AWS_CONFIG_FILE",
+	"AWS_SHARED_CREDENTIALS_FILE",
+	"AZURE_CONFIG_DIR",
+	"CLOUDSDK_CONFIG",
+	"DOCKER_CONFIG",
+	"GH_CONFIG_DIR",
+	"GOOGLE_APPLICATION_CREDENTIALS",
+	"KUBECONFIG",
+	"NETRC",
+	"SSH_AUTH_SOCK",
+}
+
+// credentialPaths returns the paths a sandbox must hide from builds: the
+// home directories of the build env and of the calling user (found even
+// when HOME is unset), which hold ~/.ssh, ~/.netrc, ~/.config/gh and cloud
+// credentials, and the existing credential files and directories named by
+// credentialPathEnv outside them. Directories are masked with a tmpfs,
+// files with /dev/null.
+func credentialPaths(env []string) (dirs, files []string) {
+	seen := make(map[string]bool)
+	addDir := func(d string) {
+		d = filepath.Clean(d)
+		if d != "." && d != "/" && !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+
+	addDir(envValue(env, "HOME"))
+	for _, home := range callerHomes() {
+		if _, err := os.Stat(home); err == nil {
+			addDir(home)
+		}
+	}
+	homes := append([]string{}, dirs...)
+
+	for _, name := range credentialPathEnv {
+		for _, p := range []string{envValue(env, name), os.Getenv(name)} {
+			if p == "" || !filepath.IsAbs(p) || underAny(p, homes) {
+				continue
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				continue
+			}
+			p = filepath.Clean(p)
+			if info.IsDir() {
+				addDir(p)
+			} else if !seen[p] {
+				seen[p] = true
+				files = append(files, p)
+			}
+		}
+	}
+	return dirs, files
+}
+
+// callerHomes returns the calling user's home directory from HOME and from
+// the user database.
+func callerHomes() []string {
+	var homes []string
+	if home := os.Getenv("HOME"); home != "" {
+		homes = append(homes, home)
+	}
+	if u, err := user.Current(); err == nil && u.HomeDir != "" {
+		homes = append(homes, u.HomeDir)
+	}
+	return homes
+}
+
+// underAny reports whether path is one of dirs or inside one.
+func underAny(path string, dirs []string) bool {
+	for _, d := range dirs {
+		if rel, err := filepath.Rel(d, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+	return false
+}
+
+// envValue returns the value of key in env, or "".
+func envValue(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(env[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// runCommand runs argv with output routed according to spec. Limits are
+// enforced with a cgroup when possible and with rlimits otherwise.
+func runCommand(ctx context.Context, dir string, env []string, spec *ExecSpec, argv []string) *ExecResult {
+	start := time.Now()
+	result := &ExecResult{
+		Command: fmt.Sprintf("%s %v", argv[0], argv[1:]),
+	}
+
+	var cg *cgroup
+	if !spec.Limits.IsZero() {
+		if cg = newCgroup(spec.Limits); cg != nil {
+			defer cg.close()
+		} else {
+			argv = rlimitCommand(ctx, spec.Limits, argv)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if cg != nil {
+		cg.attach(cmd)
+	}
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = env
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if spec.Stdout != nil {
+		cmd.Stdout = spec.Stdout
+	}
+	if spec.Stderr != nil {
+		cmd.Stderr = spec.Stderr
+	}
+
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if cmd.ProcessState != nil {
+		result.Usage = processUsage(cmd.ProcessState)
+	}
+	if cg != nil {
+		result.Usage = cg.usage()
+	}
+
+	if err != nil {
+		result.Error = err
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,269 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestNewExecutor(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", ExecutorHost, false},
+		{"host", ExecutorHost, false},
+		{"bwrap", ExecutorBubblewrap, false},
+		{"oci", ExecutorOCI, false},
+		{"docker-in-docker", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExecutor(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExecutor(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && e.Name() != tt.want {
+				t.Errorf("Name() = %q, want %q", e.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultExecutor(t *testing.T) {
+	t.Setenv(ExecutorEnvVar, "")
+	t.Setenv("CI", "")
+	e, err := DefaultExecutor()
+	if err != nil {
+		t.Fatalf("DefaultExecutor() error = %v", err)
+	}
+	if e.Name() != ExecutorHost {
+		t.Errorf("Name() = %q, want %q outside CI", e.Name(), ExecutorHost)
+	}
+
+	// In CI the sandbox is required, not silently skipped
+	t.Setenv("CI", "true")
+	t.Setenv("PATH", t.TempDir())
+	if _, err := DefaultExecutor(); err == nil {
+		t.Error("DefaultExecutor() should fail in CI without bwrap")
+	}
+
+	t.Setenv(ExecutorEnvVar, ExecutorHost)
+	if e, err := DefaultExecutor(); err != nil || e.Name() != ExecutorHost {
+		t.Errorf("DefaultExecutor() = %v, %v; want host executor", e, err)
+	}
+}
+
+func TestBuilderExecutor(t *testing.T) {
+	t.Setenv(ExecutorEnvVar, "")
+	t.Setenv("CI", "true")
+	t.Setenv("PATH", t.TempDir())
+
+	// An unset executor is resolved like DefaultExecutor, never the host
+	b := New(t.TempDir(), "testpkg", &config.Config{})
+	if e, err := b.executor(); err == nil {
+		t.Errorf("executor() = %s, want an error without bwrap in CI", e.Name())
+	}
+
+	result := b.buildForPython("1.0", "3.12", &effectiveConfig{})
+	if result.Success || result.FailureClass != FailureSetup {
+		t.Errorf("buildForPython() = %+v, want a setup failure", result)
+	}
+
+	t.Setenv("CI", "")
+	if e, err := b.executor(); err != nil || e.Name() != ExecutorHost {
+		t.Errorf("executor() = %v, %v; want the host executor outside CI", e, err)
+	}
+}
+
+func TestHostExecutorRun(t *testing.T) {
+	var out bytes.Buffer
+	spec := &ExecSpec{
+		Args:   []string{"sh", "-c", "echo $GREETING; echo oops >&2; exit 3"},
+		Env:    []string{"GREETING=hello"},
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	result := (&HostExecutor{}).Run(context.Background(), spec)
+	if result.Success() {
+		t.Error("Run() should report failure")
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if out.String() != "hello\noops\n" {
+		t.Errorf("output = %q, want %q", out.String(), "hello\noops\n")
+	}
+}
+
+func TestBubblewrapArgs(t *testing.T) {
+	spec := &ExecSpec{
+		Args:         []string{"python3.12", "-m", "pip", "wheel", "."},
+		Dir:          "/build/pkg/src",
+		Env:          []string{"HOME=/root", "CFLAGS=-O2", "GITHUB_TOKEN=ghp_secret"},
+		WritableDirs: []string{"/build/pkg/src", "/build/pkg/dist"},
+	}
+
+	e := &BubblewrapExecutor{}
+	args := strings.Join(e.args(spec), " ")
+	for _, want := range []string{
+		"--ro-bind / /",
+		"--tmpfs /tmp",
+		"--tmpfs /root",
+		"--bind /build/pkg/src /build/pkg/src",
+		"--bind /build/pkg/dist /build/pkg/dist",
+		"--cap-drop ALL",
+		"--setenv CFLAGS -O2",
+		"--chdir /build/pkg/src",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("bwrap args missing %q: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "ghp_secret") {
+		t.Errorf("bwrap args leak credentials: %s", args)
+	}
+	if strings.Contains(args, "--unshare-net") {
+		t.Errorf("network should be shared by default: %s", args)
+	}
+
+	e.IsolateNetwork = true
+	if args := strings.Join(e.args(spec), " "); !strings.Contains(args, "--unshare-net") {
+		t.Errorf("IsolateNetwork should unshare the network: %s", args)
+	}
+}
+
+func TestBubblewrapArgsCredentials(t *testing.T) {
+	home := t.TempDir()
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(netrc, []byte("machine example.com password hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The build env has no HOME; the caller's home is still masked
+	t.Setenv("HOME", home)
+	t.Setenv("NETRC", netrc)
+	mirror := filepath.Join(home, "mirror")
+
+	spec := &ExecSpec{
+		Args:         []string{"true"},
+		Env:          []string{"CFLAGS=-O2"},
+		ReadOnlyDirs: []string{mirror},
+	}
+	args := strings.Join((&BubblewrapExecutor{}).args(spec), " ")
+	for _, want := range []string{
+		"--tmpfs " + home,
+		"--ro-bind /dev/null " + netrc,
+		"--ro-bind " + mirror + " " + mirror,
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("bwrap args missing %q: %s", want, args)
+		}
+	}
+	if strings.Index(args, "--ro-bind "+mirror) < strings.Index(args, "--tmpfs "+home) {
+		t.Errorf("read-only dirs must be mounted after the home mask: %s", args)
+	}
+}
+
+func TestBubblewrapHidesHome(t *testing.T) {
+	e := &BubblewrapExecutor{}
+	if err := e.Available(); err != nil {
+		t.Skip(err)
+	}
+	if err := exec.Command("bwrap", "--ro-bind", "/", "/", "true").Run(); err != nil {
+		t.Skipf("bwrap can't create a sandbox here: %v", err)
+	}
+
+	home := t.TempDir()
+	secret := filepath.Join(home, ".ssh", "id_ed25519")
+	if err := os.MkdirAll(filepath.Dir(secret), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("PRIVATE KEY"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, env := range [][]string{{"HOME=" + home}, nil} {
+		t.Setenv("HOME", home)
+		var out bytes.Buffer
+		result := e.Run(context.Background(), &ExecSpec{
+			Args:   []string{"cat", secret},
+			Env:    env,
+			Stdout: &out,
+			Stderr: &out,
+		})
+		if result.Success() || strings.Contains(out.String(), "PRIVATE KEY") {
+			t.Errorf("sandbox with env %v read %s: %s", env, secret, out.String())
+		}
+	}
+}
+
+func TestOCIArgs(t *testing.T) {
+	spec := &ExecSpec{
+		Args:         []string{"sh", "-c", "make"},
+		Dir:          "/build/pkg/src",
+		Env:          []string{"PATH=/usr/bin", "CFLAGS=-O2", "AWS_SECRET=x"},
+		WritableDirs: []string{"/build/pkg/src"},
+	}
+
+	e := &OCIExecutor{Socket: "unix:///run/podman/podman.sock", Image: "example.com/build:1"}
+	args := e.args(spec)
+	joined := strings.Join(args, " ")
+	for _, want := range []string{
+		"--url unix:///run/podman/podman.sock",
+		"run --rm",
+		"--read-only",
+		"--cap-drop ALL",
+		"-v /build/pkg/src:/build/pkg/src:rw",
+		"-e CFLAGS=-O2",
+		"-w /build/pkg/src",
+		"example.com/build:1 sh -c make",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("oci args missing %q: %s", want, joined)
+		}
+	}
+	for _, unwanted := range []string{"PATH=", "AWS_SECRET"} {
+		if strings.Contains(joined, unwanted) {
+			t.Errorf("oci args should not contain %q: %s", unwanted, joined)
+		}
+	}
+
+	spec.SystemDeps = []string{"openblas-dev", "it's"}
+	joined = strings.Join(e.args(spec), " ")
+	if strings.Contains(joined, "--read-only") {
+		t.Errorf("installing system deps requires a writable root: %s", joined)
+	}
+	if !strings.Contains(joined, `apk add --no-cache 'openblas-dev' 'it'\''s'`) {
+		t.Errorf("oci args should install system deps: %s", joined)
+	}
+
+	spec.Limits = ResourceLimits{MemoryBytes: 16 << 30, CPUs: 1.5}
+	joined = strings.Join(e.args(spec), " ")
+	if !strings.Contains(joined, "--memory 17179869184 --memory-swap 17179869184 --cpus 1.5") {
+		t.Errorf("oci args should pass resource limits: %s", joined)
+	}
+}
+
+func TestSanitizeEnv(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin",
+		"GH_TOKEN=x",
+		"ANTHROPIC_API_KEY=x",
+		"GOOGLE_APPLICATION_CREDENTIALS=/key.json",
+		"db_password=x",
+		"CFLAGS=-O2",
+	}
+	got := strings.Join(sanitizeEnv(env), " ")
+	if got != "PATH=/usr/bin CFLAGS=-O2" {
+		t.Errorf("sanitizeEnv() = %q, want %q", got, "PATH=/usr/bin CFLAGS=-O2")
+	}
+}
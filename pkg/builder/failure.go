@@ -0,0 +1,72 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Failure classes recorded in BuildResult.FailureClass.
+const (
+	// FailureSetup means the build never ran (checkout, deps or patches failed).
+	FailureSetup = "setup"
+
+	// FailureBuild is a build failure with no more specific classification.
+	FailureBuild = "build"
+
+	// FailureNetwork means the build tried to reach the network while isolated.
+	FailureNetwork = "network"
+
+	// FailureMissingBuildRequirement means a build requirement was not found
+	// in the local package mirror.
+	FailureMissingBuildRequirement = "missing-build-requirement"
+
+	// FailureTimeout means the build exceeded its wall-clock limit.
+	FailureTimeout = "timeout"
+
+	// FailureResourceLimit means the build exceeded its memory or CPU limit.
+	FailureResourceLimit = "resource-limit"
+
+	// FailureMissingCrate means a Rust crate was not found in the vendored
+	// crates or the local cargo cache.
+	FailureMissingCrate = "missing-crate"
+)
+
+// failurePattern maps a log pattern to a failure class.
+type failurePattern struct {
+	class string
+	re    *regexp.Regexp
+}
+
+// failurePatterns are checked in order; the first match wins. Missing
+// crates come first since cargo reports the failed fetch behind them as a
+// network error, and network errors come before missing requirements
+// since pip reports a missing requirement after them.
+var failurePatterns = []failurePattern{
+	{FailureMissingCrate, regexp.MustCompile(`(?m)(no matching package named|failed to get .+ as a dependency of|failed to load source for dependency|attempting to make an HTTP request, but --offline was specified|you are in the offline mode|perhaps a crate was updated and forgotten to be re-vendored|failed to sync\b|failed to select a version for the requirement)`)},
+	{FailureNetwork, regexp.MustCompile(`(?m)(Temporary failure in name resolution|Name or service not known|Could not resolve host|getaddrinfo failed|Network is unreachable|Cannot connect to proxy|Failed to establish a new connection|NewConnectionError|ProxyError|127\.0\.0\.1:9\b|127\.0\.0\.1 port 9\b)`)},
+	{FailureMissingBuildRequirement, regexp.MustCompile(`(?m)(Could not find a version that satisfies the requirement|No matching distribution found for)`)},
+	{FailureResourceLimit, regexp.MustCompile(`(?m)(MemoryError|virtual memory exhausted|Cannot allocate memory|out of memory allocating|CPU time limit exceeded)`)},
+}
+
+// ClassifyFailure returns the failure class for a failed build log.
+func ClassifyFailure(log string) string {
+	for _, p := range failurePatterns {
+		if p.re.MatchString(log) {
+			return p.class
+		}
+	}
+	return FailureBuild
+}
+
+// classifyExecFailure classifies a failed build using its context and
+// resource usage before falling back to the log.
+func classifyExecFailure(ctx context.Context, usage ResourceUsage, log string) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	if usage.OOMKilled {
+		return FailureResourceLimit
+	}
+	return ClassifyFailure(log)
+}
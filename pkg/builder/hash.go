@@ -0,0 +1,236 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// hashVersion is bumped when the hash inputs change meaning, invalidating
+// every cached cell.
+const hashVersion = 2
+
+// commitRe matches a full git commit hash.
+var commitRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// configInputs are the resolved configuration inputs to a version's build.
+type configInputs struct {
+	SystemDeps       []string          `json:"system_deps"`
+	Env              map[string]string `json:"env"`
+	ConfigSettings   map[string]string `json:"config_settings"`
+	Patches          []patchInput      `json:"patches"`
+	Script           string            `json:"script"`
+	BuildRequires    []string          `json:"build_requires"`
+	BuildConstraints string            `json:"build_constraints"`
+	Submodules       config.Submodules `json:"submodules"`
+	LFS              bool              `json:"lfs"`
+	Rust             config.Rust       `json:"rust,omitzero"`
+	Backend          string            `json:"backend,omitempty"`
+	CrateTarball     string            `json:"crate_tarball,omitempty"`
+}
+
+// patchInput identifies a patch by its options and content.
+type patchInput struct {
+	Path     string `json:"path"`
+	Strategy string `json:"strategy"`
+	Fuzz     int    `json:"fuzz"`
+	Digest   string `json:"digest"`
+}
+
+// cellInputs are all inputs to a single cell's build.
+type cellInputs struct {
+	HashVersion int    `json:"hash_version"`
+	Config      string `json:"config"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	Python      string `json:"python"`
+	Platform    string `json:"platform"`
+	Image       string `json:"image"`
+	Frontend    string `json:"frontend"`
+}
+
+// ConfigHash returns a deterministic hash of the effective build
+// configuration of a version: the config after overrides, plus the content
+// of its patches and constraints file, read relative to packageDir.
+// Resource limits are excluded since they don't change the build output.
+func ConfigHash(cfg *config.Config, packageDir, version string) (string, error) {
+	b := &Builder{Config: cfg, WorkDir: packageDir}
+	eff, err := b.getEffectiveConfig(version)
+	if err != nil {
+		return "", err
+	}
+	return b.configHash(eff)
+}
+
+// configHash hashes an effective config.
+func (b *Builder) configHash(cfg *effectiveConfig) (string, error) {
+	in := configInputs{
+		SystemDeps:     cfg.SystemDeps,
+		Env:            cfg.Env,
+		ConfigSettings: cfg.ConfigSettings,
+		Script:         cfg.Script,
+		BuildRequires:  cfg.BuildRequires,
+		Submodules:     b.Config.Submodules,
+		LFS:            b.Config.LFS,
+		Rust:           cfg.Rust,
+		Backend:        cfg.Backend,
+	}
+	for _, p := range cfg.Patches {
+		digest, err := fileSHA256(filepath.Join(b.WorkDir, p.Path))
+		if err != nil {
+			return "", fmt.Errorf("hashing patch %s: %w", p.Path, err)
+		}
+		in.Patches = append(in.Patches, patchInput{
+			Path:     p.Path,
+			Strategy: p.EffectiveStrategy(),
+			Fuzz:     p.EffectiveFuzz(),
+			Digest:   digest,
+		})
+	}
+	if cfg.BuildConstraints != "" {
+		digest, err := fileSHA256(filepath.Join(b.WorkDir, cfg.BuildConstraints))
+		if err != nil {
+			return "", fmt.Errorf("hashing build constraints: %w", err)
+		}
+		in.BuildConstraints = digest
+	}
+	if cfg.CrateTarball != "" {
+		digest, err := fileSHA256(cfg.CrateTarball)
+		if err != nil {
+			return "", fmt.Errorf("hashing crate tarball: %w", err)
+		}
+		in.CrateTarball = digest
+	}
+	return hashJSON(in)
+}
+
+// CellHash returns a deterministic hash of every input to building a
+// version for a Python version: the effective config with its build
+// backend's profile applied, the commit the tag points to, the Python
+// version, the platform and the builder image.
+func (b *Builder) CellHash(version config.Version, python string) (string, error) {
+	hashes, err := b.cellHashes(version, []string{python})
+	if err != nil {
+		return "", err
+	}
+	return hashes[python], nil
+}
+
+// cellHashes returns the cell hash for each Python version, resolving the
+// config and commit once.
+func (b *Builder) cellHashes(version config.Version, pythonVersions []string) (map[string]string, error) {
+	eff, err := b.getEffectiveConfig(version.Version)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := b.resolveCommit(version.Tag)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := b.backendAt(version.Tag, commit)
+	if err != nil {
+		return nil, err
+	}
+	applyBackendProfile(eff, backend)
+	cfgHash, err := b.configHash(eff)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string, len(pythonVersions))
+	for _, py := range pythonVersions {
+		hash, err := hashJSON(cellInputs{
+			HashVersion: hashVersion,
+			Config:      cfgHash,
+			Version:     version.Version,
+			Commit:      commit,
+			Python:      py,
+			Platform:    b.platform(),
+			Image:       b.ImageDigest,
+			Frontend:    b.frontend(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		hashes[py] = hash
+	}
+	return hashes, nil
+}
+
+// resolveCommit returns the commit a tag or ref points to, without
+// checking it out.
+func (b *Builder) resolveCommit(ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = b.SourceDir
+	if out, err := cmd.Output(); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	// Shallow clones only have the tags fetched so far; ask the remote
+	cmd = exec.Command("git", "ls-remote", "origin", "refs/tags/"+ref, "refs/tags/"+ref+"^{}", ref)
+	cmd.Dir = b.SourceDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	var commit string
+	for _, r := range versions.ParseRefs(string(out)) {
+		// Prefer the tag over a branch of the same name
+		if r.Name == "refs/tags/"+ref || commit == "" {
+			commit = r.Commit
+		}
+	}
+	if commit == "" && commitRe.MatchString(ref) {
+		commit = ref
+	}
+	if commit == "" {
+		return "", fmt.Errorf("resolving %s: ref not found", ref)
+	}
+	return commit, nil
+}
+
+// platform returns the platform builds run on (e.g., "linux_aarch64").
+func (b *Builder) platform() string {
+	if b.Platform != "" {
+		return b.Platform
+	}
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	}
+	return runtime.GOOS + "_" + arch
+}
+
+// hashJSON returns the hex sha256 of v's JSON encoding. Map keys are
+// sorted by encoding/json, so the result is deterministic.
+func hashJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fileSHA256 returns the hex sha256 of a file's content.
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,348 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestConfigHash(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "patches"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	patch := filepath.Join(dir, "patches", "fix.patch")
+	if err := os.WriteFile(patch, []byte("--- a/x\n+++ b/x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newConfig := func() *config.Config {
+		return &config.Config{
+			Repo: "https://github.com/test/pkg",
+			Env:  map[string]string{"CFLAGS": "-O2", "A": "1"},
+			Overrides: []config.Override{
+				{Match: ">=2.0", Patches: []config.Patch{{Path: "patches/fix.patch"}}},
+				{Match: "<1.5", Env: map[string]string{"OLD": "1"}},
+			},
+			Resources: config.Resources{Memory: "4Gi"},
+		}
+	}
+
+	hash := func(cfg *config.Config, version string) string {
+		t.Helper()
+		h, err := ConfigHash(cfg, dir, version)
+		if err != nil {
+			t.Fatalf("ConfigHash(%s) error = %v", version, err)
+		}
+		return h
+	}
+
+	base := newConfig()
+	versions := []string{"1.0", "1.8", "2.0", "2.1"}
+	before := make(map[string]string)
+	for _, v := range versions {
+		before[v] = hash(base, v)
+		if again := hash(newConfig(), v); again != before[v] {
+			t.Errorf("ConfigHash(%s) not deterministic: %s != %s", v, before[v], again)
+		}
+	}
+	if before["1.8"] == before["2.0"] || before["1.0"] == before["1.8"] {
+		t.Error("versions with different overrides should hash differently")
+	}
+
+	// Changing one override only affects the versions it matches
+	head := newConfig()
+	head.Overrides[1].Env["OLD"] = "2"
+	for _, v := range versions {
+		changed := hash(head, v) != before[v]
+		if want := v == "1.0"; changed != want {
+			t.Errorf("override change: version %s changed = %v, want %v", v, changed, want)
+		}
+	}
+
+	// Patch content is part of the hash
+	if err := os.WriteFile(patch, []byte("--- a/y\n+++ b/y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash(base, "2.0") == before["2.0"] {
+		t.Error("patch content change should change the hash")
+	}
+	if hash(base, "1.8") != before["1.8"] {
+		t.Error("patch content change should not affect unpatched versions")
+	}
+
+	// Resource limits don't change the output
+	head = newConfig()
+	head.Resources.Memory = "16Gi"
+	if hash(head, "1.8") != before["1.8"] {
+		t.Error("resource limits should not change the hash")
+	}
+
+	// A missing patch is an error
+	if err := os.Remove(patch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConfigHash(base, dir, "2.0"); err == nil {
+		t.Error("ConfigHash() expected error for missing patch")
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0", "v2.0.0")
+	runGit(t, upstream, "tag", "-a", "-m", "annotated", "v2.0.0-annotated", "v2.0.0")
+	want := map[string]string{
+		"v1.0.0":           gitOutput(t, upstream, "rev-parse", "v1.0.0^{commit}"),
+		"v2.0.0":           gitOutput(t, upstream, "rev-parse", "v2.0.0^{commit}"),
+		"v2.0.0-annotated": gitOutput(t, upstream, "rev-parse", "v2.0.0^{commit}"),
+	}
+
+	dir := t.TempDir()
+	b := New(dir, "testpkg", &config.Config{Repo: "file://" + upstream})
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shallow clone has none of the tags, so they come from the remote
+	for ref, commit := range want {
+		got, err := b.resolveCommit(ref)
+		if err != nil {
+			t.Fatalf("resolveCommit(%q) error = %v", ref, err)
+		}
+		if got != commit {
+			t.Errorf("resolveCommit(%q) = %s, want %s", ref, got, commit)
+		}
+	}
+
+	if _, err := b.resolveCommit("v9.9.9"); err == nil {
+		t.Error("resolveCommit() expected error for unknown tag")
+	}
+}
+
+func TestCellHashBackend(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "init", "-q")
+	for _, tag := range []struct{ name, backend string }{
+		{"v1.0.0", "mesonpy"},
+		{"v2.0.0", "hatchling.build"},
+	} {
+		pyproject := "[build-system]\nbuild-backend = \"" + tag.backend + "\"\n# " + tag.name + "\n"
+		if err := os.WriteFile(filepath.Join(upstream, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, upstream, "add", ".")
+		runGit(t, upstream, "commit", "-q", "-m", tag.name)
+		runGit(t, upstream, "tag", tag.name)
+	}
+
+	b := New(t.TempDir(), "testpkg", &config.Config{Repo: "file://" + upstream})
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+	hash := func(tag string) string {
+		t.Helper()
+		h, err := b.CellHash(config.Version{Tag: tag, Version: tag[1:]}, "3.12")
+		if err != nil {
+			t.Fatalf("CellHash(%s) error = %v", tag, err)
+		}
+		return h
+	}
+
+	// The shallow clone has none of the tags, so backendAt fetches them
+	commit := gitOutput(t, upstream, "rev-parse", "v1.0.0^{commit}")
+	if backend, err := b.backendAt("v1.0.0", commit); err != nil || backend != pep517.BackendMesonPython {
+		t.Errorf("backendAt(v1.0.0) = %q, %v; want %q", backend, err, pep517.BackendMesonPython)
+	}
+
+	// Changing the profile of a version's backend changes its hash only
+	before := map[string]string{"v1.0.0": hash("v1.0.0"), "v2.0.0": hash("v2.0.0")}
+	profile := BackendProfiles[pep517.BackendMesonPython]
+	BackendProfiles[pep517.BackendMesonPython] = BackendProfile{SystemDeps: []string{"meson"}}
+	t.Cleanup(func() { BackendProfiles[pep517.BackendMesonPython] = profile })
+
+	if hash("v1.0.0") == before["v1.0.0"] {
+		t.Error("meson-python profile change should change the hash of a meson-python version")
+	}
+	if hash("v2.0.0") != before["v2.0.0"] {
+		t.Error("meson-python profile change should not affect a hatchling version")
+	}
+}
+
+func TestBuildAllIncremental(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0", "v2.0.0")
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo: "file://" + upstream,
+		Versions: []config.Version{
+			{Tag: "v1.0.0", Version: "1.0.0"},
+			{Tag: "v2.0.0", Version: "2.0.0"},
+		},
+		// Counts builds and produces a wheel without needing Python
+		Script: `echo built >> ../builds && touch ../dist/testpkg-$(cut -c2- VERSION)-cp311-cp311-linux_x86_64.whl`,
+		Overrides: []config.Override{
+			{Match: ">=2.0", Env: map[string]string{"FEATURE": "on"}},
+		},
+	}
+	b := New(dir, "testpkg", cfg)
+	b.Artifacts = &LocalArtifactStore{Dir: filepath.Join(t.TempDir(), "artifacts")}
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	builds := func() int {
+		data, _ := os.ReadFile(filepath.Join(dir, "builds"))
+		return len(data) / len("built\n")
+	}
+
+	run := func() map[string][]BuildResult {
+		t.Helper()
+		results := b.BuildAll([]string{"3.11"})
+		for v, rs := range results {
+			for _, r := range rs {
+				if !r.Success {
+					t.Fatalf("build %s/%s failed: %v\n%s", v, r.Python, r.Error, r.Log)
+				}
+				if r.CellHash == "" {
+					t.Errorf("build %s/%s has no cell hash", v, r.Python)
+				}
+			}
+		}
+		return results
+	}
+
+	run()
+	if got := builds(); got != 2 {
+		t.Fatalf("first run built %d cells, want 2", got)
+	}
+
+	results := run()
+	if got := builds(); got != 2 {
+		t.Errorf("unchanged run built %d more cells, want 0", got-2)
+	}
+	for v, rs := range results {
+		if !rs[0].Cached {
+			t.Errorf("version %s not served from the artifact store", v)
+		}
+	}
+
+	// Changing the override only rebuilds the versions it matches
+	cfg.Overrides[0].Env["FEATURE"] = "off"
+	results = run()
+	if got := builds(); got != 3 {
+		t.Errorf("override change built %d cells, want 1", got-2)
+	}
+	if results["1.0.0"][0].Cached != true || results["2.0.0"][0].Cached != false {
+		t.Errorf("Cached = %v/%v, want true/false", results["1.0.0"][0].Cached, results["2.0.0"][0].Cached)
+	}
+}
+
+func TestLocalArtifactStore(t *testing.T) {
+	s := &LocalArtifactStore{Dir: t.TempDir()}
+	hash := "ab12cd"
+
+	got, err := s.Lookup(hash)
+	if err != nil || got != "" {
+		t.Fatalf("Lookup() on empty store = %q, %v", got, err)
+	}
+
+	wheel := filepath.Join(t.TempDir(), "pkg-1.0-cp312-cp312-linux_aarch64.whl")
+	if err := os.WriteFile(wheel, []byte("wheel"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Put(hash, wheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(s.Dir, "ab", hash, filepath.Base(wheel)); stored != want {
+		t.Errorf("Put() = %q, want %q", stored, want)
+	}
+
+	got, err = s.Lookup(hash)
+	if err != nil || got != stored {
+		t.Errorf("Lookup() = %q, %v, want %q", got, err, stored)
+	}
+}
+
+func TestBuildAllIncrementalHashError(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0")
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:             "file://" + upstream,
+		Versions:         []config.Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		BuildConstraints: "constraints/missing.txt",
+	}
+	b := New(dir, "testpkg", cfg)
+	b.Artifacts = &LocalArtifactStore{Dir: filepath.Join(t.TempDir(), "artifacts")}
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := b.BuildAll([]string{"3.11", "3.12"})["1.0.0"]
+	if len(rs) != 2 {
+		t.Fatalf("BuildAll() returned %d results, want 2", len(rs))
+	}
+	for _, r := range rs {
+		if r.Cached || r.CellHash != "" {
+			t.Errorf("%s result = %+v, want an uncached build without a hash", r.Python, r)
+		}
+		if !strings.Contains(r.Log, "cell hash: hashing build constraints") {
+			t.Errorf("%s log doesn't report the hash error:\n%s", r.Python, r.Log)
+		}
+	}
+}
+
+func TestBuildAllIncrementalNotApplicable(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "init", "-q")
+	pyproject := "[project]\nname = \"testpkg\"\nrequires-python = \">=3.11\"\n"
+	if err := os.WriteFile(filepath.Join(upstream, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "-q", "-m", "v1.0.0")
+	runGit(t, upstream, "tag", "v1.0.0")
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:     "file://" + upstream,
+		Versions: []config.Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		Script:   `touch ../dist/testpkg-1.0.0-cp312-cp312-linux_x86_64.whl`,
+	}
+	b := New(dir, "testpkg", cfg)
+	b.Artifacts = &LocalArtifactStore{Dir: filepath.Join(t.TempDir(), "artifacts")}
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 2; run++ {
+		rs := b.BuildAll([]string{"3.10", "3.12"})["1.0.0"]
+		if len(rs) != 2 {
+			t.Fatalf("run %d returned %d results, want 2", run, len(rs))
+		}
+		if r := rs[0]; !r.NotApplicable || r.Success || r.Cached {
+			t.Errorf("run %d: 3.10 result = %+v, want not applicable", run, r)
+		}
+		if r := rs[1]; !r.Success || r.Cached != (run == 1) {
+			t.Errorf("run %d: 3.12 result = %+v, want success (cached: %v)", run, r, run == 1)
+		}
+	}
+}
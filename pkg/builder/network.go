@@ -0,0 +1,115 @@
+package builder
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// blackholeProxy is an address nothing listens on. Isolated builds route
+// proxy-aware traffic here so network access fails fast and recognizably
+// even on executors that cannot unshare the network.
+const blackholeProxy = "http://127.0.0.1:9"
+
+// NetworkPolicy controls network access for builds.
+//
+// Isolation is only enforced by sandboxing executors, which unshare the
+// network namespace (bwrap) or run without a network (OCI). The host
+// executor can only set proxy variables pointing nowhere, which build
+// backends are free to ignore, so isolation there is advisory.
+type NetworkPolicy struct {
+	// Isolated runs builds without network access. Build requirements must
+	// come from FindLinks or IndexURL.
+	Isolated bool
+
+	// FindLinks is a local directory of wheels and sdists to resolve build
+	// requirements from (PIP_FIND_LINKS with PIP_NO_INDEX).
+	FindLinks string
+
+	// IndexURL is a local simple index (e.g., "file:///mirror/simple") to
+	// resolve build requirements from (PIP_INDEX_URL).
+	IndexURL string
+}
+
+// env returns the environment variables implementing the policy.
+func (p *NetworkPolicy) env() map[string]string {
+	if p == nil || !p.Isolated {
+		return nil
+	}
+
+	env := map[string]string{
+		"PIP_RETRIES":                   "0",
+		"PIP_DISABLE_PIP_VERSION_CHECK": "1",
+		"HTTP_PROXY":                    blackholeProxy,
+		"HTTPS_PROXY":                   blackholeProxy,
+		"ALL_PROXY":                     blackholeProxy,
+		"NO_PROXY":                      "localhost,127.0.0.1,::1",
+	}
+	// Some tools only read the lowercase variants
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY"} {
+		env[strings.ToLower(k)] = env[k]
+	}
+
+	if p.IndexURL != "" {
+		env["PIP_INDEX_URL"] = p.IndexURL
+	} else {
+		env["PIP_NO_INDEX"] = "1"
+	}
+	if p.FindLinks != "" {
+		env["PIP_FIND_LINKS"] = p.FindLinks
+	}
+	return env
+}
+
+// mirrorDirs returns the local directories the mirror is served from, which
+// sandboxed executors must make readable.
+func (p *NetworkPolicy) mirrorDirs() []string {
+	var dirs []string
+	for _, loc := range []string{p.FindLinks, p.IndexURL} {
+		if dir, ok := localPath(loc); ok && loc != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// check returns an error if builds on executor couldn't reach the mirror.
+// Executors that cut off the network only have the mirror directories
+// mounted, so the mirror must be local: an http://localhost index isn't
+// reachable from inside the sandbox.
+func (p *NetworkPolicy) check(executor Executor) error {
+	if p == nil || !p.Isolated {
+		return nil
+	}
+	if n, ok := executor.(interface{ IsolatesNetwork() bool }); !ok || !n.IsolatesNetwork() {
+		return nil
+	}
+	for _, loc := range []string{p.FindLinks, p.IndexURL} {
+		if _, ok := localPath(loc); !ok {
+			return fmt.Errorf("network isolation: mirror %s is unreachable from the %s executor; use a local directory or file:// URL", loc, executor.Name())
+		}
+	}
+	return nil
+}
+
+// checkSystemDeps returns an error if executor would install deps inside an
+// isolated sandbox. The OCI executor runs apk in the build container, which
+// has no network when isolated, so the install would fail as a build error.
+func (p *NetworkPolicy) checkSystemDeps(executor Executor, deps []string) error {
+	if p == nil || !p.Isolated || len(deps) == 0 || !installsSystemDeps(executor) {
+		return nil
+	}
+	return fmt.Errorf("network isolation: the %s executor installs system deps (%s) without a network; bake them into the build image or use the bwrap executor", executor.Name(), strings.Join(deps, ", "))
+}
+
+// localPath returns the directory of a mirror location that is a local
+// path or file:// URL.
+func localPath(loc string) (string, bool) {
+	if !strings.Contains(loc, "://") {
+		return loc, true
+	}
+	if u, err := url.Parse(loc); err == nil && u.Scheme == "file" {
+		return u.Path, true
+	}
+	return "", false
+}
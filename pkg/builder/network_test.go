@@ -0,0 +1,191 @@
+package builder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestNetworkPolicyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *NetworkPolicy
+		want    map[string]string
+		wantNot []string
+	}{
+		{
+			name:    "nil",
+			policy:  nil,
+			wantNot: []string{"PIP_NO_INDEX", "PIP_INDEX_URL", "HTTPS_PROXY"},
+		},
+		{
+			name:    "not isolated",
+			policy:  &NetworkPolicy{FindLinks: "/mirror"},
+			wantNot: []string{"PIP_NO_INDEX", "PIP_FIND_LINKS"},
+		},
+		{
+			name:   "find links",
+			policy: &NetworkPolicy{Isolated: true, FindLinks: "/mirror/wheels"},
+			want: map[string]string{
+				"PIP_NO_INDEX":   "1",
+				"PIP_FIND_LINKS": "/mirror/wheels",
+				"HTTPS_PROXY":    blackholeProxy,
+				"https_proxy":    blackholeProxy,
+			},
+			wantNot: []string{"PIP_INDEX_URL"},
+		},
+		{
+			name:   "index url",
+			policy: &NetworkPolicy{Isolated: true, IndexURL: "file:///mirror/simple"},
+			want: map[string]string{
+				"PIP_INDEX_URL": "file:///mirror/simple",
+			},
+			wantNot: []string{"PIP_NO_INDEX"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.policy.env()
+			for k, v := range tt.want {
+				if env[k] != v {
+					t.Errorf("env[%q] = %q, want %q", k, env[k], v)
+				}
+			}
+			for _, k := range tt.wantNot {
+				if _, ok := env[k]; ok {
+					t.Errorf("env should not set %q", k)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildEnvNetworkPolicyWins(t *testing.T) {
+	cfg := &config.Config{Repo: "https://github.com/test/pkg"}
+	b := New("/tmp/build", "testpkg", cfg)
+	b.Network = &NetworkPolicy{Isolated: true, FindLinks: "/mirror"}
+
+	env := b.buildEnv(map[string]string{"PIP_NO_INDEX": "0"}, "3.12")
+	if got := envValue(env, "PIP_NO_INDEX"); got != "1" {
+		t.Errorf("PIP_NO_INDEX = %q, want %q", got, "1")
+	}
+}
+
+func TestMirrorDirs(t *testing.T) {
+	p := &NetworkPolicy{Isolated: true, FindLinks: "/mirror/wheels", IndexURL: "file:///mirror/simple"}
+	got := strings.Join(p.mirrorDirs(), " ")
+	if got != "/mirror/wheels /mirror/simple" {
+		t.Errorf("mirrorDirs() = %q, want %q", got, "/mirror/wheels /mirror/simple")
+	}
+
+	p = &NetworkPolicy{Isolated: true, FindLinks: "https://mirror.example.com/wheels", IndexURL: "http://127.0.0.1:3141/simple"}
+	if dirs := p.mirrorDirs(); len(dirs) != 0 {
+		t.Errorf("mirrorDirs() = %v, want none for remote mirrors", dirs)
+	}
+}
+
+func TestNetworkPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *NetworkPolicy
+		executor Executor
+		wantErr  bool
+	}{
+		{"no policy", nil, &BubblewrapExecutor{}, false},
+		{"not isolated", &NetworkPolicy{IndexURL: "http://localhost:3141/simple"}, &OCIExecutor{}, false},
+		{"file mirror", &NetworkPolicy{Isolated: true, FindLinks: "/mirror/wheels", IndexURL: "file:///mirror/simple"}, &BubblewrapExecutor{}, false},
+		{"host is advisory", &NetworkPolicy{Isolated: true, IndexURL: "http://localhost:3141/simple"}, &HostExecutor{}, false},
+		{"http index in bwrap", &NetworkPolicy{Isolated: true, IndexURL: "http://localhost:3141/simple"}, &BubblewrapExecutor{}, true},
+		{"http find-links in oci", &NetworkPolicy{Isolated: true, FindLinks: "http://127.0.0.1/wheels"}, &OCIExecutor{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.check(tt.executor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetworkPolicyCheckSystemDeps(t *testing.T) {
+	isolated := &NetworkPolicy{Isolated: true, FindLinks: "/mirror/wheels"}
+	tests := []struct {
+		name     string
+		policy   *NetworkPolicy
+		executor Executor
+		deps     []string
+		wantErr  bool
+	}{
+		{"no policy", nil, &OCIExecutor{}, []string{"rust"}, false},
+		{"not isolated", &NetworkPolicy{}, &OCIExecutor{}, []string{"rust"}, false},
+		{"no deps", isolated, &OCIExecutor{}, nil, false},
+		{"host installs", isolated, &HostExecutor{}, []string{"rust"}, false},
+		{"bwrap uses host packages", isolated, &BubblewrapExecutor{}, []string{"cmake"}, false},
+		{"oci installs without network", isolated, &OCIExecutor{}, []string{"rust", "cmake"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.checkSystemDeps(tt.executor, tt.deps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSystemDeps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"compile error", "error: command 'gcc' failed with exit status 1", FailureBuild},
+		{"dns", "socket.gaierror: [Errno -3] Temporary failure in name resolution", FailureNetwork},
+		{"proxy", "ProxyError('Cannot connect to proxy.', NewConnectionError(...))", FailureNetwork},
+		{"curl", "curl: (7) Failed to connect to 127.0.0.1 port 9 after 0 ms: Connection refused", FailureNetwork},
+		{"missing requirement", "ERROR: Could not find a version that satisfies the requirement setuptools>=64 (from versions: none)", FailureMissingBuildRequirement},
+		{"missing crate", "error: no matching package named `itoa` found\n  location searched: crates.io index", FailureMissingCrate},
+		{"cargo offline", "Caused by:\n  failed to download from `https://index.crates.io/config.json`\n  attempting to make an HTTP request, but --offline was specified", FailureMissingCrate},
+		{"crate fetch through proxy", "error: failed to get `pyo3` as a dependency of package `demo v0.1.0`\n  [7] Couldn't connect to server (Failed to connect to 127.0.0.1 port 9)", FailureMissingCrate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.log); got != tt.want {
+				t.Errorf("ClassifyFailure() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildIsolatedNetworkFailure(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0")
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:   "file://" + upstream,
+		Script: `echo "fetching from $HTTPS_PROXY"; echo "Cannot connect to proxy"; exit 1`,
+	}
+	b := New(dir, "testpkg", cfg)
+	b.Network = &NetworkPolicy{Isolated: true, FindLinks: filepath.Join(dir, "mirror")}
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("Build() should fail: %+v", results)
+	}
+	if results[0].FailureClass != FailureNetwork {
+		t.Errorf("FailureClass = %q, want %q", results[0].FailureClass, FailureNetwork)
+	}
+	if !strings.Contains(results[0].Log, blackholeProxy) {
+		t.Errorf("isolated build should see the blackhole proxy:\n%s", results[0].Log)
+	}
+}
@@ -0,0 +1,287 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// PatchResult is the outcome of checking or applying one patch to one version.
+type PatchResult struct {
+	// Version is the package version the patch was checked against.
+	Version string
+
+	// Patch is the patch file path.
+	Patch string
+
+	// Strategy is the apply strategy used.
+	Strategy string
+
+	// Applies indicates whether the patch applies cleanly.
+	Applies bool
+
+	// AlreadyApplied indicates the patch does not apply but reverse-applies
+	// cleanly, meaning upstream already contains the change.
+	AlreadyApplied bool
+
+	// FailedHunks describes each hunk that failed (e.g., "setup.py:42").
+	FailedHunks []string
+
+	// Output contains the raw output of the patch tool.
+	Output string
+
+	// Error contains any error that occurred.
+	Error error
+}
+
+var (
+	// gitApplyFailedRe matches "error: patch failed: file:line" from git apply.
+	gitApplyFailedRe = regexp.MustCompile(`(?m)^error: patch failed: (.+:\d+)$`)
+
+	// gitApplyErrorRe matches other per-file errors from git apply.
+	gitApplyErrorRe = regexp.MustCompile(`(?m)^error: (.+): (does not exist in index|No such file or directory|does not match index|already exists in working directory)$`)
+
+	// patchFileRe matches the file header lines from patch(1).
+	patchFileRe = regexp.MustCompile(`^(?:checking|patching) file (.+)$`)
+
+	// patchHunkFailedRe matches "Hunk #N FAILED at L." from patch(1).
+	patchHunkFailedRe = regexp.MustCompile(`^Hunk #(\d+) FAILED at (\d+)`)
+)
+
+// patchesFor returns the patches that apply to version. A patch whose
+// match can't be evaluated is an error rather than silently skipped.
+func patchesFor(version string, patches []config.Patch) ([]config.Patch, error) {
+	var result []config.Patch
+	for _, p := range patches {
+		ok, err := p.AppliesTo(version)
+		if err != nil {
+			return nil, fmt.Errorf("patch %s: %w", p.Path, err)
+		}
+		if ok {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// ApplyPatches applies patch files in order using each patch's strategy.
+func (b *Builder) ApplyPatches(patches []config.Patch) error {
+	for _, patch := range patches {
+		result := b.applyPatch(patch, false)
+		if result.Error != nil {
+			if len(result.FailedHunks) > 0 {
+				return fmt.Errorf("applying patch %s (%s): failed hunks %s: %w\n%s",
+					patch.Path, result.Strategy, strings.Join(result.FailedHunks, ", "), result.Error, result.Output)
+			}
+			return fmt.Errorf("applying patch %s (%s): %w\n%s", patch.Path, result.Strategy, result.Error, result.Output)
+		}
+	}
+	return nil
+}
+
+// CheckPatches dry-runs every applicable patch against every configured
+// version and reports which hunks fail. Patches that apply are applied
+// before checking the next one, so stacked patches are checked in order.
+// The source tree is left at the last checked version with patches applied.
+func (b *Builder) CheckPatches() []PatchResult {
+	var results []PatchResult
+
+	for _, v := range b.Config.Versions {
+		eff, err := b.getEffectiveConfig(v.Version)
+		if err != nil {
+			results = append(results, PatchResult{Version: v.Version, Output: err.Error(), Error: err})
+			continue
+		}
+		patches := eff.Patches
+		if len(patches) == 0 {
+			continue
+		}
+
+		if err := b.Checkout(v.Tag); err != nil {
+			for _, p := range patches {
+				results = append(results, PatchResult{
+					Version:  v.Version,
+					Patch:    p.Path,
+					Strategy: p.EffectiveStrategy(),
+					Output:   err.Error(),
+					Error:    err,
+				})
+			}
+			continue
+		}
+
+		for _, p := range patches {
+			result := b.applyPatch(p, true)
+			result.Version = v.Version
+			if !result.Applies {
+				result.AlreadyApplied = b.reverseApplies(p)
+			} else if applied := b.applyPatch(p, false); applied.Error != nil {
+				result = applied
+				result.Version = v.Version
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// LintPatches checks every patch against the versions that reference it and
+// reports patches whose changes are already present upstream.
+func (b *Builder) LintPatches() error {
+	var errs []error
+	for _, r := range b.CheckPatches() {
+		if r.AlreadyApplied {
+			errs = append(errs, fmt.Errorf("patch %s: already applied upstream in version %s", r.Patch, r.Version))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reverseApplies reports whether a patch reverse-applies cleanly to the source.
+func (b *Builder) reverseApplies(patch config.Patch) bool {
+	patchPath := filepath.Join(b.WorkDir, patch.Path)
+
+	var cmd *exec.Cmd
+	if patch.EffectiveStrategy() == config.PatchFuzz {
+		cmd = exec.Command("patch", "-p1", "-R", "--batch", "--dry-run", "-F", strconv.Itoa(patch.EffectiveFuzz()), "-i", patchPath)
+	} else {
+		cmd = exec.Command("git", "apply", "--check", "-R", patchPath)
+	}
+	cmd.Dir = b.SourceDir
+	return cmd.Run() == nil
+}
+
+// applyPatch applies a single patch, or only checks it if dryRun is set.
+func (b *Builder) applyPatch(patch config.Patch, dryRun bool) PatchResult {
+	result := PatchResult{
+		Patch:    patch.Path,
+		Strategy: patch.EffectiveStrategy(),
+	}
+
+	patchPath := filepath.Join(b.WorkDir, patch.Path)
+	if _, err := os.Stat(patchPath); err != nil {
+		result.Error = fmt.Errorf("reading patch: %w", err)
+		result.Output = result.Error.Error()
+		return result
+	}
+
+	var cmd *exec.Cmd
+	switch result.Strategy {
+	case config.PatchStrict, config.PatchThreeWay:
+		args := []string{"apply", "-v"}
+		if result.Strategy == config.PatchThreeWay {
+			args = append(args, "--3way")
+		}
+		if dryRun {
+			args = append(args, "--check")
+		}
+		cmd = exec.Command("git", append(args, patchPath)...)
+	case config.PatchFuzz:
+		args := []string{"-p1", "--forward", "--batch", "-F", strconv.Itoa(patch.EffectiveFuzz()), "-i", patchPath}
+		if dryRun {
+			args = append(args, "--dry-run")
+		}
+		cmd = exec.Command("patch", args...)
+	default:
+		result.Error = fmt.Errorf("unknown patch strategy %q", result.Strategy)
+		result.Output = result.Error.Error()
+		return result
+	}
+
+	cmd.Dir = b.SourceDir
+	output, err := cmd.CombinedOutput()
+	result.Output = string(output)
+	if err != nil {
+		result.Error = err
+		if result.Strategy == config.PatchFuzz {
+			result.FailedHunks = parsePatchFailures(result.Output)
+		} else {
+			result.FailedHunks = parseGitApplyFailures(result.Output)
+		}
+		return result
+	}
+
+	result.Applies = true
+	return result
+}
+
+// parseGitApplyFailures extracts failing hunk locations from git apply output.
+func parseGitApplyFailures(output string) []string {
+	var hunks []string
+	for _, m := range gitApplyFailedRe.FindAllStringSubmatch(output, -1) {
+		hunks = append(hunks, m[1])
+	}
+	for _, m := range gitApplyErrorRe.FindAllStringSubmatch(output, -1) {
+		hunks = append(hunks, m[1]+" ("+m[2]+")")
+	}
+	return hunks
+}
+
+// parsePatchFailures extracts failing hunks from patch(1) output.
+func parsePatchFailures(output string) []string {
+	var hunks []string
+	file := ""
+	for _, line := range strings.Split(output, "\n") {
+		if m := patchFileRe.FindStringSubmatch(line); m != nil {
+			file = m[1]
+			continue
+		}
+		if m := patchHunkFailedRe.FindStringSubmatch(line); m != nil {
+			hunks = append(hunks, fmt.Sprintf("%s:%s (hunk #%s)", file, m[2], m[1]))
+		}
+	}
+	return hunks
+}
+
+// FormatPatchResults renders patch check results as a patch-by-version table.
+func FormatPatchResults(results []PatchResult) string {
+	var versions, patches []string
+	seenVersion := make(map[string]bool)
+	seenPatch := make(map[string]bool)
+	cells := make(map[string]PatchResult)
+	for _, r := range results {
+		if !seenVersion[r.Version] {
+			seenVersion[r.Version] = true
+			versions = append(versions, r.Version)
+		}
+		if !seenPatch[r.Patch] {
+			seenPatch[r.Patch] = true
+			patches = append(patches, r.Patch)
+		}
+		cells[r.Patch+"\x00"+r.Version] = r
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "PATCH\t%s\n", strings.Join(versions, "\t"))
+	for _, p := range patches {
+		row := []string{p}
+		for _, v := range versions {
+			r, ok := cells[p+"\x00"+v]
+			switch {
+			case !ok:
+				row = append(row, "-")
+			case r.Applies:
+				row = append(row, "ok")
+			case r.AlreadyApplied:
+				row = append(row, "upstream")
+			case len(r.FailedHunks) > 0:
+				row = append(row, "FAIL "+strings.Join(r.FailedHunks, ","))
+			default:
+				row = append(row, "FAIL")
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,241 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// testPatch changes line 15 of a file containing the numbers 1-30.
+const testPatch = `--- a/lines.txt
++++ b/lines.txt
+@@ -12,7 +12,7 @@
+ 12
+ 13
+ 14
+-15
++fifteen
+ 16
+ 17
+ 18
+`
+
+// newPatchRepo creates a repository where v1.0.0 matches testPatch exactly,
+// v2.0.0 shifts it by two lines, and v3.0.0 changes a context line.
+func newPatchRepo(t *testing.T) string {
+	t.Helper()
+	dir := newTestRepo(t)
+
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	versions := []struct {
+		tag   string
+		lines []string
+	}{
+		{"v1.0.0", lines},
+		{"v2.0.0", append([]string{"a", "b"}, lines...)},
+		{"v3.0.0", append(append(append([]string{}, lines[:13]...), "fourteen"), lines[14:]...)},
+	}
+	for _, v := range versions {
+		content := strings.Join(v.lines, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "lines.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, dir, "add", "lines.txt")
+		runGit(t, dir, "commit", "-q", "-m", v.tag)
+		runGit(t, dir, "tag", v.tag)
+	}
+	return dir
+}
+
+func newPatchBuilder(t *testing.T, patches ...config.Patch) *Builder {
+	t.Helper()
+	upstream := newPatchRepo(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "patches"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "patches", "fix.patch"), []byte(testPatch), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Repo: "file://" + upstream,
+		Versions: []config.Version{
+			{Tag: "v1.0.0", Version: "1.0.0"},
+			{Tag: "v2.0.0", Version: "2.0.0"},
+			{Tag: "v3.0.0", Version: "3.0.0"},
+		},
+		Patches: patches,
+	}
+	b := New(dir, "testpkg", cfg)
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+	return b
+}
+
+func TestCheckPatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch config.Patch
+		want  map[string]bool
+	}{
+		{
+			name:  "strict",
+			patch: config.Patch{Path: "patches/fix.patch"},
+			want:  map[string]bool{"1.0.0": true, "2.0.0": true, "3.0.0": false},
+		},
+		{
+			name:  "fuzz",
+			patch: config.Patch{Path: "patches/fix.patch", Strategy: config.PatchFuzz, Fuzz: 3},
+			want:  map[string]bool{"1.0.0": true, "2.0.0": true, "3.0.0": true},
+		},
+		{
+			name:  "match",
+			patch: config.Patch{Path: "patches/fix.patch", Match: "<2.0"},
+			want:  map[string]bool{"1.0.0": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPatchBuilder(t, tt.patch)
+			results := b.CheckPatches()
+
+			if len(results) != len(tt.want) {
+				t.Fatalf("len(results) = %d, want %d: %+v", len(results), len(tt.want), results)
+			}
+			for _, r := range results {
+				want, ok := tt.want[r.Version]
+				if !ok {
+					t.Errorf("unexpected result for version %s", r.Version)
+					continue
+				}
+				if r.Applies != want {
+					t.Errorf("version %s: Applies = %v, want %v\n%s", r.Version, r.Applies, want, r.Output)
+				}
+				if !r.Applies && len(r.FailedHunks) == 0 {
+					t.Errorf("version %s: failed without hunk diagnostics\n%s", r.Version, r.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPatchesStrictFailure(t *testing.T) {
+	b := newPatchBuilder(t)
+	if err := b.Checkout("v3.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := b.ApplyPatches([]config.Patch{{Path: "patches/fix.patch"}})
+	if err == nil {
+		t.Fatal("ApplyPatches() should fail on drifted context")
+	}
+	if !strings.Contains(err.Error(), "lines.txt:12") {
+		t.Errorf("error should name the failed hunk: %v", err)
+	}
+}
+
+func TestParsePatchFailures(t *testing.T) {
+	output := `checking file setup.py
+Hunk #1 succeeded at 14 (offset 2 lines).
+Hunk #2 FAILED at 40.
+checking file src/ext.c
+Hunk #1 FAILED at 7.
+2 out of 3 hunks FAILED
+`
+	got := parsePatchFailures(output)
+	want := []string{"setup.py:40 (hunk #2)", "src/ext.c:7 (hunk #1)"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parsePatchFailures() = %v, want %v", got, want)
+	}
+}
+
+func TestParseGitApplyFailures(t *testing.T) {
+	output := `Checking patch setup.py...
+error: while searching for:
+foo
+
+error: patch failed: setup.py:12
+error: setup.py: patch does not apply
+Checking patch missing.c...
+error: missing.c: No such file or directory
+`
+	got := parseGitApplyFailures(output)
+	want := []string{"setup.py:12", "missing.c (No such file or directory)"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parseGitApplyFailures() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatPatchResults(t *testing.T) {
+	results := []PatchResult{
+		{Version: "2.0.0", Patch: "patches/a.patch", Applies: true},
+		{Version: "1.0.0", Patch: "patches/a.patch", FailedHunks: []string{"setup.py:12"}},
+		{Version: "1.0.0", Patch: "patches/b.patch", Applies: true},
+	}
+
+	got := FormatPatchResults(results)
+	for _, want := range []string{"PATCH", "2.0.0", "1.0.0", "FAIL setup.py:12", "ok"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatPatchResults() missing %q:\n%s", want, got)
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(got), "\n"); len(lines) != 3 {
+		t.Errorf("FormatPatchResults() has %d lines, want 3:\n%s", len(lines), got)
+	}
+}
+
+func TestLintPatchesAlreadyApplied(t *testing.T) {
+	b := newPatchBuilder(t, config.Patch{Path: "patches/fix.patch"})
+
+	// Tag a release that already contains the patch
+	upstream := strings.TrimPrefix(b.Config.Repo, "file://")
+	runGit(t, upstream, "checkout", "-q", "v1.0.0")
+	runGit(t, upstream, "apply", filepath.Join(b.WorkDir, "patches", "fix.patch"))
+	runGit(t, upstream, "commit", "-q", "-am", "upstream fix")
+	runGit(t, upstream, "tag", "v4.0.0")
+	b.Config.Versions = append(b.Config.Versions, config.Version{Tag: "v4.0.0", Version: "4.0.0"})
+
+	err := b.LintPatches()
+	if err == nil {
+		t.Fatal("LintPatches() should report the upstreamed patch")
+	}
+	if !strings.Contains(err.Error(), "4.0.0") {
+		t.Errorf("error should name version 4.0.0: %v", err)
+	}
+	if strings.Contains(err.Error(), "3.0.0") {
+		t.Errorf("drifted patch in 3.0.0 is not upstreamed: %v", err)
+	}
+}
+
+func TestPatchesFor(t *testing.T) {
+	patches := []config.Patch{
+		{Path: "patches/all.patch"},
+		{Path: "patches/old.patch", Match: "<2.0"},
+	}
+	got, err := patchesFor("2.1", patches)
+	if err != nil {
+		t.Fatalf("patchesFor() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Path != "patches/all.patch" {
+		t.Errorf("patchesFor() = %v, want [patches/all.patch]", got)
+	}
+
+	// An unparseable match fails instead of building unpatched
+	patches = append(patches, config.Patch{Path: "patches/bad.patch", Match: "2.0"})
+	if _, err := patchesFor("2.1", patches); err == nil || !strings.Contains(err.Error(), "patches/bad.patch") {
+		t.Errorf("patchesFor() error = %v, want an error naming patches/bad.patch", err)
+	}
+
+	b := New(t.TempDir(), "testpkg", &config.Config{Patches: patches})
+	if _, err := b.getEffectiveConfig("2.1"); err == nil {
+		t.Error("getEffectiveConfig() should fail for an unparseable patch match")
+	}
+}
@@ -0,0 +1,74 @@
+package pep517
+
+import "strings"
+
+// Known build backends.
+const (
+	BackendSetuptools      = "setuptools"
+	BackendSetuptoolsRust  = "setuptools-rust"
+	BackendMesonPython     = "meson-python"
+	BackendScikitBuildCore = "scikit-build-core"
+	BackendMaturin         = "maturin"
+	BackendHatchling       = "hatchling"
+	BackendFlit            = "flit-core"
+	BackendPoetry          = "poetry-core"
+	BackendPDM             = "pdm-backend"
+)
+
+// backendModules maps backend object references to backends.
+var backendModules = map[string]string{
+	"setuptools.build_meta":            BackendSetuptools,
+	"setuptools.build_meta:__legacy__": BackendSetuptools,
+	"mesonpy":                          BackendMesonPython,
+	"scikit_build_core.build":          BackendScikitBuildCore,
+	"maturin":                          BackendMaturin,
+	"hatchling.build":                  BackendHatchling,
+	"flit_core.buildapi":               BackendFlit,
+	"poetry.core.masonry.api":          BackendPoetry,
+	"pdm.backend":                      BackendPDM,
+}
+
+// DetectBackend returns the build backend of a project, or "" if it isn't
+// a known one. Projects without a pyproject.toml or build backend use
+// setuptools; setuptools projects that require setuptools-rust are
+// reported as BackendSetuptoolsRust.
+func DetectBackend(pp *PyProject) string {
+	if pp == nil || pp.BuildSystem.BuildBackend == "" {
+		if pp != nil && requiresSetuptoolsRust(pp) {
+			return BackendSetuptoolsRust
+		}
+		return BackendSetuptools
+	}
+	backend := backendModules[pp.BuildSystem.BuildBackend]
+	if backend == BackendSetuptools && requiresSetuptoolsRust(pp) {
+		return BackendSetuptoolsRust
+	}
+	return backend
+}
+
+// IsBackend reports whether name is a known build backend.
+func IsBackend(name string) bool {
+	if name == BackendSetuptoolsRust {
+		return true
+	}
+	for _, backend := range backendModules {
+		if backend == name {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresSetuptoolsRust reports whether the build requires setuptools-rust.
+func requiresSetuptoolsRust(pp *PyProject) bool {
+	for _, req := range pp.BuildSystem.Requires {
+		name := strings.ToLower(strings.TrimSpace(req))
+		if i := strings.IndexAny(name, "<>=!~;[ "); i >= 0 {
+			name = name[:i]
+		}
+		if strings.ReplaceAll(name, "_", "-") == "setuptools-rust" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package pep517
+
+import "testing"
+
+func TestDetectBackend(t *testing.T) {
+	tests := []struct {
+		name      string
+		pyproject string
+		want      string
+	}{
+		{"no build system", "[project]\nname = \"pkg\"\n", BackendSetuptools},
+		{"setuptools", "[build-system]\nrequires = [\"setuptools>=61\"]\nbuild-backend = \"setuptools.build_meta\"\n", BackendSetuptools},
+		{"setuptools-rust", "[build-system]\nrequires = [\"setuptools\", \"setuptools_rust>=1.5\"]\nbuild-backend = \"setuptools.build_meta\"\n", BackendSetuptoolsRust},
+		{"legacy setuptools-rust", "[build-system]\nrequires = [\"setuptools\", \"setuptools-rust\"]\n", BackendSetuptoolsRust},
+		{"meson-python", "[build-system]\nrequires = [\"meson-python\"]\nbuild-backend = \"mesonpy\"\n", BackendMesonPython},
+		{"scikit-build-core", "[build-system]\nrequires = [\"scikit-build-core\"]\nbuild-backend = \"scikit_build_core.build\"\n", BackendScikitBuildCore},
+		{"maturin", "[build-system]\nrequires = [\"maturin>=1,<2\"]\nbuild-backend = \"maturin\"\n", BackendMaturin},
+		{"hatchling", "[build-system]\nrequires = [\"hatchling\"]\nbuild-backend = \"hatchling.build\"\n", BackendHatchling},
+		{"unknown", "[build-system]\nrequires = [\"custom\"]\nbuild-backend = \"custom.backend\"\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp, err := ParsePyProject([]byte(tt.pyproject))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectBackend(pp); got != tt.want {
+				t.Errorf("DetectBackend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := DetectBackend(nil); got != BackendSetuptools {
+		t.Errorf("DetectBackend(nil) = %q, want %q", got, BackendSetuptools)
+	}
+}
@@ -0,0 +1,268 @@
+package pep517
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LegacyRequires are installed for projects without a build backend,
+// matching the PEP 517 fallback.
+var LegacyRequires = []string{"setuptools>=40.8.0", "wheel"}
+
+//go:embed shim.py
+var shim []byte
+
+// Runner runs a command in dir with env, writing output to out.
+type Runner func(ctx context.Context, args []string, dir string, env []string, out io.Writer) error
+
+// Frontend builds a wheel from a source tree using its PEP 517 backend.
+type Frontend struct {
+	// Python is the interpreter the build environment is created from.
+	Python string
+
+	// SourceDir is the project source tree.
+	SourceDir string
+
+	// OutDir is where the built wheel is written.
+	OutDir string
+
+	// WorkDir holds the isolated build environment and shim.
+	// It is removed and recreated by Build.
+	WorkDir string
+
+	// ConfigSettings are passed to the backend hooks as config_settings.
+	ConfigSettings map[string]string
+
+	// Env is the base environment for build commands.
+	Env []string
+
+	// Run executes commands (default: directly on the host).
+	Run Runner
+
+	// Log receives the output of all build commands.
+	Log io.Writer
+}
+
+// Build creates an isolated environment, installs the build requirements and
+// builds a wheel. Projects without a pyproject.toml build backend fall back
+// to "setup.py bdist_wheel". Returns the path of the built wheel.
+func (f *Frontend) Build(ctx context.Context) (string, error) {
+	pp, err := LoadPyProject(f.SourceDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(f.WorkDir); err != nil {
+		return "", fmt.Errorf("cleaning build environment: %w", err)
+	}
+	for _, dir := range []string{f.WorkDir, f.OutDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+
+	envDir := filepath.Join(f.WorkDir, "env")
+	if err := f.run(ctx, f.Python, "-m", "venv", "--without-pip", envDir); err != nil {
+		return "", fmt.Errorf("creating build environment: %w", err)
+	}
+	python := filepath.Join(envDir, "bin", "python")
+
+	if pp == nil || pp.BuildSystem.BuildBackend == "" {
+		return f.buildLegacy(ctx, pp, python)
+	}
+
+	if err := f.install(ctx, python, pp.BuildSystem.Requires); err != nil {
+		return "", err
+	}
+
+	var extra []string
+	if err := f.callHook(ctx, python, pp.BuildSystem, "get_requires_for_build_wheel", map[string]interface{}{
+		"config_settings": f.configSettings(),
+	}, &extra); err != nil {
+		return "", err
+	}
+	if err := f.install(ctx, python, extra); err != nil {
+		return "", err
+	}
+
+	outDir, err := filepath.Abs(f.OutDir)
+	if err != nil {
+		return "", err
+	}
+	var wheel string
+	if err := f.callHook(ctx, python, pp.BuildSystem, "build_wheel", map[string]interface{}{
+		"wheel_directory": outDir,
+		"config_settings": f.configSettings(),
+	}, &wheel); err != nil {
+		return "", err
+	}
+	return filepath.Join(outDir, wheel), nil
+}
+
+// buildLegacy builds with "setup.py bdist_wheel".
+func (f *Frontend) buildLegacy(ctx context.Context, pp *PyProject, python string) (string, error) {
+	if _, err := os.Stat(filepath.Join(f.SourceDir, "setup.py")); err != nil {
+		return "", fmt.Errorf("no build backend in pyproject.toml and no setup.py")
+	}
+
+	requires := LegacyRequires
+	if pp != nil && len(pp.BuildSystem.Requires) > 0 {
+		requires = pp.BuildSystem.Requires
+	}
+	if err := f.install(ctx, python, requires); err != nil {
+		return "", err
+	}
+
+	before, err := wheels(f.OutDir)
+	if err != nil {
+		return "", err
+	}
+
+	outDir, err := filepath.Abs(f.OutDir)
+	if err != nil {
+		return "", err
+	}
+	if err := f.run(ctx, python, "setup.py", "bdist_wheel", "-d", outDir); err != nil {
+		return "", fmt.Errorf("setup.py bdist_wheel: %w", err)
+	}
+
+	after, err := wheels(f.OutDir)
+	if err != nil {
+		return "", err
+	}
+	for w := range after {
+		if !before[w] {
+			return filepath.Join(outDir, w), nil
+		}
+	}
+	return "", fmt.Errorf("setup.py bdist_wheel produced no wheel")
+}
+
+// install installs requirements into the build environment, bootstrapping
+// pip on first use.
+func (f *Frontend) install(ctx context.Context, python string, requires []string) error {
+	if len(requires) == 0 {
+		return nil
+	}
+
+	pip := filepath.Join(filepath.Dir(python), "pip")
+	if _, err := os.Stat(pip); os.IsNotExist(err) {
+		if err := f.run(ctx, python, "-m", "ensurepip", "--default-pip"); err != nil {
+			return fmt.Errorf("bootstrapping pip: %w", err)
+		}
+	}
+
+	args := append([]string{python, "-m", "pip", "install", "--disable-pip-version-check"}, requires...)
+	if err := f.run(ctx, args...); err != nil {
+		return fmt.Errorf("installing build requirements %v: %w", requires, err)
+	}
+	return nil
+}
+
+// callHook calls a backend hook through the shim and decodes its result.
+func (f *Frontend) callHook(ctx context.Context, python string, bs BuildSystem, hook string, kwargs map[string]interface{}, result interface{}) error {
+	shimPath := filepath.Join(f.WorkDir, "pep517_shim.py")
+	if err := os.WriteFile(shimPath, shim, 0644); err != nil {
+		return fmt.Errorf("writing hook shim: %w", err)
+	}
+
+	backendPath, err := json.Marshal(nonNil(bs.BackendPath))
+	if err != nil {
+		return err
+	}
+	kwargsJSON, err := json.Marshal(kwargs)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(f.WorkDir, hook+".json")
+
+	if err := f.run(ctx, python, shimPath, hook, bs.BuildBackend, string(backendPath), string(kwargsJSON), outPath); err != nil {
+		return fmt.Errorf("calling %s: %w", hook, err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		return fmt.Errorf("reading %s result: %w", hook, err)
+	}
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("parsing %s result: %w", hook, err)
+	}
+	if err := json.Unmarshal(out.Result, result); err != nil {
+		return fmt.Errorf("parsing %s result: %w", hook, err)
+	}
+	return nil
+}
+
+// run runs a command in the source directory with the build environment
+// activated.
+func (f *Frontend) run(ctx context.Context, args ...string) error {
+	envBin := filepath.Join(f.WorkDir, "env", "bin")
+	env := append(append([]string{}, f.Env...), "VIRTUAL_ENV="+filepath.Dir(envBin))
+	path := envBin
+	for _, kv := range f.Env {
+		if strings.HasPrefix(kv, "PATH=") {
+			path = envBin + ":" + kv[len("PATH="):]
+		}
+	}
+	env = append(env, "PATH="+path)
+
+	out := f.Log
+	if out == nil {
+		out = io.Discard
+	}
+	fmt.Fprintf(out, "+ %s\n", strings.Join(args, " "))
+
+	runner := f.Run
+	if runner == nil {
+		runner = hostRunner
+	}
+	return runner(ctx, args, f.SourceDir, env, out)
+}
+
+// configSettings returns the config settings, or nil if there are none.
+func (f *Frontend) configSettings() map[string]string {
+	if len(f.ConfigSettings) == 0 {
+		return nil
+	}
+	return f.ConfigSettings
+}
+
+// hostRunner runs a command directly on the host.
+func hostRunner(ctx context.Context, args []string, dir string, env []string, out io.Writer) error {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// wheels returns the set of wheel filenames in dir.
+func wheels(dir string) (map[string]bool, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.whl"))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		result[filepath.Base(m)] = true
+	}
+	return result, nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
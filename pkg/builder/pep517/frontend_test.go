@@ -0,0 +1,198 @@
+package pep517
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inTreeBackend is a minimal PEP 517 backend that needs no downloads.
+const inTreeBackend = `import json
+import os
+import zipfile
+
+
+def get_requires_for_build_wheel(config_settings=None):
+    return []
+
+
+def build_wheel(wheel_directory, config_settings=None, metadata_directory=None):
+    name = "demo-1.0-py3-none-any.whl"
+    with zipfile.ZipFile(os.path.join(wheel_directory, name), "w") as zf:
+        zf.writestr("settings.json", json.dumps(config_settings or {}))
+    return name
+`
+
+func testPython(t *testing.T) string {
+	t.Helper()
+	for _, py := range []string{"/usr/bin/python3.11", "python3"} {
+		if path, err := exec.LookPath(py); err == nil {
+			return path
+		}
+	}
+	t.Skip("python3 not available")
+	return ""
+}
+
+func TestFrontendBuildInTreeBackend(t *testing.T) {
+	python := testPython(t)
+
+	src := t.TempDir()
+	files := map[string]string{
+		"pyproject.toml": "[build-system]\nrequires = []\nbuild-backend = \"backend\"\nbackend-path = [\".\"]\n",
+		"backend.py":     inTreeBackend,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var log bytes.Buffer
+	f := &Frontend{
+		Python:         python,
+		SourceDir:      src,
+		OutDir:         filepath.Join(t.TempDir(), "dist"),
+		WorkDir:        filepath.Join(t.TempDir(), "work"),
+		ConfigSettings: map[string]string{"--build-option": "--fast"},
+		Env:            os.Environ(),
+		Log:            &log,
+	}
+
+	wheel, err := f.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build() error = %v\n%s", err, log.String())
+	}
+	if filepath.Base(wheel) != "demo-1.0-py3-none-any.whl" {
+		t.Errorf("wheel = %q", wheel)
+	}
+
+	zr, err := zip.OpenReader(wheel)
+	if err != nil {
+		t.Fatalf("opening wheel: %v", err)
+	}
+	defer zr.Close()
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var settings bytes.Buffer
+	settings.ReadFrom(rc)
+	if !strings.Contains(settings.String(), `"--build-option": "--fast"`) {
+		t.Errorf("config_settings not passed to backend: %s", settings.String())
+	}
+
+	// Requirements are empty, so pip must not have been bootstrapped
+	if strings.Contains(log.String(), "ensurepip") {
+		t.Errorf("unexpected pip bootstrap:\n%s", log.String())
+	}
+}
+
+func TestFrontendBuildHookFailure(t *testing.T) {
+	python := testPython(t)
+
+	src := t.TempDir()
+	files := map[string]string{
+		"pyproject.toml": "[build-system]\nrequires = []\nbuild-backend = \"backend\"\nbackend-path = [\".\"]\n",
+		"backend.py":     "def build_wheel(wheel_directory, config_settings=None, metadata_directory=None):\n    raise RuntimeError('compiler exploded')\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var log bytes.Buffer
+	f := &Frontend{
+		Python:    python,
+		SourceDir: src,
+		OutDir:    filepath.Join(t.TempDir(), "dist"),
+		WorkDir:   filepath.Join(t.TempDir(), "work"),
+		Env:       os.Environ(),
+		Log:       &log,
+	}
+
+	_, err := f.Build(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "build_wheel") {
+		t.Fatalf("Build() error = %v, want build_wheel failure", err)
+	}
+	if !strings.Contains(log.String(), "compiler exploded") {
+		t.Errorf("log missing backend error:\n%s", log.String())
+	}
+}
+
+func TestFrontendLegacyFallback(t *testing.T) {
+	var commands []string
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "no pyproject",
+			files: map[string]string{"setup.py": ""},
+		},
+		{
+			name:  "pyproject without backend",
+			files: map[string]string{"setup.py": "", "pyproject.toml": "[tool.black]\n"},
+		},
+		{
+			name:    "no build configuration",
+			files:   map[string]string{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			outDir := t.TempDir()
+
+			commands = nil
+			f := &Frontend{
+				Python:    "python3",
+				SourceDir: src,
+				OutDir:    outDir,
+				WorkDir:   filepath.Join(t.TempDir(), "work"),
+				Run: func(ctx context.Context, args []string, dir string, env []string, out io.Writer) error {
+					commands = append(commands, strings.Join(args[1:], " "))
+					if len(args) > 1 && args[1] == "setup.py" {
+						return os.WriteFile(filepath.Join(outDir, "demo-1.0-py3-none-any.whl"), nil, 0644)
+					}
+					return nil
+				},
+			}
+
+			wheel, err := f.Build(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Build() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if filepath.Base(wheel) != "demo-1.0-py3-none-any.whl" {
+				t.Errorf("wheel = %q", wheel)
+			}
+			got := strings.Join(commands, "\n")
+			if !strings.Contains(got, "setuptools>=40.8.0 wheel") || !strings.Contains(got, "setup.py bdist_wheel -d "+outDir) {
+				t.Errorf("commands:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package pep517 implements a PEP 517 build frontend: it reads
+// pyproject.toml, creates an isolated build environment, installs the build
+// requirements and calls the backend's wheel hooks through a Python shim.
+package pep517
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// PyProject holds the parts of pyproject.toml the build system needs.
+type PyProject struct {
+	// BuildSystem is the [build-system] table.
+	BuildSystem BuildSystem
+
+	// Project is the [project] table.
+	Project Project
+
+	// root is the parsed document for lookups not covered above.
+	root map[string]interface{}
+}
+
+// BuildSystem is the PEP 518 [build-system] table.
+type BuildSystem struct {
+	// Requires are the PEP 508 build requirements.
+	Requires []string
+
+	// BuildBackend is the backend object reference (e.g., "setuptools.build_meta").
+	BuildBackend string
+
+	// BackendPath are in-tree directories to prepend to sys.path.
+	BackendPath []string
+}
+
+// Project is the PEP 621 [project] table.
+type Project struct {
+	// Name is the project name.
+	Name string
+
+	// Version is the static project version, if declared.
+	Version string
+
+	// RequiresPython is the Requires-Python specifier (e.g., ">=3.9").
+	RequiresPython string
+}
+
+// LoadPyProject reads pyproject.toml from a source directory.
+// Returns nil without error if the file does not exist.
+func LoadPyProject(sourceDir string) (*PyProject, error) {
+	data, err := os.ReadFile(filepath.Join(sourceDir, "pyproject.toml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading pyproject.toml: %w", err)
+	}
+	return ParsePyProject(data)
+}
+
+// ParsePyProject parses the contents of a pyproject.toml file.
+func ParsePyProject(data []byte) (*PyProject, error) {
+	var root map[string]interface{}
+	if err := toml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing pyproject.toml: %w", err)
+	}
+
+	pp := &PyProject{root: root}
+	if bs := pp.table("build-system"); bs != nil {
+		pp.BuildSystem.Requires = stringList(bs["requires"])
+		pp.BuildSystem.BuildBackend, _ = bs["build-backend"].(string)
+		pp.BuildSystem.BackendPath = stringList(bs["backend-path"])
+	}
+	if proj := pp.table("project"); proj != nil {
+		pp.Project.Name, _ = proj["name"].(string)
+		pp.Project.Version, _ = proj["version"].(string)
+		pp.Project.RequiresPython, _ = proj["requires-python"].(string)
+	}
+	return pp, nil
+}
+
+// HasTable reports whether the file declares a table (e.g., "tool.maturin"),
+// by header, dotted keys or inline. The name is a dotted path of bare keys.
+func (pp *PyProject) HasTable(name string) bool {
+	return pp.table(name) != nil
+}
+
+// Get returns the value of key in table, or nil.
+func (pp *PyProject) Get(table, key string) interface{} {
+	return pp.table(table)[key]
+}
+
+// table returns the table at a dotted path of bare keys, or nil.
+func (pp *PyProject) table(name string) map[string]interface{} {
+	table := pp.root
+	for _, part := range strings.Split(name, ".") {
+		next, ok := table[part].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		table = next
+	}
+	return table
+}
+
+// stringList converts a parsed TOML array to a list of strings,
+// dropping non-string elements.
+func stringList(v interface{}) []string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(arr))
+	for _, e := range arr {
+		if s, ok := e.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
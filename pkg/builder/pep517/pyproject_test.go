@@ -0,0 +1,140 @@
+package pep517
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePyProject(t *testing.T) {
+	data := `# Example project
+[build-system]
+requires = [
+    "setuptools>=61",  # modern metadata
+    'wheel',
+]
+build-backend = "setuptools.build_meta"
+backend-path = ["."]
+
+[project]
+name = "example"
+version = "1.0.0"
+requires-python = ">=3.9"
+dependencies = ["numpy>=1.21"]
+description = """
+A multi-line \
+description."""
+classifiers = []
+urls = { Homepage = "https://example.com", "Bug Tracker" = 'https://example.com/issues' }
+
+[tool.maturin]
+features = ["pyo3/extension-module"]
+strip = true
+
+[[tool.cibuildwheel.overrides]]
+select = "*-musllinux*"
+`
+	pp, err := ParsePyProject([]byte(data))
+	if err != nil {
+		t.Fatalf("ParsePyProject() error = %v", err)
+	}
+
+	wantBS := BuildSystem{
+		Requires:     []string{"setuptools>=61", "wheel"},
+		BuildBackend: "setuptools.build_meta",
+		BackendPath:  []string{"."},
+	}
+	if !reflect.DeepEqual(pp.BuildSystem, wantBS) {
+		t.Errorf("BuildSystem = %+v, want %+v", pp.BuildSystem, wantBS)
+	}
+
+	wantProj := Project{Name: "example", Version: "1.0.0", RequiresPython: ">=3.9"}
+	if pp.Project != wantProj {
+		t.Errorf("Project = %+v, want %+v", pp.Project, wantProj)
+	}
+
+	if got := pp.Get("project", "description"); got != "A multi-line description." {
+		t.Errorf("description = %q", got)
+	}
+	urls, _ := pp.Get("project", "urls").(map[string]interface{})
+	if urls["Bug Tracker"] != "https://example.com/issues" {
+		t.Errorf("urls = %v", urls)
+	}
+	if !pp.HasTable("tool.maturin") {
+		t.Error("HasTable(tool.maturin) = false")
+	}
+	if got := pp.Get("tool.maturin", "strip"); got != true {
+		t.Errorf("strip = %v, want true", got)
+	}
+	if pp.HasTable("tool.cibuildwheel.overrides") {
+		t.Error("array tables should not be recorded")
+	}
+}
+
+func TestParsePyProjectErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unterminated string", `[project]` + "\nname = \"example\n"},
+		{"unterminated array", "[build-system]\nrequires = [\"a\",\n"},
+		{"missing equals", "[project]\nname \"example\"\n"},
+		{"unterminated header", "[build-system\n"},
+		{"trailing garbage", "[project]\nname = \"a\" \"b\"\n"},
+		{"duplicate key", "[project]\nname = \"a\"\nname = \"b\"\n"},
+		{"duplicate table", "[project]\n[project]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePyProject([]byte(tt.data)); err == nil {
+				t.Error("ParsePyProject() expected error")
+			}
+		})
+	}
+}
+
+func TestLoadPyProjectMissing(t *testing.T) {
+	pp, err := LoadPyProject(t.TempDir())
+	if err != nil || pp != nil {
+		t.Errorf("LoadPyProject() = %v, %v, want nil, nil", pp, err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte("[tool.black]\nline-length = 88\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pp, err = LoadPyProject(dir)
+	if err != nil {
+		t.Fatalf("LoadPyProject() error = %v", err)
+	}
+	if pp.BuildSystem.BuildBackend != "" {
+		t.Errorf("BuildBackend = %q, want empty", pp.BuildSystem.BuildBackend)
+	}
+	if got := pp.Get("tool.black", "line-length"); got != int64(88) {
+		t.Errorf("line-length = %v, want 88", got)
+	}
+}
+
+func TestParsePyProjectDottedKeys(t *testing.T) {
+	data := `build-system.requires = ["maturin>=1.0"]
+build-system.build-backend = "maturin"
+project = { name = "example", requires-python = ">=3.10" }
+tool.maturin.strip = true
+`
+	pp, err := ParsePyProject([]byte(data))
+	if err != nil {
+		t.Fatalf("ParsePyProject() error = %v", err)
+	}
+	want := BuildSystem{Requires: []string{"maturin>=1.0"}, BuildBackend: "maturin"}
+	if !reflect.DeepEqual(pp.BuildSystem, want) {
+		t.Errorf("BuildSystem = %+v, want %+v", pp.BuildSystem, want)
+	}
+	if pp.Project.Name != "example" || pp.Project.RequiresPython != ">=3.10" {
+		t.Errorf("Project = %+v", pp.Project)
+	}
+	if !pp.HasTable("tool.maturin") || DetectBackend(pp) != BackendMaturin {
+		t.Errorf("HasTable(tool.maturin) = %v, DetectBackend() = %q", pp.HasTable("tool.maturin"), DetectBackend(pp))
+	}
+}
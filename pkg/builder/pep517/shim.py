@@ -0,0 +1,43 @@
+"""Call a PEP 517 backend hook in a fresh interpreter.
+
+Usage: shim.py HOOK BACKEND BACKEND_PATH_JSON KWARGS_JSON OUTPUT_JSON
+
+The hook's return value is written to OUTPUT_JSON as {"result": ...}.
+"""
+import importlib
+import json
+import os
+import sys
+
+
+def load_backend(ref, backend_path):
+    for p in reversed(backend_path):
+        sys.path.insert(0, os.path.abspath(p))
+    module_name, _, obj_path = ref.partition(":")
+    backend = importlib.import_module(module_name)
+    for attr in filter(None, obj_path.split(".")):
+        backend = getattr(backend, attr)
+    return backend
+
+
+def main():
+    hook, ref, backend_path, kwargs, output = sys.argv[1:6]
+    # The script's own directory must not shadow the backend
+    sys.path.pop(0)
+    backend = load_backend(ref, json.loads(backend_path))
+
+    fn = getattr(backend, hook, None)
+    if fn is None:
+        if hook.startswith("get_requires_for_"):
+            result = []
+        else:
+            sys.exit("backend %s has no hook %s" % (ref, hook))
+    else:
+        result = fn(**json.loads(kwargs))
+
+    with open(output, "w") as f:
+        json.dump({"result": result}, f)
+
+
+if __name__ == "__main__":
+    main()
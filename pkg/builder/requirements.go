@@ -0,0 +1,172 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// buildConstraintsDir holds the generated pip constraints file, relative to
+// WorkDir. Sandboxed executors mount it read-only.
+const buildConstraintsDir = ".build-constraints"
+
+// buildConstraintsFile is the generated pip constraints file, relative to
+// buildConstraintsDir.
+const buildConstraintsFile = "constraints.txt"
+
+// successfullyInstalledRe matches pip's summary line after installing packages.
+var successfullyInstalledRe = regexp.MustCompile(`(?m)Successfully installed (.+)$`)
+
+// writeBuildConstraints writes the effective build requirement constraints
+// to a file and points PIP_CONSTRAINT at it. pip passes the variable on to
+// the isolated build environment, so it pins PEP 517 build requirements.
+func (b *Builder) writeBuildConstraints(cfg *effectiveConfig) error {
+	if len(cfg.BuildRequires) == 0 && cfg.BuildConstraints == "" {
+		return nil
+	}
+
+	var sb strings.Builder
+	if cfg.BuildConstraints != "" {
+		data, err := os.ReadFile(filepath.Join(b.WorkDir, cfg.BuildConstraints))
+		if err != nil {
+			return fmt.Errorf("reading build constraints: %w", err)
+		}
+		sb.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	for _, r := range cfg.BuildRequires {
+		sb.WriteString(r + "\n")
+	}
+
+	dir := filepath.Join(b.WorkDir, buildConstraintsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating build constraints directory: %w", err)
+	}
+	path := filepath.Join(dir, buildConstraintsFile)
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("writing build constraints: %w", err)
+	}
+	cfg.Env["PIP_CONSTRAINT"] = path
+	cfg.ConstraintsDir = dir
+	return nil
+}
+
+// parseInstalledBuildRequires extracts "name==version" pins from pip's
+// "Successfully installed" lines in a build log.
+func parseInstalledBuildRequires(log string) []string {
+	seen := make(map[string]bool)
+	var pins []string
+	for _, m := range successfullyInstalledRe.FindAllStringSubmatch(log, -1) {
+		for _, dist := range strings.Fields(m[1]) {
+			i := strings.LastIndex(dist, "-")
+			if i <= 0 {
+				continue
+			}
+			pin := config.NormalizeName(dist[:i]) + "==" + dist[i+1:]
+			if !seen[pin] {
+				seen[pin] = true
+				pins = append(pins, pin)
+			}
+		}
+	}
+	sort.Strings(pins)
+	return pins
+}
+
+// RecordBuildRequirements stores the build requirements recorded in results
+// on each version whose builds all succeeded, ignoring Pythons the version
+// doesn't support. Packages installed at the same
+// version for every Python are pinned directly; others are pinned per Python
+// with an environment marker. Returns the number of versions updated.
+func (b *Builder) RecordBuildRequirements(results map[string][]BuildResult) int {
+	updated := 0
+	for version, rs := range results {
+		v := b.Config.FindVersion(version)
+		if v == nil || len(rs) == 0 {
+			continue
+		}
+
+		var built []BuildResult
+		complete := true
+		for _, r := range rs {
+			if r.NotApplicable {
+				continue
+			}
+			if !r.Success || r.BuildRequires == nil {
+				complete = false
+				break
+			}
+			built = append(built, r)
+		}
+		if !complete || len(built) == 0 {
+			continue
+		}
+
+		v.BuildRequires = mergeRecordedPins(built)
+		updated++
+	}
+	return updated
+}
+
+// mergeRecordedPins combines per-Python pins into one constraints list.
+// Not-applicable results are ignored.
+func mergeRecordedPins(results []BuildResult) []string {
+	// name -> python -> version
+	versions := make(map[string]map[string]string)
+	built := 0
+	for _, r := range results {
+		if r.NotApplicable {
+			continue
+		}
+		built++
+		for _, pin := range r.BuildRequires {
+			name, ver, ok := strings.Cut(pin, "==")
+			if !ok {
+				continue
+			}
+			if versions[name] == nil {
+				versions[name] = make(map[string]string)
+			}
+			versions[name][r.Python] = ver
+		}
+	}
+
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pins []string
+	for _, name := range names {
+		byPython := versions[name]
+		distinct := make(map[string]bool)
+		for _, ver := range byPython {
+			distinct[ver] = true
+		}
+
+		if len(distinct) == 1 && len(byPython) == built {
+			for ver := range distinct {
+				pins = append(pins, name+"=="+ver)
+			}
+			continue
+		}
+
+		pythons := make([]string, 0, len(byPython))
+		for py := range byPython {
+			pythons = append(pythons, py)
+		}
+		sort.Strings(pythons)
+		for _, py := range pythons {
+			pins = append(pins, fmt.Sprintf("%s==%s; python_version == %q", name, byPython[py], py))
+		}
+	}
+	return pins
+}
@@ -0,0 +1,178 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestEffectiveBuildRequires(t *testing.T) {
+	cfg := &config.Config{
+		Repo: "https://github.com/test/pkg",
+		Versions: []config.Version{
+			{Tag: "v1.0.0", Version: "1.0.0", BuildRequires: []string{"setuptools==65.5.0"}},
+			{Tag: "v2.0.0", Version: "2.0.0"},
+		},
+		BuildRequires:    []string{"setuptools<70", "wheel"},
+		BuildConstraints: "constraints/base.txt",
+		Overrides: []config.Override{
+			{Match: "<2.0", BuildRequires: []string{"cython<3"}, BuildConstraints: "constraints/old.txt"},
+		},
+	}
+	b := New("/tmp/build", "testpkg", cfg)
+
+	eff, err := b.getEffectiveConfig("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"wheel", "cython<3", "setuptools==65.5.0"}
+	if !reflect.DeepEqual(eff.BuildRequires, want) {
+		t.Errorf("1.0.0 BuildRequires = %v, want %v", eff.BuildRequires, want)
+	}
+	if eff.BuildConstraints != "constraints/old.txt" {
+		t.Errorf("1.0.0 BuildConstraints = %q, want %q", eff.BuildConstraints, "constraints/old.txt")
+	}
+
+	eff, err = b.getEffectiveConfig("2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"setuptools<70", "wheel"}
+	if !reflect.DeepEqual(eff.BuildRequires, want) {
+		t.Errorf("2.0.0 BuildRequires = %v, want %v", eff.BuildRequires, want)
+	}
+}
+
+func TestParseInstalledBuildRequires(t *testing.T) {
+	log := `Processing /build/src
+  Installing build dependencies: started
+  Successfully installed setuptools-69.0.3 wheel-0.42.0
+  Getting requirements to build wheel: started
+  Successfully installed Cython-3.0.8 typing_extensions-4.9.0
+Building wheels for collected packages: pkg
+`
+	got := parseInstalledBuildRequires(log)
+	want := []string{"cython==3.0.8", "setuptools==69.0.3", "typing-extensions==4.9.0", "wheel==0.42.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInstalledBuildRequires() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeRecordedPins(t *testing.T) {
+	results := []BuildResult{
+		{Python: "3.10", BuildRequires: []string{"numpy==1.21.6", "setuptools==69.0.3"}},
+		{Python: "3.12", BuildRequires: []string{"numpy==1.26.4", "setuptools==69.0.3"}},
+	}
+	got := mergeRecordedPins(results)
+	want := []string{
+		`numpy==1.21.6; python_version == "3.10"`,
+		`numpy==1.26.4; python_version == "3.12"`,
+		"setuptools==69.0.3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeRecordedPins() = %v, want %v", got, want)
+	}
+}
+
+func TestRecordBuildRequirementsNotApplicable(t *testing.T) {
+	cfg := &config.Config{Versions: []config.Version{{Tag: "v1.0.0", Version: "1.0.0"}}}
+	b := New(t.TempDir(), "testpkg", cfg)
+	results := map[string][]BuildResult{
+		"1.0.0": {
+			notApplicableResult("1.0.0", "3.9", ">=3.10"),
+			{Version: "1.0.0", Python: "3.11", Success: true, BuildRequires: []string{"setuptools==69.0.3"}},
+			{Version: "1.0.0", Python: "3.12", Success: true, BuildRequires: []string{"setuptools==69.0.3"}},
+		},
+	}
+
+	if n := b.RecordBuildRequirements(results); n != 1 {
+		t.Errorf("RecordBuildRequirements() = %d, want 1", n)
+	}
+	want := []string{"setuptools==69.0.3"}
+	if !reflect.DeepEqual(cfg.Versions[0].BuildRequires, want) {
+		t.Errorf("Versions[0].BuildRequires = %v, want %v", cfg.Versions[0].BuildRequires, want)
+	}
+}
+
+func TestBuildConstraintsAndRecording(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0")
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "constraints"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "constraints", "build.txt"), []byte("cython<3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Repo:             "file://" + upstream,
+		Versions:         []config.Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		BuildRequires:    []string{"setuptools<70"},
+		BuildConstraints: "constraints/build.txt",
+	}
+	b := New(dir, "testpkg", cfg)
+	b.RecordBuildRequires = true
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+	wheel := filepath.Join(b.DistDir, "testpkg-1.0.0-cp312-cp312-linux_x86_64.whl")
+	cfg.Script = fmt.Sprintf(`cat "$PIP_CONSTRAINT"
+echo "Successfully installed setuptools-69.0.3 Cython-0.29.37"
+touch %s`, wheel)
+
+	results := b.BuildAll([]string{"3.12"})
+	r := results["1.0.0"]
+	if len(r) != 1 || !r[0].Success {
+		t.Fatalf("BuildAll() failed: %+v", r)
+	}
+	for _, want := range []string{"cython<3\n", "setuptools<70\n"} {
+		if !strings.Contains(r[0].Log, want) {
+			t.Errorf("constraints file missing %q:\n%s", want, r[0].Log)
+		}
+	}
+
+	if n := b.RecordBuildRequirements(results); n != 1 {
+		t.Errorf("RecordBuildRequirements() = %d, want 1", n)
+	}
+	want := []string{"cython==0.29.37", "setuptools==69.0.3"}
+	if !reflect.DeepEqual(cfg.Versions[0].BuildRequires, want) {
+		t.Errorf("Versions[0].BuildRequires = %v, want %v", cfg.Versions[0].BuildRequires, want)
+	}
+}
+
+func TestExecSpecBuildConstraintsOCI(t *testing.T) {
+	cfg := &config.Config{BuildRequires: []string{"setuptools<70"}}
+	b := New(t.TempDir(), "testpkg", cfg)
+	eff, err := b.getEffectiveConfig("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.writeBuildConstraints(eff); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := b.execSpec([]string{"true"}, eff, "3.12", nil)
+	constraints := envValue(spec.Env, "PIP_CONSTRAINT")
+	if constraints == "" {
+		t.Fatal("PIP_CONSTRAINT not set")
+	}
+
+	// The container only sees mounted directories
+	args := strings.Join((&OCIExecutor{}).args(spec), " ")
+	dir := filepath.Dir(constraints)
+	for _, want := range []string{"-v " + dir + ":" + dir + ":ro", "-e PIP_CONSTRAINT=" + constraints} {
+		if !strings.Contains(args, want) {
+			t.Errorf("oci args missing %q: %s", want, args)
+		}
+	}
+	if dir == b.WorkDir {
+		t.Errorf("constraints should be in their own directory, not all of %s", b.WorkDir)
+	}
+}
@@ -0,0 +1,121 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// setupPyRequiresRe matches python_requires="..." in setup.py.
+var setupPyRequiresRe = regexp.MustCompile(`python_requires\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// SourceRequiresPython reads the Requires-Python specifier from a source
+// tree: pyproject.toml [project] requires-python, then setup.cfg
+// [options] python_requires, then python_requires in setup.py.
+// Returns "" if none is declared.
+func SourceRequiresPython(sourceDir string) (string, error) {
+	pp, err := pep517.LoadPyProject(sourceDir)
+	if err != nil {
+		return "", err
+	}
+	if pp != nil && pp.Project.RequiresPython != "" {
+		return pp.Project.RequiresPython, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(sourceDir, "setup.cfg"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading setup.cfg: %w", err)
+	}
+	if spec := setupCfgRequiresPython(data); spec != "" {
+		return spec, nil
+	}
+
+	data, err = os.ReadFile(filepath.Join(sourceDir, "setup.py"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading setup.py: %w", err)
+	}
+	if m := setupPyRequiresRe.FindSubmatch(data); m != nil {
+		return strings.TrimSpace(string(m[1]) + string(m[2])), nil
+	}
+	return "", nil
+}
+
+// setupCfgRequiresPython returns python_requires from the [options]
+// section of a setup.cfg.
+func setupCfgRequiresPython(data []byte) string {
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != "options" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if ok && strings.TrimSpace(key) == "python_requires" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// pypiTimeout bounds the PyPI lookup for a version's Requires-Python.
+const pypiTimeout = 30 * time.Second
+
+// requiresPython returns the Requires-Python of the checked-out (and
+// patched) version, falling back to PyPI metadata if the source doesn't
+// declare one. PyPI is best effort: if it can't be reached, every Python
+// is built.
+func (b *Builder) requiresPython(version string) (string, error) {
+	spec, err := SourceRequiresPython(b.SourceDir)
+	if err != nil {
+		return "", fmt.Errorf("reading requires-python: %w", err)
+	}
+	if spec != "" || b.PyPI == nil {
+		return spec, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pypiTimeout)
+	defer cancel()
+	p, err := b.PyPI.Project(ctx, b.PackageName)
+	if err != nil {
+		return "", nil
+	}
+	return p.RequiresPython(version), nil
+}
+
+// PythonApplicable reports whether a Requires-Python specifier allows a
+// Python version. Unknown or unparseable specifiers allow every version.
+func PythonApplicable(python, requiresPython string) bool {
+	ok, err := versions.Match(python, requiresPython)
+	return err != nil || ok
+}
+
+// notApplicableResult is the result of a cell whose Requires-Python
+// excludes its Python version.
+func notApplicableResult(version, python, requiresPython string) BuildResult {
+	return BuildResult{
+		Version:        version,
+		Python:         python,
+		NotApplicable:  true,
+		RequiresPython: requiresPython,
+		Log:            fmt.Sprintf("not applicable: requires-python %q excludes Python %s", requiresPython, python),
+	}
+}
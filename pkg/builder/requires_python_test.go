@@ -0,0 +1,208 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestSourceRequiresPython(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "pyproject",
+			files: map[string]string{
+				"pyproject.toml": "[project]\nname = \"pkg\"\nrequires-python = \">=3.9\"\n",
+				"setup.cfg":      "[options]\npython_requires = >=3.6\n",
+			},
+			want: ">=3.9",
+		},
+		{
+			name: "setup.cfg",
+			files: map[string]string{
+				"pyproject.toml": "[build-system]\nrequires = [\"setuptools\"]\n",
+				"setup.cfg":      "[metadata]\nname = pkg\n\n[options]\npackages = find:\npython_requires = >=3.7, <3.11\n",
+			},
+			want: ">=3.7, <3.11",
+		},
+		{
+			name: "setup.py",
+			files: map[string]string{
+				"setup.py": "from setuptools import setup\nsetup(name='pkg', python_requires='>=3.8')\n",
+			},
+			want: ">=3.8",
+		},
+		{
+			name:  "undeclared",
+			files: map[string]string{"setup.py": "from setuptools import setup\nsetup(name='pkg')\n"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := SourceRequiresPython(dir)
+			if err != nil {
+				t.Fatalf("SourceRequiresPython() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SourceRequiresPython() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPythonApplicable(t *testing.T) {
+	tests := []struct {
+		python string
+		spec   string
+		want   bool
+	}{
+		{"3.12", "", true},
+		{"3.10", ">=3.11", false},
+		{"3.12", "<3.11", false},
+		{"3.10", ">=3.8,!=3.9.*", true},
+		{"3.12", "not a specifier", true},
+	}
+	for _, tt := range tests {
+		if got := PythonApplicable(tt.python, tt.spec); got != tt.want {
+			t.Errorf("PythonApplicable(%q, %q) = %v, want %v", tt.python, tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestBuildNotApplicable(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "init", "-q")
+	for tag, spec := range map[string]string{"v1.0.0": ">=3.11", "v0.9.0": "<3.10"} {
+		pyproject := "[project]\nname = \"testpkg\"\nrequires-python = \"" + spec + "\"\n"
+		if err := os.WriteFile(filepath.Join(upstream, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, upstream, "add", ".")
+		runGit(t, upstream, "commit", "-q", "-m", tag)
+		runGit(t, upstream, "tag", tag)
+	}
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo: "file://" + upstream,
+		// Produces wheels for every Python without needing them installed
+		Script: `echo built >> ../builds && for cp in 310 311 312; do touch ../dist/testpkg-1.0.0-cp$cp-cp$cp-linux_x86_64.whl; done`,
+	}
+	b := New(dir, "testpkg", cfg)
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.10", "3.11", "3.12"})
+	if len(results) != 3 {
+		t.Fatalf("Build() returned %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Python != "3.10" || !r.NotApplicable || r.Success || r.FailureClass != "" || r.RequiresPython != ">=3.11" {
+		t.Errorf("3.10 result = %+v, want not applicable", r)
+	}
+	for _, r := range results[1:] {
+		if !r.Success || r.NotApplicable || r.RequiresPython != ">=3.11" {
+			t.Errorf("%s result = %+v, want success", r.Python, r)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "builds"))
+	if got := len(data) / len("built\n"); got != 2 {
+		t.Errorf("built %d cells, want 2", got)
+	}
+
+	// No applicable Pythons: nothing is built
+	results = b.Build(config.Version{Tag: "v0.9.0", Version: "0.9.0"}, []string{"3.11", "3.12"})
+	for _, r := range results {
+		if !r.NotApplicable {
+			t.Errorf("%s result = %+v, want not applicable", r.Python, r)
+		}
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "builds"))
+	if got := len(data) / len("built\n"); got != 2 {
+		t.Errorf("built %d cells after inapplicable version, want 2", got)
+	}
+}
+
+func TestBuildRequiresPythonPatched(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, upstream, "init", "-q")
+	for _, tag := range []struct{ name, pyproject string }{
+		{"v1.0.0", "[project]\nname = \"testpkg\"\nrequires-python = \">=3.11\"\n"},
+		{"v2.0.0", "[project\nname = \"testpkg\"\n"},
+	} {
+		if err := os.WriteFile(filepath.Join(upstream, "pyproject.toml"), []byte(tag.pyproject), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, upstream, "add", ".")
+		runGit(t, upstream, "commit", "-q", "-m", tag.name)
+		runGit(t, upstream, "tag", tag.name)
+	}
+
+	dir := t.TempDir()
+	patch := `--- a/pyproject.toml
++++ b/pyproject.toml
+@@ -1,3 +1,3 @@
+ [project]
+ name = "testpkg"
+-requires-python = ">=3.11"
++requires-python = ">=3.12"
+`
+	if err := os.MkdirAll(filepath.Join(dir, "patches"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "patches", "py.patch"), []byte(patch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Repo:    "file://" + upstream,
+		Patches: []config.Patch{{Path: "patches/py.patch", Match: "<2.0"}},
+		Script:  `for cp in 311 312; do touch ../dist/testpkg-1.0.0-cp$cp-cp$cp-linux_x86_64.whl; done`,
+	}
+	b := New(dir, "testpkg", cfg)
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The patched specifier decides which Pythons apply
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.11", "3.12"})
+	if len(results) != 2 {
+		t.Fatalf("Build() returned %d results, want 2", len(results))
+	}
+	if r := results[0]; !r.NotApplicable || r.RequiresPython != ">=3.12" {
+		t.Errorf("3.11 result = %+v, want not applicable under the patched specifier", r)
+	}
+	if r := results[1]; !r.Success {
+		t.Errorf("3.12 result = %+v, want success", r)
+	}
+
+	// An unreadable pyproject.toml fails the version
+	results = b.Build(config.Version{Tag: "v2.0.0", Version: "2.0.0"}, []string{"3.12"})
+	if len(results) != 1 || results[0].Success || !strings.Contains(results[0].Log, "requires-python") {
+		t.Errorf("Build(2.0.0) = %+v, want a requires-python failure", results)
+	}
+}
@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ResourceLimits are enforced on a build command. Zero fields are unlimited.
+type ResourceLimits struct {
+	// MemoryBytes caps the memory of the command's process tree.
+	MemoryBytes int64
+
+	// CPUs caps CPU bandwidth in cores. Without cgroups it is approximated
+	// with a CPU time limit of CPUs × the remaining wall-clock time.
+	CPUs float64
+}
+
+// IsZero reports whether no limits are set.
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// ResourceUsage is the resource accounting for one or more commands.
+type ResourceUsage struct {
+	// PeakRSS is the peak resident memory in bytes.
+	PeakRSS int64
+
+	// CPUTime is the user plus system CPU time.
+	CPUTime time.Duration
+
+	// OOMKilled reports whether the memory limit killed a process.
+	OOMKilled bool
+}
+
+// add accumulates the usage of another command run in sequence.
+func (u *ResourceUsage) add(o ResourceUsage) {
+	if o.PeakRSS > u.PeakRSS {
+		u.PeakRSS = o.PeakRSS
+	}
+	u.CPUTime += o.CPUTime
+	u.OOMKilled = u.OOMKilled || o.OOMKilled
+}
+
+// resolveResources parses the effective resource limits into cfg.
+func (b *Builder) resolveResources(cfg *effectiveConfig) error {
+	mem, err := cfg.Resources.MemoryBytes()
+	if err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	cpus, err := cfg.Resources.CPUCores()
+	if err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	timeout, err := cfg.Resources.TimeoutDuration()
+	if err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	cfg.Limits = ResourceLimits{MemoryBytes: mem, CPUs: cpus}
+	cfg.Timeout = timeout
+	return nil
+}
+
+// rlimitCommand wraps argv in a shell that sets rlimits approximating
+// limits, for hosts without a usable cgroup v2 hierarchy.
+func rlimitCommand(ctx context.Context, limits ResourceLimits, argv []string) []string {
+	script := ""
+	if limits.MemoryBytes > 0 {
+		// ulimit -v takes KiB and caps address space, which overcounts
+		// shared mappings but is the only memory rlimit Linux enforces
+		script += "ulimit -v " + strconv.FormatInt(limits.MemoryBytes/1024, 10) + " && "
+	}
+	if deadline, ok := ctx.Deadline(); ok && limits.CPUs > 0 {
+		seconds := int64(math.Ceil(limits.CPUs * time.Until(deadline).Seconds()))
+		if seconds > 0 {
+			script += "ulimit -t " + strconv.FormatInt(seconds, 10) + " && "
+		}
+	}
+	if script == "" {
+		return argv
+	}
+	return append([]string{"sh", "-c", script + `exec "$@"`, "sh"}, argv...)
+}
+
+// diskUsage returns the bytes used by the build directories for a Python version.
+func (b *Builder) diskUsage(python string) int64 {
+	return dirSize(b.SourceDir) + dirSize(filepath.Join(b.WorkDir, "pep517", python))
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,196 @@
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// CgroupEnvVar names a delegated cgroup v2 directory to create build
+// cgroups under (default: the agent's own cgroup).
+const CgroupEnvVar = "SUPERWHEELIE_CGROUP"
+
+// cgroupFS is the cgroup v2 mount point.
+const cgroupFS = "/sys/fs/cgroup"
+
+// cgroupPeriod is the cpu.max period in microseconds.
+const cgroupPeriod = 100000
+
+var cgroupSeq atomic.Int64
+
+// cgroup is a transient cgroup v2 holding a single build command.
+type cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+// newCgroup creates a cgroup enforcing limits. Returns nil without error
+// if cgroup v2 is unavailable or not delegated to us.
+func newCgroup(limits ResourceLimits) *cgroup {
+	parent := cgroupParent()
+	if parent == "" {
+		return nil
+	}
+
+	dir := filepath.Join(parent, fmt.Sprintf("superwheelie-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil
+	}
+	cg := &cgroup{dir: dir}
+
+	if !cg.hasControllers("memory", "cpu") {
+		// Try enabling them; this fails if the parent has processes of its own
+		os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+		if !cg.hasControllers("memory", "cpu") {
+			cg.close()
+			return nil
+		}
+	}
+
+	if limits.MemoryBytes > 0 {
+		if err := cg.write("memory.max", strconv.FormatInt(limits.MemoryBytes, 10)); err != nil {
+			cg.close()
+			return nil
+		}
+		// Swapping would hide the limit; not every kernel has swap accounting
+		cg.write("memory.swap.max", "0")
+	}
+	if limits.CPUs > 0 {
+		quota := int64(limits.CPUs * cgroupPeriod)
+		if err := cg.write("cpu.max", fmt.Sprintf("%d %d", quota, cgroupPeriod)); err != nil {
+			cg.close()
+			return nil
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		cg.close()
+		return nil
+	}
+	cg.fd = fd
+	return cg
+}
+
+// cgroupParent returns the cgroup directory to create build cgroups under,
+// or "" if there is no cgroup v2 hierarchy.
+func cgroupParent() string {
+	if dir := os.Getenv(CgroupEnvVar); dir != "" {
+		return dir
+	}
+	if _, err := os.Stat(filepath.Join(cgroupFS, "cgroup.controllers")); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupFS, path)
+		}
+	}
+	return ""
+}
+
+// hasControllers reports whether all controllers are enabled in the cgroup.
+func (cg *cgroup) hasControllers(names ...string) bool {
+	data, err := os.ReadFile(filepath.Join(cg.dir, "cgroup.controllers"))
+	if err != nil {
+		return false
+	}
+	enabled := strings.Fields(string(data))
+	for _, name := range names {
+		found := false
+		for _, e := range enabled {
+			if e == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (cg *cgroup) write(file, value string) error {
+	return os.WriteFile(filepath.Join(cg.dir, file), []byte(value), 0644)
+}
+
+// attach starts cmd directly inside the cgroup.
+func (cg *cgroup) attach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.fd.Fd())
+}
+
+// usage reads the cgroup's accounting, which covers every process that ran
+// in it including daemonized compiler servers.
+func (cg *cgroup) usage() ResourceUsage {
+	var u ResourceUsage
+	if data, err := os.ReadFile(filepath.Join(cg.dir, "memory.peak")); err == nil {
+		u.PeakRSS, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	if usec, ok := cg.stat("cpu.stat", "usage_usec"); ok {
+		u.CPUTime = time.Duration(usec) * time.Microsecond
+	}
+	if kills, ok := cg.stat("memory.events", "oom_kill"); ok {
+		u.OOMKilled = kills > 0
+	}
+	return u
+}
+
+// stat returns a value from a flat-keyed cgroup file.
+func (cg *cgroup) stat(file, key string) (int64, bool) {
+	f, err := os.Open(filepath.Join(cg.dir, file))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), " ")
+		if ok && k == key {
+			n, err := strconv.ParseInt(v, 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// close kills any remaining processes and removes the cgroup.
+func (cg *cgroup) close() {
+	if cg.fd != nil {
+		cg.fd.Close()
+	}
+	cg.write("cgroup.kill", "1")
+	// The kill is asynchronous; retry until the cgroup is empty
+	for i := 0; i < 50; i++ {
+		if err := os.Remove(cg.dir); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processUsage returns the accounting of a finished process and the
+// descendants it waited for.
+func processUsage(state *os.ProcessState) ResourceUsage {
+	u := ResourceUsage{CPUTime: state.UserTime() + state.SystemTime()}
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// ru_maxrss is in KiB on Linux
+		u.PeakRSS = ru.Maxrss * 1024
+	}
+	return u
+}
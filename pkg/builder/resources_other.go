@@ -0,0 +1,25 @@
+//go:build !linux
+
+package builder
+
+import (
+	"os"
+	"os/exec"
+)
+
+// cgroup is unsupported outside Linux.
+type cgroup struct{}
+
+// newCgroup returns nil; limits fall back to rlimits.
+func newCgroup(limits ResourceLimits) *cgroup { return nil }
+
+func (cg *cgroup) attach(cmd *exec.Cmd) {}
+
+func (cg *cgroup) usage() ResourceUsage { return ResourceUsage{} }
+
+func (cg *cgroup) close() {}
+
+// processUsage returns the CPU time of a finished process.
+func processUsage(state *os.ProcessState) ResourceUsage {
+	return ResourceUsage{CPUTime: state.UserTime() + state.SystemTime()}
+}
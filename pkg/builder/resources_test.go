@@ -0,0 +1,157 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestRlimitCommand(t *testing.T) {
+	argv := []string{"python3", "-m", "pip", "wheel", "."}
+
+	if got := rlimitCommand(context.Background(), ResourceLimits{}, argv); !reflect.DeepEqual(got, argv) {
+		t.Errorf("no limits: got %v, want %v", got, argv)
+	}
+
+	got := rlimitCommand(context.Background(), ResourceLimits{MemoryBytes: 1 << 30, CPUs: 2}, argv)
+	want := append([]string{"sh", "-c", `ulimit -v 1048576 && exec "$@"`, "sh"}, argv...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("memory limit: got %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got = rlimitCommand(ctx, ResourceLimits{CPUs: 2}, argv)
+	if len(got) != len(argv)+4 || got[2] != `ulimit -t 20 && exec "$@"` {
+		t.Errorf("CPU limit: got %v", got)
+	}
+}
+
+func TestRunCommandMemoryLimit(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+
+	spec := &ExecSpec{Limits: ResourceLimits{MemoryBytes: 256 << 20}}
+	result := runCommand(context.Background(), "", nil, spec,
+		[]string{python, "-c", "x = bytearray(1024 * 1024 * 1024)"})
+	if result.Error == nil {
+		t.Fatal("allocation above the memory limit succeeded")
+	}
+	if !result.Usage.OOMKilled && ClassifyFailure(result.CombinedOutput()) != FailureResourceLimit {
+		t.Errorf("failure not attributed to the memory limit:\n%s", result.CombinedOutput())
+	}
+
+	result = runCommand(context.Background(), "", nil, spec,
+		[]string{python, "-c", "x = bytearray(64 * 1024 * 1024); x[::4096] = b'x' * len(x[::4096])"})
+	if result.Error != nil {
+		t.Fatalf("allocation below the memory limit failed: %v\n%s", result.Error, result.CombinedOutput())
+	}
+	if result.Usage.PeakRSS < 64<<20 {
+		t.Errorf("PeakRSS = %d, want at least 64MiB", result.Usage.PeakRSS)
+	}
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	result := runCommand(context.Background(), "", nil, &ExecSpec{},
+		[]string{"sh", "-c", "i=0; while [ $i -lt 200000 ]; do i=$((i+1)); done"})
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.Usage.CPUTime <= 0 {
+		t.Errorf("CPUTime = %v, want > 0", result.Usage.CPUTime)
+	}
+	if result.Usage.PeakRSS <= 0 {
+		t.Errorf("PeakRSS = %d, want > 0", result.Usage.PeakRSS)
+	}
+}
+
+func TestClassifyExecFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if got := classifyExecFailure(ctx, ResourceUsage{}, ""); got != FailureTimeout {
+		t.Errorf("expired context: got %q, want %q", got, FailureTimeout)
+	}
+
+	if got := classifyExecFailure(context.Background(), ResourceUsage{OOMKilled: true}, ""); got != FailureResourceLimit {
+		t.Errorf("OOM kill: got %q, want %q", got, FailureResourceLimit)
+	}
+
+	log := "cc1plus: out of memory allocating 65536 bytes"
+	if got := classifyExecFailure(context.Background(), ResourceUsage{}, log); got != FailureResourceLimit {
+		t.Errorf("log: got %q, want %q", got, FailureResourceLimit)
+	}
+}
+
+func TestResolveResources(t *testing.T) {
+	cfg := &config.Config{
+		Resources: config.Resources{Memory: "8Gi"},
+		Overrides: []config.Override{
+			{Match: ">=2.0", Resources: config.Resources{Memory: "16Gi", Timeout: "30m"}},
+		},
+	}
+	b := New("/tmp/build", "testpkg", cfg)
+	b.Resources = config.Resources{CPUs: "4", Timeout: "2h"}
+
+	tests := []struct {
+		version     string
+		wantLimits  ResourceLimits
+		wantTimeout time.Duration
+	}{
+		{"1.0", ResourceLimits{MemoryBytes: 8 << 30, CPUs: 4}, 2 * time.Hour},
+		{"2.0", ResourceLimits{MemoryBytes: 16 << 30, CPUs: 4}, 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			eff, err := b.getEffectiveConfig(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := b.resolveResources(eff); err != nil {
+				t.Fatal(err)
+			}
+			if eff.Limits != tt.wantLimits {
+				t.Errorf("Limits = %+v, want %+v", eff.Limits, tt.wantLimits)
+			}
+			if eff.Timeout != tt.wantTimeout {
+				t.Errorf("Timeout = %v, want %v", eff.Timeout, tt.wantTimeout)
+			}
+		})
+	}
+
+	b.Resources = config.Resources{}
+	eff, err := New("/tmp/build", "testpkg", &config.Config{}).getEffectiveConfig("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.resolveResources(eff); err != nil || eff.Timeout != DefaultTimeout {
+		t.Errorf("default timeout = %v, %v, want %v", eff.Timeout, err, DefaultTimeout)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a": 100, "sub/b": 250} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := dirSize(dir); got != 350 {
+		t.Errorf("dirSize() = %d, want 350", got)
+	}
+	if got := dirSize(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("dirSize(missing) = %d, want 0", got)
+	}
+}
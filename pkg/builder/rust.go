@@ -0,0 +1,212 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// cratesIOVendorConfig replaces crates.io with a vendored directory, for
+// crate tarballs that don't carry cargo vendor's own config.
+const cratesIOVendorConfig = `[source.crates-io]
+replace-with = "vendored-sources"
+
+[source.vendored-sources]
+directory = %q
+`
+
+// applyRustToolchain pins the rust system dep to the configured toolchain
+// version, replacing any unpinned "rust" entry, and selects it for rustup.
+func applyRustToolchain(cfg *effectiveConfig) {
+	toolchain := cfg.Rust.Toolchain
+	if toolchain == "" {
+		return
+	}
+	deps := make([]string, 0, len(cfg.SystemDeps)+1)
+	for _, dep := range cfg.SystemDeps {
+		if apkPackageName(dep) != "rust" {
+			deps = append(deps, dep)
+		}
+	}
+	cfg.SystemDeps = append(deps, "rust~"+toolchain)
+	if _, ok := cfg.Env["RUSTUP_TOOLCHAIN"]; !ok {
+		cfg.Env["RUSTUP_TOOLCHAIN"] = toolchain
+	}
+}
+
+// cargoHome returns the local cargo home crates are resolved from.
+func (b *Builder) cargoHome() string {
+	if b.CargoHome != "" {
+		return b.CargoHome
+	}
+	return os.Getenv("CARGO_HOME")
+}
+
+// vendorDir is where crates are vendored for the current version.
+func (b *Builder) vendorDir() string {
+	return filepath.Join(b.WorkDir, "cargo-vendor")
+}
+
+// vendorHome is the cargo home of vendored builds, holding only the
+// config that points cargo at vendorDir.
+func (b *Builder) vendorHome() string {
+	return filepath.Join(b.WorkDir, "cargo-home")
+}
+
+// cargoEnv returns the cargo environment for a build: the vendored cargo
+// home when vendoring, otherwise the local cargo home, offline when the
+// network is isolated. The target for the build platform is selected
+// from rust.targets.
+func (b *Builder) cargoEnv() map[string]string {
+	env := make(map[string]string)
+	switch {
+	case b.Config.Rust.CargoVendor:
+		env["CARGO_HOME"] = b.vendorHome()
+		env["CARGO_NET_OFFLINE"] = "true"
+	case b.CargoHome != "":
+		env["CARGO_HOME"] = b.CargoHome
+		if b.Network != nil && b.Network.Isolated {
+			env["CARGO_NET_OFFLINE"] = "true"
+		}
+	}
+	_, arch, _ := strings.Cut(b.platform(), "_")
+	if target := b.Config.Rust.Target(arch); target != "" {
+		env["CARGO_BUILD_TARGET"] = target
+	}
+	return env
+}
+
+// cargoDirs returns the directories cargo reads and writes during a
+// build, which sandboxed executors must expose.
+func (b *Builder) cargoDirs() (readOnly, writable []string) {
+	switch {
+	case b.Config.Rust.CargoVendor:
+		return []string{b.vendorDir()}, []string{b.vendorHome()}
+	case b.CargoHome != "":
+		return nil, []string{b.CargoHome}
+	}
+	return nil, nil
+}
+
+// vendorCrates vendors the Rust dependencies of the checked-out source
+// when rust.cargo_vendor is set. The version's crate tarball
+// (cfg.CrateTarball) is used if there is one; otherwise cargo vendor runs
+// offline against the local cargo home, through the build's executor and
+// with its environment and limits. Either way the vendored cargo home is
+// left pointing at the vendored crates.
+func (b *Builder) vendorCrates(cfg *effectiveConfig) error {
+	if !b.Config.Rust.CargoVendor {
+		return nil
+	}
+
+	vendorDir, home := b.vendorDir(), b.vendorHome()
+	for _, dir := range []string{vendorDir, home} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("vendoring crates: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("vendoring crates: %w", err)
+		}
+	}
+
+	var cargoConfig []byte
+	if tarball := cfg.CrateTarball; tarball != "" {
+		cmd := exec.Command("tar", "-xzf", tarball, "-C", vendorDir)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("extracting %s: %w\n%s", tarball, err, output)
+		}
+		cargoConfig = fmt.Appendf(nil, cratesIOVendorConfig, vendorDir)
+	} else {
+		manifests, err := cargoManifests(b.SourceDir)
+		if err != nil {
+			return err
+		}
+		args := []string{"cargo", "vendor", "--locked", "--offline", "--manifest-path", manifests[0]}
+		for _, m := range manifests[1:] {
+			args = append(args, "--sync", m)
+		}
+		args = append(args, vendorDir)
+
+		executor, err := b.executor()
+		if err != nil {
+			return err
+		}
+		// cargo vendor prints the source replacement config on stdout
+		spec := b.execSpec(args, cfg, "", nil)
+		spec.Stdout, spec.Stderr = nil, nil
+		spec.Env = append(spec.Env, "CARGO_NET_OFFLINE=true")
+		if home := b.cargoHome(); home != "" {
+			spec.Env = append(spec.Env, "CARGO_HOME="+home)
+			spec.WritableDirs = append(spec.WritableDirs, home)
+		}
+		spec.WritableDirs = append(spec.WritableDirs, vendorDir)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+		defer cancel()
+		result := executor.Run(ctx, spec)
+		if result.Error != nil {
+			return fmt.Errorf("cargo vendor: %w\n%s", result.Error, result.Stderr)
+		}
+		cargoConfig = []byte(result.Stdout)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), cargoConfig, 0644); err != nil {
+		return fmt.Errorf("writing cargo config: %w", err)
+	}
+	return nil
+}
+
+// crateTarball returns the vendored crate tarball for a version, or "".
+func (b *Builder) crateTarball(version string) string {
+	if b.CargoVendorDir == "" {
+		return ""
+	}
+	path := filepath.Join(b.CargoVendorDir, fmt.Sprintf("%s-%s.tar.gz", config.NormalizeName(b.PackageName), version))
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// cargoManifests returns the Cargo.toml of each locked cargo workspace in
+// a source tree (those with a Cargo.lock), shallowest first.
+func cargoManifests(sourceDir string) ([]string, error) {
+	var manifests []string
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != sourceDir && (strings.HasPrefix(name, ".") || name == "target" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "Cargo.lock" {
+			return nil
+		}
+		manifest := filepath.Join(filepath.Dir(path), "Cargo.toml")
+		if _, err := os.Stat(manifest); err == nil {
+			manifests = append(manifests, manifest)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding Cargo.lock: %w", err)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("cargo_vendor: no Cargo.lock found; vendoring needs locked dependencies")
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return strings.Count(manifests[i], string(filepath.Separator)) < strings.Count(manifests[j], string(filepath.Separator))
+	})
+	return manifests, nil
+}
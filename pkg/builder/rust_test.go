@@ -0,0 +1,261 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestApplyRustToolchain(t *testing.T) {
+	cfg := &config.Config{
+		SystemDeps: []string{"rust", "openssl-dev"},
+		Rust:       config.Rust{Toolchain: "1.75"},
+	}
+	eff, err := New("/tmp/build", "testpkg", cfg).getEffectiveConfig("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyBackendProfile(eff, pep517.BackendMaturin)
+
+	if want := []string{"openssl-dev", "rust~1.75"}; !reflect.DeepEqual(eff.SystemDeps, want) {
+		t.Errorf("SystemDeps = %v, want %v", eff.SystemDeps, want)
+	}
+	if got := eff.Env["RUSTUP_TOOLCHAIN"]; got != "1.75" {
+		t.Errorf("RUSTUP_TOOLCHAIN = %q, want %q", got, "1.75")
+	}
+}
+
+func TestCargoEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		rust      config.Rust
+		cargoHome string
+		network   *NetworkPolicy
+		want      map[string]string
+	}{
+		{
+			name: "nothing configured",
+			want: map[string]string{},
+		},
+		{
+			name:      "local cargo home",
+			cargoHome: "/cache/cargo",
+			want:      map[string]string{"CARGO_HOME": "/cache/cargo"},
+		},
+		{
+			name:      "local cargo home, isolated",
+			cargoHome: "/cache/cargo",
+			network:   &NetworkPolicy{Isolated: true},
+			want:      map[string]string{"CARGO_HOME": "/cache/cargo", "CARGO_NET_OFFLINE": "true"},
+		},
+		{
+			name:      "vendored",
+			rust:      config.Rust{CargoVendor: true},
+			cargoHome: "/cache/cargo",
+			want:      map[string]string{"CARGO_HOME": "/tmp/build/cargo-home", "CARGO_NET_OFFLINE": "true"},
+		},
+		{
+			name: "target for platform",
+			rust: config.Rust{Targets: []string{"x86_64-unknown-linux-gnu", "aarch64-unknown-linux-musl"}},
+			want: map[string]string{"CARGO_BUILD_TARGET": "aarch64-unknown-linux-musl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("/tmp/build", "testpkg", &config.Config{Rust: tt.rust})
+			b.CargoHome = tt.cargoHome
+			b.Network = tt.network
+			b.Platform = "linux_aarch64"
+			if got := b.cargoEnv(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cargoEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildEnvCargoWins(t *testing.T) {
+	b := New("/tmp/build", "testpkg", &config.Config{Rust: config.Rust{CargoVendor: true}})
+	env := b.buildEnv(map[string]string{"CARGO_NET_OFFLINE": "false"}, "3.12")
+	if got := envValue(env, "CARGO_NET_OFFLINE"); got != "true" {
+		t.Errorf("CARGO_NET_OFFLINE = %q, want %q", got, "true")
+	}
+}
+
+// writeCargoProject writes a crate with the given Cargo.toml dependencies
+// section and Cargo.lock packages.
+func writeCargoProject(t *testing.T, dir, deps, lockPackages string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"Cargo.toml": "[package]\nname = \"demo\"\nversion = \"0.1.0\"\nedition = \"2021\"\n\n[dependencies]\n" + deps,
+		"Cargo.lock": "version = 3\n\n" + lockPackages,
+		"src/lib.rs": "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCargoManifests(t *testing.T) {
+	dir := t.TempDir()
+	writeCargoProject(t, filepath.Join(dir, "src", "rust"), "", "")
+	writeCargoProject(t, dir, "", "")
+	// Dependencies of a vendored or built tree are not workspaces
+	writeCargoProject(t, filepath.Join(dir, "target", "package", "demo"), "", "")
+
+	got, err := cargoManifests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "Cargo.toml"), filepath.Join(dir, "src", "rust", "Cargo.toml")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cargoManifests() = %v, want %v", got, want)
+	}
+
+	if _, err := cargoManifests(t.TempDir()); err == nil || !strings.Contains(err.Error(), "Cargo.lock") {
+		t.Errorf("cargoManifests() without Cargo.lock error = %v", err)
+	}
+}
+
+func TestVendorCratesTarball(t *testing.T) {
+	vendored := filepath.Join(t.TempDir(), "vendored")
+	if err := os.MkdirAll(filepath.Join(vendored, "itoa"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vendored, "itoa", "Cargo.toml"), []byte("[package]\nname = \"itoa\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tarballs := t.TempDir()
+	tarball := filepath.Join(tarballs, "test-pkg-1.0.0.tar.gz")
+	if out, err := exec.Command("tar", "-czf", tarball, "-C", vendored, ".").CombinedOutput(); err != nil {
+		t.Fatalf("tar: %v\n%s", err, out)
+	}
+
+	b := New(t.TempDir(), "Test_Pkg", &config.Config{Rust: config.Rust{CargoVendor: true}})
+	b.CargoVendorDir = tarballs
+	eff, err := b.getEffectiveConfig("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eff.CrateTarball != tarball {
+		t.Errorf("CrateTarball = %q, want %q", eff.CrateTarball, tarball)
+	}
+	if err := b.vendorCrates(eff); err != nil {
+		t.Fatalf("vendorCrates() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(b.vendorDir(), "itoa", "Cargo.toml")); err != nil {
+		t.Errorf("vendored crate not extracted: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(b.vendorHome(), "config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `replace-with = "vendored-sources"`) || !strings.Contains(string(data), b.vendorDir()) {
+		t.Errorf("cargo config = %s", data)
+	}
+}
+
+func TestVendorCratesCargo(t *testing.T) {
+	if _, err := exec.LookPath("cargo"); err != nil {
+		t.Skip("cargo not installed")
+	}
+
+	b := New(t.TempDir(), "testpkg", &config.Config{Rust: config.Rust{CargoVendor: true}})
+	b.CargoHome = t.TempDir()
+	executor := &recordingExecutor{}
+	b.Executor = executor
+	eff, err := b.getEffectiveConfig("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCargoProject(t, b.SourceDir, "", "[[package]]\nname = \"demo\"\nversion = \"0.1.0\"\n")
+	if err := b.vendorCrates(eff); err != nil {
+		t.Fatalf("vendorCrates() without dependencies error = %v", err)
+	}
+
+	// cargo vendor runs through the executor with the cargo home exposed
+	if len(executor.specs) != 1 {
+		t.Fatalf("executor ran %d commands, want 1", len(executor.specs))
+	}
+	spec := executor.specs[0]
+	if spec.Args[0] != "cargo" || spec.Args[1] != "vendor" {
+		t.Errorf("Args = %v, want cargo vendor", spec.Args)
+	}
+	for _, dir := range []string{b.CargoHome, b.vendorDir()} {
+		if !slices.Contains(spec.WritableDirs, dir) {
+			t.Errorf("WritableDirs = %v, want %s", spec.WritableDirs, dir)
+		}
+	}
+	if !slices.Contains(spec.Env, "CARGO_HOME="+b.CargoHome) || !slices.Contains(spec.Env, "CARGO_NET_OFFLINE=true") {
+		t.Errorf("Env is missing the local cargo home or offline mode")
+	}
+
+	// A crate missing from the empty cargo home
+	writeCargoProject(t, b.SourceDir, "itoa = \"1\"\n",
+		"[[package]]\nname = \"demo\"\nversion = \"0.1.0\"\ndependencies = [\"itoa\"]\n\n"+
+			"[[package]]\nname = \"itoa\"\nversion = \"1.0.11\"\nsource = \"registry+https://github.com/rust-lang/crates.io-index\"\n"+
+			"checksum = \"49f1f14873335454500d59611f1cf4a4b0f786f9ac11f4312a78e4cf2566695b\"\n")
+	err = b.vendorCrates(eff)
+	if err == nil {
+		t.Fatal("vendorCrates() with a missing crate succeeded")
+	}
+	if class := ClassifyFailure(err.Error()); class != FailureMissingCrate {
+		t.Errorf("ClassifyFailure() = %q, want %q\n%v", class, FailureMissingCrate, err)
+	}
+}
+
+// recordingExecutor runs commands on the host and records their specs.
+type recordingExecutor struct {
+	HostExecutor
+	specs []*ExecSpec
+}
+
+func (e *recordingExecutor) Run(ctx context.Context, spec *ExecSpec) *ExecResult {
+	e.specs = append(e.specs, spec)
+	return e.HostExecutor.Run(ctx, spec)
+}
+
+func TestCellHashCrateTarball(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0")
+	tarballs := t.TempDir()
+	tarball := filepath.Join(tarballs, "testpkg-1.0.0.tar.gz")
+	if err := os.WriteFile(tarball, []byte("crates v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(t.TempDir(), "testpkg", &config.Config{Repo: "file://" + upstream, Rust: config.Rust{CargoVendor: true}})
+	b.CargoVendorDir = tarballs
+	if err := b.CloneSource(); err != nil {
+		t.Fatal(err)
+	}
+	version := config.Version{Tag: "v1.0.0", Version: "1.0.0"}
+	before, err := b.CellHash(version, "3.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(tarball, []byte("crates v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	after, err := b.CellHash(version, "3.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Error("crate tarball content change should change the cell hash")
+	}
+}
@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// UpdateSubmodules initializes the configured submodules at the currently
+// checked-out ref and fetches LFS objects if enabled.
+func (b *Builder) UpdateSubmodules() error {
+	subs := b.Config.Submodules
+	if subs.Enabled() {
+		paths := subs.Paths
+		recursive := subs.Mode == config.SubmodulesRecursive
+		if recursive {
+			var err error
+			paths, err = b.submodulePaths()
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(paths) > 0 {
+			cmd := exec.Command("git", "submodule", "sync", "--recursive")
+			cmd.Dir = b.SourceDir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("syncing submodules: %w\n%s", err, output)
+			}
+		}
+
+		// Update one submodule at a time so failures name the culprit
+		for _, path := range paths {
+			args := []string{"submodule", "update", "--init", "--force"}
+			if recursive {
+				args = append(args, "--recursive")
+			}
+			args = append(args, "--", path)
+
+			cmd := exec.Command("git", args...)
+			cmd.Dir = b.SourceDir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("updating submodule %s: %w\n%s", path, err, output)
+			}
+		}
+	}
+
+	if b.Config.LFS {
+		if err := b.pullLFS(b.SourceDir); err != nil {
+			return err
+		}
+		if subs.Enabled() {
+			cmd := exec.Command("git", "submodule", "foreach", "--quiet", "--recursive",
+				"git lfs pull || { echo \"LFS pull failed in submodule $sm_path\"; exit 1; }")
+			cmd.Dir = b.SourceDir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("fetching LFS objects in submodules: %w\n%s", err, output)
+			}
+		}
+	}
+
+	return nil
+}
+
+// cleanSource removes untracked files from the source tree and its submodules.
+func (b *Builder) cleanSource() {
+	// -ff also removes stale submodule checkouts left by a previous ref
+	cmd := exec.Command("git", "clean", "-ffdx")
+	cmd.Dir = b.SourceDir
+	cmd.Run() // Ignore errors
+
+	if b.Config.Submodules.Enabled() {
+		cmd = exec.Command("git", "submodule", "foreach", "--quiet", "--recursive", "git clean -ffdx")
+		cmd.Dir = b.SourceDir
+		cmd.Run() // Ignore errors
+	}
+}
+
+// submodulePaths returns the top-level submodule paths declared in .gitmodules.
+func (b *Builder) submodulePaths() ([]string, error) {
+	if _, err := os.Stat(filepath.Join(b.SourceDir, ".gitmodules")); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	cmd := exec.Command("git", "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	cmd.Dir = b.SourceDir
+	output, err := cmd.Output()
+	if err != nil {
+		// Exit code 1 means no matching keys
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading .gitmodules: %w", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) == 2 {
+			paths = append(paths, fields[1])
+		}
+	}
+	return paths, nil
+}
+
+// pullLFS fetches and checks out LFS objects in a repository.
+func (b *Builder) pullLFS(dir string) error {
+	cmd := exec.Command("git", "lfs", "install", "--local")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("installing git lfs: %w\n%s", err, output)
+	}
+
+	cmd = exec.Command("git", "lfs", "pull")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("fetching LFS objects: %w\n%s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/gitcache"
+)
+
+// newSuperproject creates a repository tagged v1.0.0 with two submodules.
+func newSuperproject(t *testing.T) string {
+	t.Helper()
+	// Local file:// submodules are disabled by default in recent git.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libA := newTestRepo(t, "a1")
+	libB := newTestRepo(t, "b1")
+	super := newTestRepo(t, "v0.1.0")
+	runGit(t, super, "submodule", "add", "-q", "file://"+libA, "third_party/a")
+	runGit(t, super, "submodule", "add", "-q", "file://"+libB, "third_party/b")
+	runGit(t, super, "commit", "-q", "-m", "add submodules")
+	runGit(t, super, "tag", "v1.0.0")
+	return super
+}
+
+func TestUpdateSubmodules(t *testing.T) {
+	super := newSuperproject(t)
+
+	tests := []struct {
+		name       string
+		submodules config.Submodules
+		wantA      bool
+		wantB      bool
+	}{
+		{"none", config.Submodules{Mode: config.SubmodulesNone}, false, false},
+		{"unset", config.Submodules{}, false, false},
+		{"recursive", config.Submodules{Mode: config.SubmodulesRecursive}, true, true},
+		{"paths", config.Submodules{Mode: config.SubmodulesPaths, Paths: []string{"third_party/b"}}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{Repo: "file://" + super, Submodules: tt.submodules}
+			b := New(dir, "testpkg", cfg)
+			runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+			if err := b.Checkout("v1.0.0"); err != nil {
+				t.Fatalf("Checkout() failed: %v", err)
+			}
+
+			for path, want := range map[string]bool{"third_party/a": tt.wantA, "third_party/b": tt.wantB} {
+				_, err := os.Stat(filepath.Join(b.SourceDir, path, "VERSION"))
+				if got := err == nil; got != want {
+					t.Errorf("%s initialized = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateSubmodulesError(t *testing.T) {
+	super := newSuperproject(t)
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:       "file://" + super,
+		Submodules: config.Submodules{Mode: config.SubmodulesPaths, Paths: []string{"third_party/missing"}},
+	}
+	b := New(dir, "testpkg", cfg)
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+	err := b.Checkout("v1.0.0")
+	if err == nil {
+		t.Fatal("Checkout() should fail for unknown submodule")
+	}
+	if !strings.Contains(err.Error(), "third_party/missing") {
+		t.Errorf("error should name the submodule: %v", err)
+	}
+}
+
+func TestCheckoutCachedSubmodules(t *testing.T) {
+	super := newSuperproject(t)
+
+	dir := t.TempDir()
+	cfg := &config.Config{Repo: "file://" + super, Submodules: config.Submodules{Mode: config.SubmodulesRecursive}}
+	b := New(dir, "testpkg", cfg)
+	b.Cache = gitcache.New(filepath.Join(dir, "cache"))
+
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CloneSource(); err != nil {
+		t.Fatalf("CloneSource() failed: %v", err)
+	}
+	defer b.Cleanup()
+
+	if err := b.Checkout("v1.0.0"); err != nil {
+		t.Fatalf("Checkout(v1.0.0) failed: %v", err)
+	}
+	for _, path := range []string{"third_party/a", "third_party/b"} {
+		if _, err := os.Stat(filepath.Join(b.SourceDir, path, "VERSION")); err != nil {
+			t.Errorf("%s not initialized: %v", path, err)
+		}
+	}
+
+	// Checking out a ref without submodules leaves no stale checkouts
+	if err := b.Checkout("v0.1.0"); err != nil {
+		t.Fatalf("Checkout(v0.1.0) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b.SourceDir, "third_party")); !os.IsNotExist(err) {
+		t.Errorf("third_party should be removed at v0.1.0, stat err = %v", err)
+	}
+}
+
+func TestUpdateSubmodulesLFS(t *testing.T) {
+	upstream := newTestRepo(t, "v1.0.0")
+
+	dir := t.TempDir()
+	cfg := &config.Config{Repo: "file://" + upstream, LFS: true}
+	b := New(dir, "testpkg", cfg)
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+	err := b.Checkout("v1.0.0")
+	if exec.Command("git", "lfs", "version").Run() != nil {
+		// Without git-lfs the checkout must fail rather than build pointer files
+		if err == nil || !strings.Contains(err.Error(), "git lfs") {
+			t.Errorf("Checkout() error = %v, want a git lfs error", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Checkout() failed: %v", err)
+	}
+}
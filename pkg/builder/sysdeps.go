@@ -0,0 +1,200 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// apkWorldFile is the path of the apk world file relative to the APK root.
+const apkWorldFile = "etc/apk/world"
+
+// apkWorld returns the path of the apk world file.
+func (b *Builder) apkWorld() string {
+	root := b.APKRoot
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(root, apkWorldFile)
+}
+
+// apk runs an apk command against the configured root.
+func (b *Builder) apk(args ...string) ([]byte, error) {
+	if b.APKRoot != "" {
+		args = append([]string{"--root", b.APKRoot}, args...)
+	}
+	return exec.Command("apk", args...).CombinedOutput()
+}
+
+// apkAvailable reports whether the builder is running on an apk-based system.
+func (b *Builder) apkAvailable() bool {
+	_, err := os.Stat(b.apkWorld())
+	return err == nil
+}
+
+// readWorld returns the sorted entries of the apk world file.
+func (b *Builder) readWorld() ([]string, error) {
+	data, err := os.ReadFile(b.apkWorld())
+	if err != nil {
+		return nil, fmt.Errorf("reading apk world: %w", err)
+	}
+	world := strings.Fields(string(data))
+	sort.Strings(world)
+	return world, nil
+}
+
+// snapshotSystemDeps records the apk world before a build. The first snapshot
+// becomes the baseline for clean room builds. In clean room mode, packages
+// left behind by earlier builds are removed before the snapshot is taken.
+// Returns nil if apk is not in use.
+func (b *Builder) snapshotSystemDeps() ([]string, error) {
+	if !b.apkAvailable() {
+		return nil, nil
+	}
+
+	world, err := b.readWorld()
+	if err != nil {
+		return nil, err
+	}
+	if b.BaseWorld == nil {
+		b.BaseWorld = world
+	}
+
+	if b.CleanRoom {
+		if err := b.restoreSystemDeps(b.BaseWorld); err != nil {
+			return nil, err
+		}
+		return b.BaseWorld, nil
+	}
+	return world, nil
+}
+
+// restoreSystemDeps removes packages added to the apk world since snapshot.
+func (b *Builder) restoreSystemDeps(snapshot []string) error {
+	if snapshot == nil {
+		return nil
+	}
+
+	world, err := b.readWorld()
+	if err != nil {
+		return err
+	}
+	added, removed := diffWorld(snapshot, world)
+
+	if len(added) > 0 {
+		args := append([]string{"del", "--no-cache"}, added...)
+		if output, err := b.apk(args...); err != nil {
+			return fmt.Errorf("removing system deps %v: %w\n%s", added, err, output)
+		}
+	}
+	if len(removed) > 0 {
+		args := append([]string{"add", "--no-cache"}, removed...)
+		if output, err := b.apk(args...); err != nil {
+			return fmt.Errorf("restoring system deps %v: %w\n%s", removed, err, output)
+		}
+	}
+	return nil
+}
+
+// undeclaredSystemDeps returns world entries added since snapshot that are
+// not among the declared deps, e.g. packages installed by a build script.
+func (b *Builder) undeclaredSystemDeps(snapshot, declared []string) ([]string, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	world, err := b.readWorld()
+	if err != nil {
+		return nil, err
+	}
+	added, _ := diffWorld(snapshot, world)
+
+	allowed := make(map[string]bool)
+	for _, d := range declared {
+		allowed[apkPackageName(d)] = true
+	}
+
+	var undeclared []string
+	for _, a := range added {
+		if !allowed[apkPackageName(a)] {
+			undeclared = append(undeclared, a)
+		}
+	}
+	return undeclared, nil
+}
+
+// resolveSystemDeps returns the installed "name=version" of each declared dep.
+func (b *Builder) resolveSystemDeps(deps []string) ([]string, error) {
+	if len(deps) == 0 || !b.apkAvailable() {
+		return nil, nil
+	}
+
+	output, err := b.apk("info", "-v")
+	if err != nil {
+		return nil, fmt.Errorf("listing installed packages: %w\n%s", err, output)
+	}
+	installed := parseAPKInfo(string(output))
+
+	resolved := make([]string, 0, len(deps))
+	for _, d := range deps {
+		name := apkPackageName(d)
+		if v, ok := installed[name]; ok {
+			resolved = append(resolved, name+"="+v)
+		} else {
+			resolved = append(resolved, name)
+		}
+	}
+	return resolved, nil
+}
+
+// diffWorld returns the entries added to and removed from before.
+func diffWorld(before, after []string) (added, removed []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, w := range before {
+		inBefore[w] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, w := range after {
+		inAfter[w] = true
+		if !inBefore[w] {
+			added = append(added, w)
+		}
+	}
+	for _, w := range before {
+		if !inAfter[w] {
+			removed = append(removed, w)
+		}
+	}
+	return added, removed
+}
+
+// apkPackageName strips any version constraint from a world entry or
+// system_deps entry (e.g., "openssl-dev=3.1.0" -> "openssl-dev").
+func apkPackageName(dep string) string {
+	if i := strings.IndexAny(dep, "=<>~"); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
+
+// parseAPKInfo parses "apk info -v" output into a map of name to version.
+// Each line has the form "{name}-{version}-r{release}".
+func parseAPKInfo(output string) map[string]string {
+	installed := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		r := strings.LastIndex(line, "-r")
+		if r <= 0 {
+			continue
+		}
+		v := strings.LastIndex(line[:r], "-")
+		if v <= 0 {
+			continue
+		}
+		installed[line[:v]] = line[v+1:]
+	}
+	return installed
+}
@@ -0,0 +1,215 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// fakeAPK is a minimal apk stand-in that manages the world file under --root
+// and reports every world entry as installed at version 1.0-r0.
+const fakeAPK = `#!/bin/sh
+root=/
+if [ "$1" = "--root" ]; then root=$2; shift 2; fi
+world="$root/etc/apk/world"
+cmd=$1; shift
+for a; do
+	case $a in
+	-*) continue ;;
+	esac
+	case $cmd in
+	add) echo "$a" >> "$world" ;;
+	del) grep -vx "$a" "$world" > "$world.tmp"; mv "$world.tmp" "$world" ;;
+	esac
+done
+if [ "$cmd" = info ]; then sed 's/[=<>~].*//; s/$/-1.0-r0/' "$world"; fi
+`
+
+// setupFakeAPK installs fakeAPK on PATH and returns an APK root whose world
+// contains the given entries.
+func setupFakeAPK(t *testing.T, world ...string) string {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "apk"), []byte(fakeAPK), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc", "apk"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(world, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(root, apkWorldFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// newSysdepsBuilder returns a builder whose script writes a wheel for 1.0.0
+// after running extra.
+func newSysdepsBuilder(t *testing.T, root, extra string, deps ...string) *Builder {
+	t.Helper()
+	upstream := newTestRepo(t, "v1.0.0")
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repo:       "file://" + upstream,
+		SystemDeps: deps,
+	}
+	b := New(dir, "testpkg", cfg)
+	b.APKRoot = root
+	if err := b.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "clone", "-q", cfg.Repo, b.SourceDir)
+
+	wheel := filepath.Join(b.DistDir, "testpkg-1.0.0-cp312-cp312-linux_x86_64.whl")
+	cfg.Script = fmt.Sprintf("%s\ntouch %s", extra, wheel)
+	return b
+}
+
+func TestBuildRestoresSystemDeps(t *testing.T) {
+	root := setupFakeAPK(t, "base")
+	b := newSysdepsBuilder(t, root, "", "libfoo=1.0")
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Build() failed: %+v", results)
+	}
+	if want := []string{"libfoo=1.0-r0"}; !reflect.DeepEqual(results[0].SystemDeps, want) {
+		t.Errorf("SystemDeps = %v, want %v", results[0].SystemDeps, want)
+	}
+
+	world, err := b.readWorld()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(world, []string{"base"}) {
+		t.Errorf("world after build = %v, want [base]", world)
+	}
+}
+
+func TestBuildCleanRoom(t *testing.T) {
+	root := setupFakeAPK(t, "base", "leaked")
+	script := fmt.Sprintf("apk --root %s add sneaky-dev", root)
+	b := newSysdepsBuilder(t, root, script, "libfoo")
+	b.CleanRoom = true
+	b.BaseWorld = []string{"base"}
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	if results[0].Success {
+		t.Fatal("Build() should fail when the script installs undeclared deps")
+	}
+	if !strings.Contains(results[0].Error.Error(), "sneaky-dev") {
+		t.Errorf("error should name the undeclared dep: %v", results[0].Error)
+	}
+	if strings.Contains(results[0].Error.Error(), "libfoo") || strings.Contains(results[0].Error.Error(), "leaked") {
+		t.Errorf("error should only name undeclared deps installed by the build: %v", results[0].Error)
+	}
+
+	world, err := b.readWorld()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(world, []string{"base"}) {
+		t.Errorf("world after clean room build = %v, want [base]", world)
+	}
+}
+
+// sandboxedExecutor is a recordingExecutor that claims to install system
+// deps itself.
+type sandboxedExecutor struct {
+	recordingExecutor
+}
+
+func (e *sandboxedExecutor) InstallsSystemDeps() bool { return true }
+
+func TestBuildExecutorInstallsSystemDeps(t *testing.T) {
+	root := setupFakeAPK(t, "base")
+	b := newSysdepsBuilder(t, root, "cat "+filepath.Join(root, apkWorldFile), "libfoo")
+	b.CleanRoom = true
+	executor := &sandboxedExecutor{}
+	b.Executor = executor
+
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Build() failed: %+v", results)
+	}
+	if strings.Contains(results[0].Log, "libfoo") {
+		t.Errorf("system deps were installed on the host:\n%s", results[0].Log)
+	}
+	if results[0].SystemDeps != nil {
+		t.Errorf("SystemDeps = %v, want none resolved on the host", results[0].SystemDeps)
+	}
+
+	var deps []string
+	for _, spec := range executor.specs {
+		deps = append(deps, spec.SystemDeps...)
+	}
+	if !slices.Contains(deps, "libfoo") {
+		t.Errorf("executor specs don't carry the system deps: %v", deps)
+	}
+}
+
+func TestBuildWithoutAPK(t *testing.T) {
+	b := newSysdepsBuilder(t, t.TempDir(), "")
+	results := b.Build(config.Version{Tag: "v1.0.0", Version: "1.0.0"}, []string{"3.12"})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Build() failed without apk: %+v", results)
+	}
+}
+
+func TestDiffWorld(t *testing.T) {
+	added, removed := diffWorld([]string{"a", "b", "c"}, []string{"b", "c", "d", "e"})
+	if !reflect.DeepEqual(added, []string{"d", "e"}) {
+		t.Errorf("added = %v, want [d e]", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"a"}) {
+		t.Errorf("removed = %v, want [a]", removed)
+	}
+}
+
+func TestAPKPackageName(t *testing.T) {
+	tests := []struct {
+		dep  string
+		want string
+	}{
+		{"openssl-dev", "openssl-dev"},
+		{"openblas-dev=0.3.26", "openblas-dev"},
+		{"python-3.12-base>=3.12.1", "python-3.12-base"},
+		{"gcc~13", "gcc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dep, func(t *testing.T) {
+			if got := apkPackageName(tt.dep); got != tt.want {
+				t.Errorf("apkPackageName(%q) = %q, want %q", tt.dep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAPKInfo(t *testing.T) {
+	output := `python-3.12-base-dev-3.12.1-r0
+openssl-dev-3.1.4-r5
+busybox-1.36.1-r2
+`
+	got := parseAPKInfo(output)
+	want := map[string]string{
+		"python-3.12-base-dev": "3.12.1-r0",
+		"openssl-dev":          "3.1.4-r5",
+		"busybox":              "1.36.1-r2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAPKInfo() = %v, want %v", got, want)
+	}
+}
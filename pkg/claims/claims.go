@@ -0,0 +1,347 @@
+// Package claims implements the claims protocol: agents take exclusive
+// ownership of a package by committing claims/{package}.yaml to the claims
+// branch with a fast-forward-only push. A push rejected because the branch
+// moved is retried against the new head, so two agents can never both
+// believe they hold the same claim.
+//
+// Each acquisition is issued a fencing token, one higher than any token
+// issued before. Agents pass their token to Verify before publishing work;
+// once a claim is garbage collected or re-acquired, the old token no
+// longer verifies.
+package claims
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// DefaultBranch is the branch claims are stored on.
+const DefaultBranch = "claims"
+
+// DefaultMaxRetries is how many times a lost push race is retried.
+const DefaultMaxRetries = 20
+
+// claimsDir is the directory of claim files on the branch.
+const claimsDir = "claims"
+
+// tokenFile holds the last fencing token issued on the branch.
+const tokenFile = "fencing-token"
+
+var (
+	// ErrClaimed is returned when another agent holds the claim.
+	ErrClaimed = errors.New("package is already claimed")
+
+	// ErrNotHeld is returned when a lease no longer matches the claim on
+	// the branch (it was released, garbage collected or re-acquired).
+	ErrNotHeld = errors.New("claim is not held")
+
+	// ErrContention is returned when pushes keep losing races.
+	ErrContention = errors.New("too many concurrent claim updates")
+)
+
+// Lease is a held claim.
+type Lease struct {
+	// Package is the normalized package name.
+	Package string
+
+	// Claim is the claim as last written by this agent.
+	Claim config.Claim
+}
+
+// Token returns the lease's fencing token.
+func (l *Lease) Token() int64 { return l.Claim.Token }
+
+// Manager acquires, renews and releases claims for one agent.
+// It is safe for concurrent use.
+type Manager struct {
+	// Agent identifies this agent in claims.
+	Agent string
+
+	// MaxRetries bounds retries of lost push races (default: DefaultMaxRetries).
+	MaxRetries int
+
+	// Now returns the current time (default: time.Now).
+	Now func() time.Time
+
+	mu   sync.Mutex
+	repo *repo
+}
+
+// New returns a Manager for the claims branch of remote, using dir as a
+// local scratch repository.
+func New(remote, dir, agent string) *Manager {
+	return &Manager{
+		Agent: agent,
+		repo:  &repo{dir: dir, remote: remote, branch: DefaultBranch, agent: agent},
+	}
+}
+
+// Acquire claims a package. It returns ErrClaimed if another claim exists.
+func (m *Manager) Acquire(pkg, claimType string) (*Lease, error) {
+	pkg = config.NormalizeName(pkg)
+	var lease *Lease
+	err := m.update(func(head string) (map[string][]byte, string, error) {
+		existing, err := m.read(head, pkg)
+		if err != nil {
+			return nil, "", err
+		}
+		if existing != nil {
+			return nil, "", fmt.Errorf("%s: %w by %s", pkg, ErrClaimed, existing.Agent)
+		}
+
+		token, err := m.lastToken(head)
+		if err != nil {
+			return nil, "", err
+		}
+		now := m.now()
+		claim := config.Claim{Agent: m.Agent, ClaimedAt: now, Type: claimType, Token: token + 1}
+		data, err := yaml.Marshal(&claim)
+		if err != nil {
+			return nil, "", err
+		}
+		lease = &Lease{Package: pkg, Claim: claim}
+		return map[string][]byte{
+			claimPath(pkg): data,
+			tokenFile:      []byte(strconv.FormatInt(claim.Token, 10) + "\n"),
+		}, fmt.Sprintf("Claim %s for %s", pkg, m.Agent), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Renew records a heartbeat on a held claim.
+func (m *Manager) Renew(l *Lease) error {
+	return m.update(func(head string) (map[string][]byte, string, error) {
+		if err := m.checkHeld(head, l); err != nil {
+			return nil, "", err
+		}
+		claim := l.Claim
+		claim.RenewedAt = m.now()
+		data, err := yaml.Marshal(&claim)
+		if err != nil {
+			return nil, "", err
+		}
+		l.Claim = claim
+		return map[string][]byte{claimPath(l.Package): data},
+			fmt.Sprintf("Renew claim %s for %s", l.Package, m.Agent), nil
+	})
+}
+
+// Release deletes a held claim.
+func (m *Manager) Release(l *Lease) error {
+	return m.update(func(head string) (map[string][]byte, string, error) {
+		if err := m.checkHeld(head, l); err != nil {
+			return nil, "", err
+		}
+		return map[string][]byte{claimPath(l.Package): nil},
+			fmt.Sprintf("Release claim %s for %s", l.Package, m.Agent), nil
+	})
+}
+
+// Verify returns nil if the lease still holds the claim on the remote.
+// Agents call it before publishing work so a lease lost to garbage
+// collection can't publish stale results.
+func (m *Manager) Verify(l *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.repo.init(); err != nil {
+		return err
+	}
+	head, err := m.repo.fetch()
+	if err != nil {
+		return err
+	}
+	return m.checkHeld(head, l)
+}
+
+// Heartbeat renews a lease every interval until ctx is done. It returns
+// ErrNotHeld if the claim is lost; other renewal errors are retried at
+// the next interval.
+func (m *Manager) Heartbeat(ctx context.Context, l *Lease, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.Renew(l); errors.Is(err, ErrNotHeld) {
+				return err
+			}
+		}
+	}
+}
+
+// Expire deletes every claim for which expired returns true in a single
+// commit and returns the deleted claims. Claims are re-evaluated against
+// the branch head on each retry, so a claim renewed mid-race is kept.
+func (m *Manager) Expire(expired func(pkg string, claim *config.Claim) bool) (map[string]*config.Claim, error) {
+	var deleted map[string]*config.Claim
+	err := m.update(func(head string) (map[string][]byte, string, error) {
+		all, err := m.list(head)
+		if err != nil {
+			return nil, "", err
+		}
+		deleted = make(map[string]*config.Claim)
+		files := make(map[string][]byte)
+		for pkg, claim := range all {
+			if expired(pkg, claim) {
+				deleted[pkg] = claim
+				files[claimPath(pkg)] = nil
+			}
+		}
+		if len(files) == 0 {
+			return nil, "", nil
+		}
+		return files, fmt.Sprintf("Expire %d stale claims", len(files)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// List returns all claims on the branch, keyed by package.
+func (m *Manager) List() (map[string]*config.Claim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.repo.init(); err != nil {
+		return nil, err
+	}
+	head, err := m.repo.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.list(head)
+}
+
+// list returns the claims at head, keyed by package.
+func (m *Manager) list(head string) (map[string]*config.Claim, error) {
+	names, err := m.repo.list(head, claimsDir)
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]*config.Claim)
+	for _, name := range names {
+		pkg, ok := strings.CutSuffix(name, ".yaml")
+		if !ok {
+			continue
+		}
+		claim, err := m.read(head, pkg)
+		if err != nil {
+			return nil, err
+		}
+		if claim != nil {
+			claims[pkg] = claim
+		}
+	}
+	return claims, nil
+}
+
+// update applies a change to the branch head, retrying if a concurrent
+// push wins the race. change returns the files to write (nil deletes) and
+// the commit message; no files means there is nothing to do.
+func (m *Manager) update(change func(head string) (map[string][]byte, string, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.repo.init(); err != nil {
+		return err
+	}
+
+	retries := m.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		head, err := m.repo.fetch()
+		if err != nil {
+			return err
+		}
+		files, message, err := change(head)
+		if err != nil {
+			return err
+		}
+		if files == nil {
+			return nil
+		}
+		commit, err := m.repo.commit(head, message, files)
+		if err != nil {
+			return err
+		}
+
+		err = m.repo.push(commit)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errRejected) {
+			return err
+		}
+		// Back off with jitter so racing agents spread out
+		time.Sleep(time.Duration(rand.IntN(20*(attempt+1))) * time.Millisecond)
+	}
+	return ErrContention
+}
+
+// checkHeld returns ErrNotHeld unless the claim at head matches the lease.
+func (m *Manager) checkHeld(head string, l *Lease) error {
+	claim, err := m.read(head, l.Package)
+	if err != nil {
+		return err
+	}
+	if claim == nil || claim.Agent != l.Claim.Agent || claim.Token != l.Claim.Token {
+		return fmt.Errorf("%s (token %d): %w", l.Package, l.Claim.Token, ErrNotHeld)
+	}
+	return nil
+}
+
+// read returns the claim for pkg at head, or nil.
+func (m *Manager) read(head, pkg string) (*config.Claim, error) {
+	data, err := m.repo.read(head, claimPath(pkg))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var claim config.Claim
+	if _, err := config.Decode(claimPath(pkg), data, &claim); err != nil {
+		return nil, fmt.Errorf("parsing claim %s: %w", pkg, err)
+	}
+	return &claim, nil
+}
+
+// lastToken returns the last fencing token issued at head.
+func (m *Manager) lastToken(head string) (int64, error) {
+	data, err := m.repo.read(head, tokenFile)
+	if err != nil || data == nil {
+		return 0, err
+	}
+	token, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", tokenFile, err)
+	}
+	return token, nil
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// claimPath returns the path of a package's claim file on the branch.
+func claimPath(pkg string) string {
+	return path.Join(claimsDir, pkg+".yaml")
+}
@@ -0,0 +1,291 @@
+package claims
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// newRemote creates an empty bare repository to act as the remote.
+func newRemote(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--bare", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	return dir
+}
+
+func newManager(t *testing.T, remote, agent string) *Manager {
+	t.Helper()
+	return New(remote, filepath.Join(t.TempDir(), agent), agent)
+}
+
+func TestAcquireRenewRelease(t *testing.T) {
+	remote := newRemote(t)
+	a := newManager(t, remote, "agent-a")
+	b := newManager(t, remote, "agent-b")
+
+	lease, err := a.Acquire("NumPy", config.ClaimTypeBuild)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if lease.Package != "numpy" || lease.Token() != 1 {
+		t.Errorf("lease = %+v, want numpy with token 1", lease)
+	}
+
+	if _, err := b.Acquire("numpy", config.ClaimTypeBuild); !errors.Is(err, ErrClaimed) {
+		t.Errorf("second Acquire() error = %v, want ErrClaimed", err)
+	}
+
+	renewedAt := time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)
+	a.Now = func() time.Time { return renewedAt }
+	if err := a.Renew(lease); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	claims, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := claims["numpy"]
+	if got == nil || got.Agent != "agent-a" || !got.RenewedAt.Equal(renewedAt) || got.Token != 1 || got.Type != config.ClaimTypeBuild {
+		t.Errorf("claim = %+v", got)
+	}
+
+	if err := a.Verify(lease); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := a.Release(lease); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := a.Verify(lease); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Verify() after release error = %v, want ErrNotHeld", err)
+	}
+	if err := a.Release(lease); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("second Release() error = %v, want ErrNotHeld", err)
+	}
+
+	// Tokens keep increasing across claims
+	lease2, err := b.Acquire("numpy", config.ClaimTypeFixer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease2.Token() != 2 {
+		t.Errorf("token = %d, want 2", lease2.Token())
+	}
+}
+
+func TestFencingToken(t *testing.T) {
+	remote := newRemote(t)
+	a := newManager(t, remote, "agent-a")
+	b := newManager(t, remote, "agent-b")
+
+	stale, err := a.Acquire("numpy", config.ClaimTypeBuild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The claim is collected and re-acquired while agent-a is stalled
+	if err := b.Release(&Lease{Package: "numpy", Claim: stale.Claim}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Acquire("numpy", config.ClaimTypeBuild); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same agent, but the stale lease's token is superseded
+	if err := a.Verify(stale); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Verify(stale) error = %v, want ErrNotHeld", err)
+	}
+	if err := a.Renew(stale); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Renew(stale) error = %v, want ErrNotHeld", err)
+	}
+}
+
+func TestConcurrentAcquireSamePackage(t *testing.T) {
+	remote := newRemote(t)
+	const agents = 12
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	errs := make(chan error, agents)
+	for i := 0; i < agents; i++ {
+		m := newManager(t, remote, fmt.Sprintf("agent-%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Acquire("numpy", config.ClaimTypeBuild)
+			switch {
+			case err == nil:
+				mu.Lock()
+				winners = append(winners, m.Agent)
+				mu.Unlock()
+			case !errors.Is(err, ErrClaimed):
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Acquire() unexpected error = %v", err)
+	}
+	if len(winners) != 1 {
+		t.Fatalf("%d agents acquired the claim, want exactly 1: %v", len(winners), winners)
+	}
+
+	claims, err := newManager(t, remote, "observer").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["numpy"] == nil || claims["numpy"].Agent != winners[0] {
+		t.Errorf("claim = %+v, want holder %s", claims["numpy"], winners[0])
+	}
+}
+
+func TestConcurrentAcquireDistinctPackages(t *testing.T) {
+	remote := newRemote(t)
+	const agents = 8
+
+	var wg sync.WaitGroup
+	tokens := make([]int64, agents)
+	errs := make([]error, agents)
+	for i := 0; i < agents; i++ {
+		m := newManager(t, remote, fmt.Sprintf("agent-%d", i))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lease, err := m.Acquire(fmt.Sprintf("pkg%d", i), config.ClaimTypeBuild)
+			errs[i] = err
+			if err == nil {
+				tokens[i] = lease.Token()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for i := 0; i < agents; i++ {
+		if errs[i] != nil {
+			t.Fatalf("agent %d: Acquire() error = %v", i, errs[i])
+		}
+		if seen[tokens[i]] {
+			t.Errorf("token %d issued twice", tokens[i])
+		}
+		seen[tokens[i]] = true
+	}
+	for tok := int64(1); tok <= agents; tok++ {
+		if !seen[tok] {
+			t.Errorf("token %d never issued; tokens = %v", tok, tokens)
+		}
+	}
+
+	claims, err := newManager(t, remote, "observer").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims) != agents {
+		t.Errorf("List() returned %d claims, want %d", len(claims), agents)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	remote := newRemote(t)
+	a := newManager(t, remote, "agent-a")
+	lease, err := a.Acquire("numpy", config.ClaimTypeBuild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- a.Heartbeat(ctx, lease, 250*time.Millisecond) }()
+
+	// Losing the claim stops the heartbeat
+	time.Sleep(400 * time.Millisecond)
+	b := newManager(t, remote, "gc")
+	claims, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["numpy"].RenewedAt.IsZero() {
+		t.Error("heartbeat did not renew the claim")
+	}
+	if err := b.Release(&Lease{Package: "numpy", Claim: *claims["numpy"]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Heartbeat() error = %v, want ErrNotHeld", err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	remote := newRemote(t)
+	a := newManager(t, remote, "agent-a")
+	for _, pkg := range []string{"numpy", "requests", "six"} {
+		if _, err := a.Acquire(pkg, config.ClaimTypeBuild); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gc := newManager(t, remote, "gc")
+	deleted, err := gc.Expire(func(pkg string, claim *config.Claim) bool {
+		return pkg != "requests"
+	})
+	if err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if len(deleted) != 2 || deleted["numpy"] == nil || deleted["six"] == nil {
+		t.Errorf("Expire() deleted %v, want numpy and six", deleted)
+	}
+
+	claims, err := gc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims) != 1 || claims["requests"] == nil {
+		t.Errorf("List() = %v, want only requests", claims)
+	}
+
+	// Nothing to expire is not an error
+	deleted, err = gc.Expire(func(string, *config.Claim) bool { return false })
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("Expire() = %v, %v, want nothing deleted", deleted, err)
+	}
+}
+
+func TestRepoRead(t *testing.T) {
+	remote := newRemote(t)
+	m := newManager(t, remote, "agent-a")
+	if _, err := m.Acquire("numpy", config.ClaimTypeBuild); err != nil {
+		t.Fatal(err)
+	}
+	head, err := m.repo.fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := m.repo.read(head, "claims/numpy.yaml"); err != nil || len(data) == 0 {
+		t.Errorf("read(numpy) = %q, %v; want the claim", data, err)
+	}
+	if data, err := m.repo.read(head, "claims/scipy.yaml"); err != nil || data != nil {
+		t.Errorf("read(scipy) = %q, %v; want nil, nil", data, err)
+	}
+
+	// A lookup failure is an error, not a missing claim
+	m.repo.dir = filepath.Join(t.TempDir(), "missing")
+	if _, err := m.repo.read(head, "claims/numpy.yaml"); err == nil {
+		t.Error("read() in a missing repository should fail")
+	}
+}
@@ -0,0 +1,205 @@
+package claims
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// errRejected is returned by push when the remote branch moved.
+var errRejected = errors.New("push rejected: branch moved")
+
+// rejectedMarkers identify a push lost to a concurrent update.
+var rejectedMarkers = []string{
+	"non-fast-forward",
+	"fetch first",
+	"[rejected]",
+	"failed to update ref",
+	"cannot lock ref",
+	"failed to lock",
+	"stale info",
+}
+
+// repo is the local bare repository used to build claim commits without
+// a working tree.
+type repo struct {
+	dir    string
+	remote string
+	branch string
+	agent  string
+}
+
+// remoteRef is the local tracking ref for the claims branch.
+func (r *repo) remoteRef() string {
+	return "refs/remotes/origin/" + r.branch
+}
+
+// git runs a git command in the local repository and returns its trimmed output.
+func (r *repo) git(stdin []byte, env []string, args ...string) (string, error) {
+	out, err := r.run(stdin, env, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// run runs a git command in the local repository.
+func (r *repo) run(stdin []byte, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(append(os.Environ(),
+		"GIT_AUTHOR_NAME="+r.agent,
+		"GIT_AUTHOR_EMAIL="+r.agent+"@superwheelie",
+		"GIT_COMMITTER_NAME="+r.agent,
+		"GIT_COMMITTER_EMAIL="+r.agent+"@superwheelie",
+	), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("git %s: %w\n%s", args[0], err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// init creates the local repository if needed.
+func (r *repo) init() error {
+	if _, err := os.Stat(filepath.Join(r.dir, "HEAD")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("creating claims repo: %w", err)
+	}
+	_, err := r.git(nil, nil, "init", "--bare", "-q", ".")
+	return err
+}
+
+// fetch updates the tracking ref and returns the branch head, or "" if the
+// branch doesn't exist yet.
+func (r *repo) fetch() (string, error) {
+	_, err := r.git(nil, nil, "fetch", "-q", "--no-tags", r.remote, "+refs/heads/"+r.branch+":"+r.remoteRef())
+	if err != nil {
+		if strings.Contains(err.Error(), "couldn't find remote ref") {
+			r.git(nil, nil, "update-ref", "-d", r.remoteRef())
+			return "", nil
+		}
+		return "", err
+	}
+	return r.git(nil, nil, "rev-parse", "--verify", r.remoteRef())
+}
+
+// read returns the content of path at commit, or nil if it doesn't exist.
+func (r *repo) read(commit, path string) ([]byte, error) {
+	if commit == "" {
+		return nil, nil
+	}
+	if _, err := r.run(nil, nil, "cat-file", "-e", commit+":"+path); err != nil {
+		if isMissing(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	data, err := r.run(nil, nil, "cat-file", "blob", commit+":"+path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// isMissing reports whether a cat-file -e failure means the object doesn't
+// exist, rather than that git couldn't look it up.
+func isMissing(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	// cat-file -e exits 1 for a missing object and 128 with this message
+	// for a path missing from the commit's tree.
+	return exitErr.ExitCode() == 1 || strings.Contains(err.Error(), "does not exist in")
+}
+
+// list returns the file names in dir at commit.
+func (r *repo) list(commit, dir string) ([]string, error) {
+	if commit == "" {
+		return nil, nil
+	}
+	out, err := r.git(nil, nil, "ls-tree", "--name-only", commit, dir+"/")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	var names []string
+	for _, p := range strings.Split(out, "\n") {
+		names = append(names, strings.TrimPrefix(p, dir+"/"))
+	}
+	return names, nil
+}
+
+// commit creates a commit on top of parent that writes files (nil content
+// deletes the file) and returns its hash.
+func (r *repo) commit(parent, message string, files map[string][]byte) (string, error) {
+	index, err := os.CreateTemp("", "claims-index-*")
+	if err != nil {
+		return "", fmt.Errorf("creating index: %w", err)
+	}
+	index.Close()
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	if parent != "" {
+		if _, err := r.git(nil, env, "read-tree", parent); err != nil {
+			return "", err
+		}
+	}
+	// update-index --index-info works without a working tree; mode 0
+	// removes the entry
+	var info bytes.Buffer
+	for path, content := range files {
+		if content == nil {
+			fmt.Fprintf(&info, "0 %s\t%s\n", strings.Repeat("0", 40), path)
+			continue
+		}
+		blob, err := r.git(content, nil, "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&info, "100644 %s\t%s\n", blob, path)
+	}
+	if _, err := r.git(info.Bytes(), env, "update-index", "--index-info"); err != nil {
+		return "", err
+	}
+	tree, err := r.git(nil, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	return r.git(nil, nil, args...)
+}
+
+// push fast-forwards the remote branch to commit. It returns errRejected
+// if the branch moved since it was fetched.
+func (r *repo) push(commit string) error {
+	out, err := r.run(nil, nil, "push", "--porcelain", r.remote, commit+":refs/heads/"+r.branch)
+	if err == nil {
+		return nil
+	}
+	// --porcelain reports the ref status on stdout
+	msg := string(out) + err.Error()
+	for _, marker := range rejectedMarkers {
+		if strings.Contains(msg, marker) {
+			return errRejected
+		}
+	}
+	return err
+}
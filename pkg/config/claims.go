@@ -12,6 +12,21 @@ type Claim struct {
 
 	// Type is the type of claim (build, version, fixer).
 	Type string `yaml:"type,omitempty"`
+
+	// RenewedAt is the last heartbeat from the agent, if any.
+	RenewedAt time.Time `yaml:"renewed_at,omitempty"`
+
+	// Token is the fencing token issued when the claim was acquired.
+	// Tokens increase monotonically across all claims on the branch.
+	Token int64 `yaml:"token,omitempty"`
+}
+
+// LastSeen returns when the agent last refreshed the claim.
+func (c *Claim) LastSeen() time.Time {
+	if c.RenewedAt.After(c.ClaimedAt) {
+		return c.RenewedAt
+	}
+	return c.ClaimedAt
 }
 
 // Claim types.
@@ -12,6 +12,18 @@ type Config struct {
 	// Versions is the list of tag/version mappings to build.
 	Versions []Version `yaml:"versions"`
 
+	// Retired are versions that have rotated out of the version window.
+	// Their published wheels are kept, but they are no longer built.
+	Retired []Version `yaml:"retired,omitempty"`
+
+	// TagPattern maps upstream tags to versions, with "{version}" standing
+	// for the version (e.g., "v{version}", "numpy-{version}").
+	// Default: "v{version}" or "{version}".
+	TagPattern string `yaml:"tag_pattern,omitempty"`
+
+	// Prereleases includes pre-releases in version discovery.
+	Prereleases bool `yaml:"prereleases,omitempty"`
+
 	// SystemDeps are APK packages to install before building.
 	// Supports pinning: "pkg=1.0"
 	SystemDeps []string `yaml:"system_deps,omitempty"`
@@ -20,13 +32,44 @@ type Config struct {
 	Env map[string]string `yaml:"env,omitempty"`
 
 	// Patches is a list of patch files to apply in order.
-	Patches []string `yaml:"patches,omitempty"`
+	Patches []Patch `yaml:"patches,omitempty"`
 
-	// Script is a custom build script that replaces the default pip wheel command.
+	// Script is a custom build script that replaces the default wheel build.
 	Script string `yaml:"script,omitempty"`
 
+	// ConfigSettings are passed to the PEP 517 backend as config_settings.
+	ConfigSettings map[string]string `yaml:"config_settings,omitempty"`
+
+	// Backend selects the build backend whose defaults are applied (e.g.,
+	// "maturin"). Default: detected from each version's pyproject.toml.
+	Backend string `yaml:"backend,omitempty"`
+
+	// BuildRequires are PEP 508 constraints on PEP 517 build requirements
+	// (e.g., "setuptools<70"), passed to pip via PIP_CONSTRAINT.
+	BuildRequires []string `yaml:"build_requires,omitempty"`
+
+	// BuildConstraints is a pip constraints file, relative to the package directory.
+	BuildConstraints string `yaml:"build_constraints,omitempty"`
+
 	// Overrides contains version-specific build configuration overrides.
 	Overrides []Override `yaml:"overrides,omitempty"`
+
+	// Submodules controls git submodule initialization at checkout:
+	// "recursive", "none", or a list of submodule paths.
+	Submodules Submodules `yaml:"submodules,omitempty"`
+
+	// LFS enables fetching Git LFS objects at checkout.
+	LFS bool `yaml:"lfs,omitempty"`
+
+	// Resources limits the memory, CPU and time each build may use.
+	Resources Resources `yaml:"resources,omitempty"`
+
+	// Rust configures the Rust toolchain and crate vendoring for packages
+	// with Rust extensions.
+	Rust Rust `yaml:"rust,omitempty"`
+
+	// src is the file the config was loaded from, if any.
+	src *source
 }
 
 // Version represents a tag-to-version mapping.
@@ -36,6 +79,10 @@ type Version struct {
 
 	// Version is the PyPI version string.
 	Version string `yaml:"version"`
+
+	// BuildRequires are exact build requirement pins recorded from a
+	// successful build. They take precedence over base and override constraints.
+	BuildRequires []string `yaml:"build_requires,omitempty"`
 }
 
 // Override represents version-specific build configuration.
@@ -51,10 +98,97 @@ type Override struct {
 	Env map[string]string `yaml:"env,omitempty"`
 
 	// Patches are additional patch files (merged with base config).
-	Patches []string `yaml:"patches,omitempty"`
+	Patches []Patch `yaml:"patches,omitempty"`
 
 	// Script replaces the base script entirely.
 	Script string `yaml:"script,omitempty"`
+
+	// ConfigSettings are additional backend config settings (merged with base config).
+	ConfigSettings map[string]string `yaml:"config_settings,omitempty"`
+
+	// BuildRequires are additional build requirement constraints. Entries
+	// replace base entries for the same package.
+	BuildRequires []string `yaml:"build_requires,omitempty"`
+
+	// BuildConstraints replaces the base constraints file.
+	BuildConstraints string `yaml:"build_constraints,omitempty"`
+
+	// Resources replaces individual base resource limits.
+	Resources Resources `yaml:"resources,omitempty"`
+}
+
+// Resources are per-build resource limits. Unset fields are unlimited
+// (or, for Timeout, the builder default).
+type Resources struct {
+	// Memory is the memory limit in Kubernetes quantity form (e.g., "16Gi", "512M").
+	Memory string `yaml:"memory,omitempty"`
+
+	// CPUs is the CPU limit in cores (e.g., "4", "1.5", "500m").
+	CPUs string `yaml:"cpus,omitempty"`
+
+	// Timeout is the wall-clock limit per build (e.g., "2h", "45m").
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Rust configures Rust builds.
+type Rust struct {
+	// Toolchain pins the Rust toolchain version (e.g., "1.75" or "1.75.0").
+	Toolchain string `yaml:"toolchain,omitempty"`
+
+	// Targets are Rust target triples by architecture (e.g.,
+	// "aarch64-unknown-linux-gnu"). The target for the build platform's
+	// architecture is passed as CARGO_BUILD_TARGET.
+	Targets []string `yaml:"targets,omitempty"`
+
+	// CargoVendor vendors crates before the build so it runs offline.
+	CargoVendor bool `yaml:"cargo_vendor,omitempty"`
+}
+
+// Patch apply strategies.
+const (
+	// PatchStrict applies with git apply and no fuzz (default).
+	PatchStrict = "strict"
+	// PatchThreeWay falls back to a three-way merge with git apply --3way.
+	PatchThreeWay = "3way"
+	// PatchFuzz applies with patch -F, tolerating drifted context lines.
+	PatchFuzz = "fuzz"
+)
+
+// DefaultPatchFuzz is the fuzz factor used by PatchFuzz when none is set.
+const DefaultPatchFuzz = 2
+
+// Patch is a patch file to apply to the source.
+// In YAML it is either a path string or a mapping with options.
+type Patch struct {
+	// Path is the patch file path, relative to the package directory.
+	Path string `yaml:"path"`
+
+	// Strategy is how the patch is applied (default: PatchStrict).
+	Strategy string `yaml:"strategy,omitempty"`
+
+	// Fuzz is the fuzz factor for PatchFuzz (default: DefaultPatchFuzz).
+	Fuzz int `yaml:"fuzz,omitempty"`
+
+	// Match is an optional PEP 440 specifier limiting the versions the patch applies to.
+	Match string `yaml:"match,omitempty"`
+}
+
+// Submodule modes.
+const (
+	SubmodulesNone      = "none"
+	SubmodulesRecursive = "recursive"
+	SubmodulesPaths     = "paths"
+)
+
+// Submodules selects which git submodules are initialized at checkout.
+// In YAML it is either a mode string ("recursive", "none") or a list of paths.
+type Submodules struct {
+	// Mode is one of SubmodulesNone, SubmodulesRecursive or SubmodulesPaths.
+	// Empty means none.
+	Mode string
+
+	// Paths lists the submodules to initialize when Mode is SubmodulesPaths.
+	Paths []string
 }
 
 // DefaultVersionCount is the default number of versions to build.
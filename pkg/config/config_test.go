@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -177,6 +178,43 @@ func TestSaveSkipsEmpty(t *testing.T) {
 	}
 }
 
+func TestSkipsFind(t *testing.T) {
+	skips := &Skips{Skips: []Skip{
+		{Version: "1.0.0", Python: []string{"3.13"}, Reason: "exact"},
+		{Version: "<1.5", Python: []string{"3.12", "3.13"}, Reason: "range"},
+	}}
+
+	tests := []struct {
+		version string
+		python  string
+		want    string
+	}{
+		{"1.0.0", "3.13", "exact"},
+		{"1.0.0", "3.12", "range"},
+		{"1.4.0", "3.13", "range"},
+		{"1.5.0", "3.13", ""},
+		{"1.0.0", "3.11", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+"/"+tt.python, func(t *testing.T) {
+			got := skips.Find(tt.version, tt.python)
+			reason := ""
+			if got != nil {
+				reason = got.Reason
+			}
+			if reason != tt.want {
+				t.Errorf("Find(%q, %q) = %q, want %q", tt.version, tt.python, reason, tt.want)
+			}
+		})
+	}
+
+	var none *Skips
+	if none.Find("1.0.0", "3.13") != nil {
+		t.Error("Find() on nil skips should return nil")
+	}
+}
+
 func TestLoadClaim(t *testing.T) {
 	dir := t.TempDir()
 	claimPath := filepath.Join(dir, "numpy.yaml")
@@ -231,3 +269,152 @@ func TestSaveAndLoadClaim(t *testing.T) {
 		t.Errorf("Type = %q, want %q", loaded.Type, claim.Type)
 	}
 }
+
+func TestClaimLastSeen(t *testing.T) {
+	claimed := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	renewed := claimed.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		claim Claim
+		want  time.Time
+	}{
+		{"never renewed", Claim{ClaimedAt: claimed}, claimed},
+		{"renewed", Claim{ClaimedAt: claimed, RenewedAt: renewed}, renewed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claim.LastSeen(); !got.Equal(tt.want) {
+				t.Errorf("LastSeen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSubmodules(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		wantMode  string
+		wantPaths []string
+	}{
+		{"unset", "", "", nil},
+		{"recursive", "submodules: recursive\n", SubmodulesRecursive, nil},
+		{"none", "submodules: none\n", SubmodulesNone, nil},
+		{"paths", "submodules:\n  - third_party/pybind11\n  - vendor/zlib\n", SubmodulesPaths, []string{"third_party/pybind11", "vendor/zlib"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			content := "repo: https://github.com/example/pkg\nlfs: true\n" + tt.yaml
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if cfg.Submodules.Mode != tt.wantMode {
+				t.Errorf("Submodules.Mode = %q, want %q", cfg.Submodules.Mode, tt.wantMode)
+			}
+			if len(cfg.Submodules.Paths) != len(tt.wantPaths) {
+				t.Errorf("Submodules.Paths = %v, want %v", cfg.Submodules.Paths, tt.wantPaths)
+			}
+			if !cfg.LFS {
+				t.Error("LFS = false, want true")
+			}
+
+			// Round trip
+			if err := SaveConfig(cfg, configPath); err != nil {
+				t.Fatalf("SaveConfig failed: %v", err)
+			}
+			loaded, err := LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if loaded.Submodules.Mode != tt.wantMode || len(loaded.Submodules.Paths) != len(tt.wantPaths) {
+				t.Errorf("round trip Submodules = %+v, want mode %q paths %v", loaded.Submodules, tt.wantMode, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestLoadConfigPatches(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `repo: https://github.com/example/pkg
+patches:
+  - patches/plain.patch
+  - path: patches/drifty.patch
+    strategy: fuzz
+    fuzz: 3
+overrides:
+  - match: "<2.0"
+    patches:
+      - path: patches/legacy.patch
+        strategy: 3way
+        match: ">=1.5"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	want := []Patch{
+		{Path: "patches/plain.patch"},
+		{Path: "patches/drifty.patch", Strategy: PatchFuzz, Fuzz: 3},
+	}
+	if len(cfg.Patches) != len(want) {
+		t.Fatalf("Patches = %+v, want %+v", cfg.Patches, want)
+	}
+	for i := range want {
+		if cfg.Patches[i] != want[i] {
+			t.Errorf("Patches[%d] = %+v, want %+v", i, cfg.Patches[i], want[i])
+		}
+	}
+	legacy := Patch{Path: "patches/legacy.patch", Strategy: PatchThreeWay, Match: ">=1.5"}
+	if len(cfg.Overrides[0].Patches) != 1 || cfg.Overrides[0].Patches[0] != legacy {
+		t.Errorf("Overrides[0].Patches = %+v, want [%+v]", cfg.Overrides[0].Patches, legacy)
+	}
+
+	// Plain patches round trip as strings
+	if err := SaveConfig(cfg, configPath); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- patches/plain.patch\n") {
+		t.Errorf("plain patch not written as a string:\n%s", data)
+	}
+}
+
+func TestPatchAppliesTo(t *testing.T) {
+	tests := []struct {
+		patch   Patch
+		version string
+		want    bool
+	}{
+		{Patch{Path: "a.patch"}, "1.0.0", true},
+		{Patch{Path: "a.patch", Match: "<2.0"}, "1.0.0", true},
+		{Patch{Path: "a.patch", Match: "<2.0"}, "2.0.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch.Match+"_"+tt.version, func(t *testing.T) {
+			got, err := tt.patch.AppliesTo(tt.version)
+			if err != nil {
+				t.Fatalf("AppliesTo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AppliesTo(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
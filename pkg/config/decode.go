@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PositionError is an error at a position in a config file. Line and
+// Column are 1-based; zero means unknown.
+type PositionError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ":%d", e.Column)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// source is the parsed file a config was loaded from, used to position
+// validation errors.
+type source struct {
+	path string
+	root *yaml.Node
+}
+
+// yamlLineRe matches the line prefix of yaml.v3 syntax and type errors.
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Decode strictly decodes YAML from path into v: fields v doesn't have are
+// errors, reported with their line and column. All unknown fields are
+// reported together. Returns the parsed document for positioning later
+// errors, or nil for an empty file.
+func Decode(path string, data []byte, v interface{}) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, yamlError(path, nil, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+
+	if errs := unknownFields(path, root, reflect.TypeOf(v)); len(errs) > 0 {
+		return nil, errors.Join(uniqueErrors(errs)...)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return nil, yamlError(path, root, err)
+	}
+	return root, nil
+}
+
+// yamlError converts a yaml.v3 error into PositionErrors.
+func yamlError(path string, root *yaml.Node, err error) error {
+	var msgs []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make([]error, 0, len(msgs))
+	for _, msg := range msgs {
+		pe := &PositionError{Path: path, Err: errors.New(strings.TrimPrefix(msg, "yaml: "))}
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			pe.Line, _ = strconv.Atoi(m[1])
+			pe.Column = valueColumn(root, pe.Line)
+			pe.Err = errors.New(m[2])
+		}
+		errs = append(errs, pe)
+	}
+	return errors.Join(errs...)
+}
+
+// valueColumn returns the column of the first value (not mapping key)
+// on a line, or 0.
+func valueColumn(n *yaml.Node, line int) int {
+	if n == nil {
+		return 0
+	}
+	for i, c := range n.Content {
+		isKey := n.Kind == yaml.MappingNode && i%2 == 0
+		if !isKey && c.Line == line {
+			return c.Column
+		}
+		if col := valueColumn(c, line); col != 0 {
+			return col
+		}
+	}
+	return 0
+}
+
+// unknownFields returns an error for each mapping key under n that the
+// struct type t (or the structs it contains) has no field for. Aliases
+// are followed, and mappings merged in with "<<" are checked against t.
+func unknownFields(path string, n *yaml.Node, t reflect.Type) []error {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map) {
+		if t.Kind() == reflect.Map && n.Kind == yaml.MappingNode {
+			var errs []error
+			for i := 1; i < len(n.Content); i += 2 {
+				errs = append(errs, unknownFields(path, n.Content[i], t.Elem())...)
+			}
+			return errs
+		}
+		if t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode {
+			var errs []error
+			for _, c := range n.Content {
+				errs = append(errs, unknownFields(path, c, t.Elem())...)
+			}
+			return errs
+		}
+		if t.Kind() != reflect.Pointer {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	_, fields := StructFields(t)
+	var errs []error
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i]
+		if key.ShortTag() == "!!merge" {
+			merged := []*yaml.Node{n.Content[i+1]}
+			if v := n.Content[i+1]; v.Kind == yaml.SequenceNode {
+				merged = v.Content
+			}
+			for _, m := range merged {
+				errs = append(errs, unknownFields(path, m, t)...)
+			}
+			continue
+		}
+		ft, ok := fields[key.Value]
+		if !ok {
+			msg := fmt.Sprintf("unknown field %q", key.Value)
+			if s := suggest(key.Value, fields); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			errs = append(errs, &PositionError{Path: path, Line: key.Line, Column: key.Column, Err: errors.New(msg)})
+			continue
+		}
+		errs = append(errs, unknownFields(path, n.Content[i+1], ft)...)
+	}
+	return errs
+}
+
+// uniqueErrors drops repeated errors, such as those of an anchored node
+// reported again where it's merged in.
+func uniqueErrors(errs []error) []error {
+	seen := make(map[string]bool, len(errs))
+	var unique []error
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			unique = append(unique, err)
+		}
+	}
+	return unique
+}
+
+// StructFields returns the YAML keys of a struct type in field order and
+// the type of each.
+func StructFields(t reflect.Type) ([]string, map[string]reflect.Type) {
+	var order []string
+	types := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		order = append(order, name)
+		types[name] = f.Type
+	}
+	return order, types
+}
+
+// suggest returns the field closest to an unknown key, if it's a likely
+// typo (e.g., "sytem_deps" for "system_deps" or "override" for "overrides").
+func suggest(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// fieldAliases maps the singular names validation errors use for list
+// entries to their YAML keys.
+var fieldAliases = map[string]string{
+	"version":  "versions",
+	"override": "overrides",
+	"patch":    "patches",
+	"skip":     "skips",
+}
+
+// fieldSegmentRe matches a leading field path segment of a validation
+// error, e.g. "override[1]: ", "rust.targets[0]: " or "tag_pattern: ".
+var fieldSegmentRe = regexp.MustCompile(`^([a-z_]+(?:\.[a-z_]+)*)(?:\[(\d+)\])?: `)
+
+// patchFileRe matches the "patch {path}: " prefix of patch file errors.
+var patchFileRe = regexp.MustCompile(`^patch (\S+): `)
+
+// position wraps each of a validation error's errors in a PositionError
+// pointing at the field it names.
+func (s *source) position(err error) error {
+	if s == nil || err == nil {
+		return err
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		positioned := make([]error, len(errs))
+		for i, e := range errs {
+			positioned[i] = s.position(e)
+		}
+		return errors.Join(positioned...)
+	}
+
+	n := s.locate(err.Error())
+	return &PositionError{Path: s.path, Line: n.Line, Column: n.Column, Err: err}
+}
+
+// locate returns the node a validation error message is about: the
+// deepest node along its field path, or the document root.
+func (s *source) locate(msg string) *yaml.Node {
+	n := s.root
+	if m := patchFileRe.FindStringSubmatch(msg); m != nil {
+		if p := findScalar(n, m[1]); p != nil {
+			return p
+		}
+		return n
+	}
+	for {
+		m := fieldSegmentRe.FindStringSubmatch(msg)
+		if m == nil {
+			return n
+		}
+		msg = msg[len(m[0]):]
+		for _, key := range strings.Split(m[1], ".") {
+			if alias, ok := fieldAliases[key]; ok {
+				key = alias
+			}
+			next := MappingValue(n, key)
+			if next == nil {
+				return n
+			}
+			n = next
+		}
+		if m[2] != "" {
+			i, _ := strconv.Atoi(m[2])
+			if n.Kind != yaml.SequenceNode || i >= len(n.Content) {
+				return n
+			}
+			n = n.Content[i]
+		}
+	}
+}
+
+// MappingValue returns the value of key in a mapping node, or nil.
+func MappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// findScalar returns the first scalar node with a value, in document order.
+func findScalar(n *yaml.Node, value string) *yaml.Node {
+	if n.Kind == yaml.ScalarNode && n.Value == value {
+		return n
+	}
+	for _, c := range n.Content {
+		if found := findScalar(c, value); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "top-level typo",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+sytem_deps:
+  - openblas-dev
+`,
+			want: []string{`config.yaml:5:1: unknown field "sytem_deps" (did you mean "system_deps"?)`},
+		},
+		{
+			name: "singular overrides",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+override:
+  - match: ">=1.0"
+`,
+			want: []string{`config.yaml:5:1: unknown field "override" (did you mean "overrides"?)`},
+		},
+		{
+			name: "nested fields",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+    pyhton: ["3.12"]
+overrides:
+  - match: ">=1.0"
+    evn:
+      CFLAGS: -O2
+patches:
+  - path: patches/fix.patch
+    stratgy: 3way
+`,
+			want: []string{
+				`config.yaml:5:5: unknown field "pyhton"`,
+				`config.yaml:8:5: unknown field "evn" (did you mean "env"?)`,
+				`config.yaml:12:5: unknown field "stratgy" (did you mean "strategy"?)`,
+			},
+		},
+		{
+			name: "type error",
+			content: `repo: https://github.com/example/pkg
+version_count: many
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+`,
+			want: []string{"config.yaml:2:16: cannot unmarshal !!str `many` into int"},
+		},
+		{
+			name: "syntax error",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+   version: 1.0.0
+`,
+			want: []string{"config.yaml:2: did not find expected"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("LoadConfig() = nil error, want error")
+			}
+			var pe *PositionError
+			if !errors.As(err, &pe) || pe.Path != path {
+				t.Errorf("LoadConfig() error = %v, want a PositionError for %s", err, path)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadConfig() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSkipsStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skips.yaml")
+	content := `skips:
+  - version: 1.0.0
+    python: ["3.13"]
+    reasn: needs distutils
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadSkips(path)
+	want := `skips.yaml:4:5: unknown field "reasn" (did you mean "reason"?)`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadSkips() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestLoadClaimStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "numpy.yaml")
+	content := `agent: agent-1
+claimed_at: 2025-01-01T00:00:00Z
+tokn: 3
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadClaim(path)
+	want := `numpy.yaml:3:1: unknown field "tokn" (did you mean "token"?)`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadClaim() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestValidateConfigPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "missing repo",
+			content: `versions:
+  - tag: v1.0.0
+    version: 1.0.0
+`,
+			want: "config.yaml:1:1: repo is required",
+		},
+		{
+			name: "version entry",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+  - version: 0.9.0
+`,
+			want: "config.yaml:5:5: version[1]: tag is required",
+		},
+		{
+			name: "override field",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+overrides:
+  - match: ">=1.0"
+  - match: "not a specifier"
+`,
+			want: "config.yaml:7:5: override[1]: ",
+		},
+		{
+			name: "rust target",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+rust:
+  targets:
+    - x86_64-unknown-linux-gnu
+    - x86_64
+`,
+			want: "config.yaml:8:7: rust.targets[1]: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			err = ValidateConfig(cfg, "")
+			if err == nil || !strings.HasPrefix(err.Error(), filepath.Dir(path)+"/"+tt.want) {
+				t.Errorf("ValidateConfig() error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSkipsPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skips.yaml")
+	content := `skips:
+  - version: 1.0.0
+    python: ["3.13"]
+    reason: needs distutils
+  - version: 1.1.0
+    python: ["3.13"]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	skips, err := LoadSkips(path)
+	if err != nil {
+		t.Fatalf("LoadSkips() error = %v", err)
+	}
+	err = ValidateSkips(skips)
+	want := path + ":5:5: skip[1]: reason is required"
+	if err == nil || err.Error() != want {
+		t.Errorf("ValidateSkips() error = %v, want %q", err, want)
+	}
+}
+
+func TestValidateConfigWithoutSource(t *testing.T) {
+	err := ValidateConfig(&Config{}, "")
+	var pe *PositionError
+	if err == nil || errors.As(err, &pe) {
+		t.Errorf("ValidateConfig() error = %v, want an unpositioned error", err)
+	}
+}
+
+func TestLoadConfigMergeKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "merged override",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+overrides:
+  - &base
+    match: ">=2.0"
+    env:
+      CFLAGS: -O2
+  - <<: *base
+    match: "<1.0"
+`,
+		},
+		{
+			name: "merged list",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+overrides:
+  - &env
+    match: ">=2.0"
+    env: {A: "1"}
+  - &deps
+    match: ">=3.0"
+    system_deps: [zlib-dev]
+  - <<: [*env, *deps]
+    match: "<1.0"
+`,
+		},
+		{
+			name: "typo in anchored mapping",
+			content: `repo: https://github.com/example/pkg
+versions:
+  - tag: v1.0.0
+    version: 1.0.0
+overrides:
+  - &base
+    match: ">=2.0"
+    evn:
+      CFLAGS: -O2
+  - <<: *base
+    match: "<1.0"
+`,
+			want: `config.yaml:8:5: unknown field "evn" (did you mean "env"?)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig() error = %v", err)
+				}
+				last := cfg.Overrides[len(cfg.Overrides)-1]
+				if last.Match != "<1.0" || len(last.Env) == 0 {
+					t.Errorf("merged override = %+v, want match <1.0 with the anchored env", last)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadConfig() error = %v, want %q", err, tt.want)
+			}
+			if n := strings.Count(err.Error(), "evn"); n != 1 {
+				t.Errorf("LoadConfig() reported the typo %d times, want once:\n%v", n, err)
+			}
+		})
+	}
+}
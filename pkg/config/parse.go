@@ -16,9 +16,13 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	root, err := Decode(path, data, &cfg)
+	if err != nil {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
+	if root != nil {
+		cfg.src = &source{path: path, root: root}
+	}
 
 	// Apply defaults
 	if cfg.VersionCount == 0 {
@@ -57,9 +61,13 @@ func LoadSkips(path string) (*Skips, error) {
 	}
 
 	var skips Skips
-	if err := yaml.Unmarshal(data, &skips); err != nil {
+	root, err := Decode(path, data, &skips)
+	if err != nil {
 		return nil, fmt.Errorf("parsing skips file: %w", err)
 	}
+	if root != nil {
+		skips.src = &source{path: path, root: root}
+	}
 
 	return &skips, nil
 }
@@ -98,7 +106,7 @@ func LoadClaim(path string) (*Claim, error) {
 	}
 
 	var claim Claim
-	if err := yaml.Unmarshal(data, &claim); err != nil {
+	if _, err := Decode(path, data, &claim); err != nil {
 		return nil, fmt.Errorf("parsing claim file: %w", err)
 	}
 
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PatchesDir is the directory inside a package that holds patch files.
+const PatchesDir = "patches"
+
+// hunkHeaderRe matches a unified diff hunk header: @@ -a[,b] +c[,d] @@
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// AppliesTo reports whether the patch should be applied to version.
+// Patches without a match apply to every version.
+func (p Patch) AppliesTo(version string) (bool, error) {
+	if p.Match == "" {
+		return true, nil
+	}
+	return MatchesVersion(version, p.Match)
+}
+
+// EffectiveStrategy returns the strategy, defaulting to PatchStrict.
+func (p Patch) EffectiveStrategy() string {
+	if p.Strategy == "" {
+		return PatchStrict
+	}
+	return p.Strategy
+}
+
+// EffectiveFuzz returns the fuzz factor, defaulting to DefaultPatchFuzz.
+func (p Patch) EffectiveFuzz() int {
+	if p.Fuzz == 0 {
+		return DefaultPatchFuzz
+	}
+	return p.Fuzz
+}
+
+// UnmarshalYAML accepts either a path string or a mapping.
+func (p *Patch) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*p = Patch{Path: node.Value}
+		return nil
+	case yaml.MappingNode:
+		// Decode via an alias type to avoid recursing into this method
+		type plain Patch
+		var v plain
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		*p = Patch(v)
+		return nil
+	default:
+		return fmt.Errorf("line %d: patch must be a path or a mapping", node.Line)
+	}
+}
+
+// MarshalYAML writes patches without options as a plain path string.
+func (p Patch) MarshalYAML() (interface{}, error) {
+	if p.Strategy == "" && p.Fuzz == 0 && p.Match == "" {
+		return p.Path, nil
+	}
+	type plain Patch
+	return plain(p), nil
+}
+
+// AllPatches returns every patch referenced by the base config and overrides.
+func (cfg *Config) AllPatches() []Patch {
+	patches := append([]Patch{}, cfg.Patches...)
+	for _, o := range cfg.Overrides {
+		patches = append(patches, o.Patches...)
+	}
+	return patches
+}
+
+// isPatchPath reports whether p is a clean relative path inside PatchesDir.
+func isPatchPath(p string) bool {
+	if filepath.IsAbs(p) || strings.Contains(p, "\\") {
+		return false
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	clean := path.Clean(p)
+	return strings.HasPrefix(clean, PatchesDir+"/")
+}
+
+// validatePatchFiles checks referenced patch files against packageDir and
+// reports missing, malformed and orphaned patches together.
+func validatePatchFiles(cfg *Config, packageDir string) error {
+	var errs []error
+	referenced := make(map[string]bool)
+
+	for _, p := range cfg.AllPatches() {
+		clean := path.Clean(p.Path)
+		if referenced[clean] {
+			continue
+		}
+		referenced[clean] = true
+
+		data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(clean)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("patch %s: file does not exist", p.Path))
+			} else {
+				errs = append(errs, fmt.Errorf("patch %s: %w", p.Path, err))
+			}
+			continue
+		}
+		if err := ParsePatch(data); err != nil {
+			errs = append(errs, fmt.Errorf("patch %s: %w", p.Path, err))
+		}
+	}
+
+	patchesDir := filepath.Join(packageDir, PatchesDir)
+	var orphans []string
+	err := filepath.WalkDir(patchesDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(packageDir, p)
+		if err != nil {
+			return err
+		}
+		if !referenced[filepath.ToSlash(rel)] {
+			orphans = append(orphans, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("scanning %s: %w", PatchesDir, err))
+	}
+	sort.Strings(orphans)
+	for _, o := range orphans {
+		errs = append(errs, fmt.Errorf("patch %s: not referenced by config", o))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParsePatch checks that data is a well-formed unified diff: it must contain
+// at least one file header pair and every hunk's line counts must match its
+// header. Git binary patches are accepted without hunk checks, and git
+// entries with extended headers (renames, copies, mode changes, new or
+// deleted empty files) count as files that need no hunks.
+func ParsePatch(data []byte) error {
+	if bytes.Contains(data, []byte("\nGIT binary patch\n")) {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	files, hunks, gitEntries := 0, 0, 0
+	lineNo := 0
+	sawOld, inGitHeader := false, false
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inGitHeader = true
+		case inGitHeader && isGitExtendedHeader(line):
+			gitEntries++
+			inGitHeader = false
+		case strings.HasPrefix(line, "--- "):
+			inGitHeader = false
+			sawOld = true
+		case strings.HasPrefix(line, "+++ "):
+			if !sawOld {
+				return fmt.Errorf("line %d: \"+++\" without preceding \"---\"", lineNo)
+			}
+			sawOld = false
+			files++
+		case strings.HasPrefix(line, "@@ "):
+			if files == 0 {
+				return fmt.Errorf("line %d: hunk before file header", lineNo)
+			}
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return fmt.Errorf("line %d: malformed hunk header %q", lineNo, line)
+			}
+			oldCount, newCount := hunkCount(m[2]), hunkCount(m[4])
+			start := lineNo
+			for oldCount > 0 || newCount > 0 {
+				if !scanner.Scan() {
+					return fmt.Errorf("line %d: hunk truncated", start)
+				}
+				lineNo++
+				body := scanner.Text()
+				switch {
+				case body == "" || body[0] == ' ':
+					oldCount--
+					newCount--
+				case body[0] == '-':
+					oldCount--
+				case body[0] == '+':
+					newCount--
+				case body[0] == '\\':
+					// "\ No newline at end of file"
+				default:
+					return fmt.Errorf("line %d: unexpected line in hunk starting at line %d", lineNo, start)
+				}
+				if oldCount < 0 || newCount < 0 {
+					return fmt.Errorf("line %d: hunk longer than its header", start)
+				}
+			}
+			hunks++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading patch: %w", err)
+	}
+
+	if files == 0 && gitEntries == 0 {
+		return fmt.Errorf("not a unified diff: no file headers")
+	}
+	if hunks == 0 && gitEntries == 0 {
+		return fmt.Errorf("not a unified diff: no hunks")
+	}
+	return nil
+}
+
+// gitExtendedHeaders are the git diff extended header lines that describe
+// a change without hunks.
+var gitExtendedHeaders = []string{
+	"old mode ", "new mode ", "deleted file mode ", "new file mode ",
+	"copy from ", "copy to ", "rename from ", "rename to ",
+}
+
+// isGitExtendedHeader reports whether line is a git extended header that
+// can make up a whole file entry.
+func isGitExtendedHeader(line string) bool {
+	for _, prefix := range gitExtendedHeaders {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkCount parses an optional hunk line count, which defaults to 1.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validPatch = `diff --git a/setup.py b/setup.py
+--- a/setup.py
++++ b/setup.py
+@@ -1,3 +1,3 @@
+ import setuptools
+-setuptools.setup(name="old")
++setuptools.setup(name="new")
+ # end
+`
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr bool
+	}{
+		{"valid", validPatch, false},
+		{"no newline marker", "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n", false},
+		{"binary", "diff --git a/x.bin b/x.bin\nGIT binary patch\nliteral 3\n", false},
+		{"rename", "diff --git a/old.py b/new.py\nsimilarity index 100%\nrename from old.py\nrename to new.py\n", false},
+		{"mode change", "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n", false},
+		{"new empty file", "diff --git a/pkg/__init__.py b/pkg/__init__.py\nnew file mode 100644\nindex 0000000..e69de29\n", false},
+		{"rename and edit", "diff --git a/a b/b\nsimilarity index 90%\nrename from a\nrename to b\n--- a/a\n+++ b/b\n@@ -1 +1 @@\n-x\n+y\n", false},
+		{"git header only", "diff --git a/f b/f\nindex 1111111..2222222 100644\n", true},
+		{"empty", "", true},
+		{"not a diff", "hello world\n", true},
+		{"no hunks", "--- a/f\n+++ b/f\n", true},
+		{"truncated hunk", "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n", true},
+		{"malformed header", "--- a/f\n+++ b/f\n@@ -x +1 @@\n a\n", true},
+		{"garbage in hunk", "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n*b\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParsePatch([]byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPatchPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"patches/fix.patch", true},
+		{"patches/legacy/fix.patch", true},
+		{"fix.patch", false},
+		{"../numpy/patches/fix.patch", false},
+		{"patches/../../etc/passwd", false},
+		{"/etc/passwd", false},
+		{"patches\\fix.patch", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isPatchPath(tt.path); got != tt.want {
+				t.Errorf("isPatchPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigPatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"patches/good.patch":    validPatch,
+		"patches/broken.patch":  "not a patch\n",
+		"patches/orphan.patch":  validPatch,
+		"patches/old/fix.patch": validPatch,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{
+		Repo:     "https://github.com/test/pkg",
+		Versions: []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		Patches: []Patch{
+			{Path: "patches/good.patch"},
+			{Path: "patches/broken.patch"},
+			{Path: "patches/missing.patch"},
+		},
+		Overrides: []Override{
+			{Match: "<1.0", Patches: []Patch{{Path: "patches/old/fix.patch"}}},
+		},
+	}
+
+	err := ValidateConfig(cfg, dir)
+	if err == nil {
+		t.Fatal("ValidateConfig() should fail")
+	}
+	msg := err.Error()
+	for _, want := range []string{"patches/broken.patch", "patches/missing.patch: file does not exist", "patches/orphan.patch: not referenced"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+	for _, unwanted := range []string{"good.patch", "old/fix.patch"} {
+		if strings.Contains(msg, unwanted) {
+			t.Errorf("error should not mention %q:\n%s", unwanted, msg)
+		}
+	}
+
+	// Fix everything and validate again
+	cfg.Patches = []Patch{{Path: "patches/good.patch"}, {Path: "patches/orphan.patch"}}
+	if err := os.Remove(filepath.Join(dir, "patches", "broken.patch")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateConfig(cfg, dir); err != nil {
+		t.Errorf("ValidateConfig() error = %v", err)
+	}
+}
+
+func TestValidateConfigPatchTraversal(t *testing.T) {
+	cfg := &Config{
+		Repo:     "https://github.com/test/pkg",
+		Versions: []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		Patches:  []Patch{{Path: "../other/patches/fix.patch"}},
+	}
+	if err := ValidateConfig(cfg, ""); err == nil {
+		t.Error("ValidateConfig() should reject path traversal")
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// requirementRe matches a PEP 508 requirement: a name, optional extras,
+// optional version specifiers and an optional environment marker.
+var requirementRe = regexp.MustCompile(`^([A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?)\s*(\[[^\]]*\])?\s*([<>=!~][^;]*)?(;.+)?$`)
+
+// nameSeparatorRe matches runs of characters that PEP 503 treats as equivalent.
+var nameSeparatorRe = regexp.MustCompile(`[-_.]+`)
+
+// NormalizeName normalizes a Python package name per PEP 503.
+func NormalizeName(name string) string {
+	return strings.ToLower(nameSeparatorRe.ReplaceAllString(strings.TrimSpace(name), "-"))
+}
+
+// RequirementName returns the normalized package name of a PEP 508
+// requirement, or "" if it cannot be parsed.
+func RequirementName(req string) string {
+	m := requirementRe.FindStringSubmatch(strings.TrimSpace(req))
+	if m == nil {
+		return ""
+	}
+	return NormalizeName(m[1])
+}
+
+// isValidRequirement checks that req is a PEP 508 requirement whose version
+// specifiers, if any, are valid.
+func isValidRequirement(req string) bool {
+	m := requirementRe.FindStringSubmatch(strings.TrimSpace(req))
+	if m == nil {
+		return false
+	}
+	if spec := strings.TrimSpace(m[3]); spec != "" {
+		if !isValidPEP440(spec) {
+			return false
+		}
+		// Rejects operators the pattern allows but PEP 440 doesn't, like "="
+		_, err := MatchesVersion("0", spec)
+		return err == nil
+	}
+	return true
+}
+
+// MergeRequirements returns base with extra applied on top: entries in extra
+// replace base entries for the same package, and new packages are appended.
+func MergeRequirements(base, extra []string) []string {
+	if len(extra) == 0 {
+		return append([]string{}, base...)
+	}
+
+	replaced := make(map[string]bool)
+	for _, r := range extra {
+		replaced[RequirementName(r)] = true
+	}
+
+	result := make([]string, 0, len(base)+len(extra))
+	for _, r := range base {
+		if !replaced[RequirementName(r)] {
+			result = append(result, r)
+		}
+	}
+	return append(result, extra...)
+}
+
+// FindVersion returns the configured version entry for a version string.
+func (cfg *Config) FindVersion(version string) *Version {
+	for i := range cfg.Versions {
+		if cfg.Versions[i].Version == version {
+			return &cfg.Versions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"numpy", "numpy"},
+		{"Typing_Extensions", "typing-extensions"},
+		{"zope.interface", "zope-interface"},
+		{"foo-_.bar", "foo-bar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeName(tt.name); got != tt.want {
+				t.Errorf("NormalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidRequirement(t *testing.T) {
+	tests := []struct {
+		req   string
+		valid bool
+	}{
+		{"setuptools", true},
+		{"setuptools<70", true},
+		{"Cython>=0.29,<3", true},
+		{"setuptools-scm[toml]>=6.2", true},
+		{`numpy==1.21.6; python_version == "3.10"`, true},
+		{"", false},
+		{"<70", false},
+		{"setuptools latest", false},
+		{"setuptools=70", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.req, func(t *testing.T) {
+			if got := isValidRequirement(tt.req); got != tt.valid {
+				t.Errorf("isValidRequirement(%q) = %v, want %v", tt.req, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMergeRequirements(t *testing.T) {
+	base := []string{"setuptools<70", "Cython<3", "wheel"}
+	extra := []string{"cython==0.29.36", "numpy==1.21.6"}
+
+	got := MergeRequirements(base, extra)
+	want := []string{"setuptools<70", "wheel", "cython==0.29.36", "numpy==1.21.6"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeRequirements() = %v, want %v", got, want)
+	}
+
+	// The base slice must not be modified
+	if base[1] != "Cython<3" {
+		t.Errorf("base modified: %v", base)
+	}
+}
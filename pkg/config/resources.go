@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memorySuffixes are the quantity suffixes accepted for memory limits.
+var memorySuffixes = []struct {
+	suffix string
+	factor int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"k", 1e3},
+	{"K", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+}
+
+// ParseMemory parses a memory quantity (e.g., "16Gi", "512M", "1048576")
+// into bytes.
+func ParseMemory(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	factor := int64(1)
+	for _, m := range memorySuffixes {
+		if strings.HasSuffix(num, m.suffix) {
+			num = strings.TrimSuffix(num, m.suffix)
+			factor = m.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// ParseCPUs parses a CPU quantity in cores (e.g., "4", "1.5", "500m").
+func ParseCPUs(s string) (float64, error) {
+	num := strings.TrimSpace(s)
+	divisor := 1.0
+	if strings.HasSuffix(num, "m") {
+		num = strings.TrimSuffix(num, "m")
+		divisor = 1000
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid CPU quantity %q", s)
+	}
+	return n / divisor, nil
+}
+
+// IsZero reports whether no limits are set.
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// Merge returns r with the fields set in o replacing its own.
+func (r Resources) Merge(o Resources) Resources {
+	if o.Memory != "" {
+		r.Memory = o.Memory
+	}
+	if o.CPUs != "" {
+		r.CPUs = o.CPUs
+	}
+	if o.Timeout != "" {
+		r.Timeout = o.Timeout
+	}
+	return r
+}
+
+// MemoryBytes returns the memory limit in bytes, or 0 if unset.
+func (r Resources) MemoryBytes() (int64, error) {
+	if r.Memory == "" {
+		return 0, nil
+	}
+	return ParseMemory(r.Memory)
+}
+
+// CPUCores returns the CPU limit in cores, or 0 if unset.
+func (r Resources) CPUCores() (float64, error) {
+	if r.CPUs == "" {
+		return 0, nil
+	}
+	return ParseCPUs(r.CPUs)
+}
+
+// TimeoutDuration returns the wall-clock limit, or 0 if unset.
+func (r Resources) TimeoutDuration() (time.Duration, error) {
+	if r.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", r.Timeout)
+	}
+	return d, nil
+}
+
+// validateResources checks that all set resource limits parse.
+func validateResources(prefix string, r Resources) error {
+	if _, err := r.MemoryBytes(); err != nil {
+		return fmt.Errorf("%sresources: %w", prefix, err)
+	}
+	if _, err := r.CPUCores(); err != nil {
+		return fmt.Errorf("%sresources: %w", prefix, err)
+	}
+	if _, err := r.TimeoutDuration(); err != nil {
+		return fmt.Errorf("%sresources: %w", prefix, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"16Gi", 16 << 30, false},
+		{"512Mi", 512 << 20, false},
+		{"1.5Gi", 3 << 29, false},
+		{"2G", 2000000000, false},
+		{"100k", 100000, false},
+		{"1048576", 1048576, false},
+		{"", 0, true},
+		{"Gi", 0, true},
+		{"-1Gi", 0, true},
+		{"16GB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMemory(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMemory(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMemory(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCPUs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"4", 4, false},
+		{"1.5", 1.5, false},
+		{"500m", 0.5, false},
+		{"0", 0, true},
+		{"four", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCPUs(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCPUs(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCPUs(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourcesMerge(t *testing.T) {
+	base := Resources{Memory: "4Gi", CPUs: "2", Timeout: "1h"}
+	got := base.Merge(Resources{Memory: "16Gi"})
+	want := Resources{Memory: "16Gi", CPUs: "2", Timeout: "1h"}
+	if got != want {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+
+	d, err := got.TimeoutDuration()
+	if err != nil || d != time.Hour {
+		t.Errorf("TimeoutDuration() = %v, %v, want 1h", d, err)
+	}
+}
+
+func TestResourcesYAML(t *testing.T) {
+	var cfg Config
+	data := "repo: https://github.com/test/pkg\nresources: {memory: 16Gi, cpus: \"8\"}\n"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Resources.Memory != "16Gi" || cfg.Resources.CPUs != "8" {
+		t.Errorf("Resources = %+v", cfg.Resources)
+	}
+
+	out, err := yaml.Marshal(&Config{Repo: "https://github.com/test/pkg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out); got != "repo: https://github.com/test/pkg\nversions: []\n" {
+		t.Errorf("empty resources should be omitted, got:\n%s", got)
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	cfg := &Config{
+		Repo:     "https://github.com/test/pkg",
+		Versions: []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+		Overrides: []Override{
+			{Match: ">=1.0", Resources: Resources{Timeout: "forever"}},
+		},
+	}
+	if err := ValidateConfig(cfg, ""); err == nil {
+		t.Error("ValidateConfig() expected error for invalid timeout")
+	}
+
+	cfg.Overrides[0].Resources.Timeout = "90m"
+	cfg.Resources.Memory = "16Gi"
+	if err := ValidateConfig(cfg, ""); err != nil {
+		t.Errorf("ValidateConfig() error = %v", err)
+	}
+}
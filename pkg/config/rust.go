@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rustToolchainPattern matches a Rust release version.
+var rustToolchainPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+
+// IsZero reports whether nothing is configured.
+func (r Rust) IsZero() bool {
+	return r.Toolchain == "" && len(r.Targets) == 0 && !r.CargoVendor
+}
+
+// Target returns the target triple for an architecture (e.g., "aarch64"),
+// or "" if none is configured.
+func (r Rust) Target(arch string) string {
+	for _, t := range r.Targets {
+		if targetArch(t) == arch {
+			return t
+		}
+	}
+	return ""
+}
+
+// targetArch returns the architecture of a target triple.
+func targetArch(target string) string {
+	arch, _, _ := strings.Cut(target, "-")
+	return arch
+}
+
+// validateRust validates the rust section. systemDeps are the config's
+// system deps, which mustn't also pin the toolchain.
+func validateRust(r Rust, systemDeps []string) error {
+	if r.Toolchain != "" {
+		if !rustToolchainPattern.MatchString(r.Toolchain) {
+			return fmt.Errorf("rust.toolchain: invalid version %q (want e.g. \"1.75\" or \"1.75.0\")", r.Toolchain)
+		}
+		for _, dep := range systemDeps {
+			if i := strings.IndexAny(dep, "=<>~"); i >= 0 && dep[:i] == "rust" {
+				return fmt.Errorf("rust.toolchain: conflicts with pinned system dep %q", dep)
+			}
+		}
+	}
+
+	arches := make(map[string]bool)
+	for i, t := range r.Targets {
+		if strings.Count(t, "-") < 2 || strings.ContainsAny(t, " \t") {
+			return fmt.Errorf("rust.targets[%d]: invalid target triple %q", i, t)
+		}
+		arch := targetArch(t)
+		if arches[arch] {
+			return fmt.Errorf("rust.targets[%d]: duplicate target for %s", i, arch)
+		}
+		arches[arch] = true
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRustYAML(t *testing.T) {
+	var cfg Config
+	data := "repo: https://github.com/test/pkg\nrust:\n  toolchain: \"1.75\"\n  targets: [aarch64-unknown-linux-gnu]\n  cargo_vendor: true\n"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Rust.Toolchain != "1.75" || len(cfg.Rust.Targets) != 1 || !cfg.Rust.CargoVendor {
+		t.Errorf("Rust = %+v", cfg.Rust)
+	}
+}
+
+func TestRustTarget(t *testing.T) {
+	r := Rust{Targets: []string{"x86_64-unknown-linux-gnu", "aarch64-unknown-linux-musl"}}
+	if got := r.Target("aarch64"); got != "aarch64-unknown-linux-musl" {
+		t.Errorf("Target(aarch64) = %q", got)
+	}
+	if got := r.Target("riscv64"); got != "" {
+		t.Errorf("Target(riscv64) = %q, want none", got)
+	}
+}
+
+func TestValidateRust(t *testing.T) {
+	tests := []struct {
+		name       string
+		rust       Rust
+		systemDeps []string
+		wantErr    bool
+	}{
+		{"empty", Rust{}, nil, false},
+		{"valid", Rust{Toolchain: "1.75.0", Targets: []string{"x86_64-unknown-linux-gnu", "aarch64-unknown-linux-gnu"}, CargoVendor: true}, []string{"rust"}, false},
+		{"toolchain name", Rust{Toolchain: "stable"}, nil, true},
+		{"pinned rust dep", Rust{Toolchain: "1.75"}, []string{"rust=1.74.1-r0"}, true},
+		{"pinned other dep", Rust{Toolchain: "1.75"}, []string{"rust-src=1.74.1-r0"}, false},
+		{"bad triple", Rust{Targets: []string{"aarch64"}}, nil, true},
+		{"duplicate arch", Rust{Targets: []string{"aarch64-unknown-linux-gnu", "aarch64-unknown-linux-musl"}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Repo:       "https://github.com/test/pkg",
+				Versions:   []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+				SystemDeps: tt.systemDeps,
+				Rust:       tt.rust,
+			}
+			err := ValidateConfig(cfg, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
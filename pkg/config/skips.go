@@ -4,6 +4,9 @@ package config
 type Skips struct {
 	// Skips is the list of known failures.
 	Skips []Skip `yaml:"skips"`
+
+	// src is the file the skips were loaded from, if any.
+	src *source
 }
 
 // Skip represents a known build failure for a version/Python combination.
@@ -23,3 +26,38 @@ type Skip struct {
 	// Attempts is the number of times the fixer agent has tried.
 	Attempts int `yaml:"attempts,omitempty"`
 }
+
+// Matches reports whether the skip covers a version and Python version.
+// Version is an exact version or a PEP 440 specifier.
+func (s Skip) Matches(version, python string) bool {
+	covered := false
+	for _, py := range s.Python {
+		if py == python {
+			covered = true
+			break
+		}
+	}
+	return covered && s.MatchesVersion(version)
+}
+
+// MatchesVersion reports whether the skip covers a version for any Python.
+func (s Skip) MatchesVersion(version string) bool {
+	if s.Version == version {
+		return true
+	}
+	matches, err := MatchesVersion(version, s.Version)
+	return err == nil && matches
+}
+
+// Find returns the first skip covering a version and Python version, or nil.
+func (s *Skips) Find(version, python string) *Skip {
+	if s == nil {
+		return nil
+	}
+	for i := range s.Skips {
+		if s.Skips[i].Matches(version, python) {
+			return &s.Skips[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Enabled reports whether any submodules should be initialized.
+func (s Submodules) Enabled() bool {
+	return s.Mode == SubmodulesRecursive || (s.Mode == SubmodulesPaths && len(s.Paths) > 0)
+}
+
+// IsZero reports whether s is unset, so omitempty drops it when marshaling.
+func (s Submodules) IsZero() bool {
+	return s.Mode == "" && len(s.Paths) == 0
+}
+
+// UnmarshalYAML accepts either a mode string or a list of paths.
+func (s *Submodules) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		s.Mode = node.Value
+		s.Paths = nil
+		return nil
+	case yaml.SequenceNode:
+		var paths []string
+		if err := node.Decode(&paths); err != nil {
+			return err
+		}
+		s.Mode = SubmodulesPaths
+		s.Paths = paths
+		return nil
+	default:
+		return fmt.Errorf("line %d: submodules must be a string or a list of paths", node.Line)
+	}
+}
+
+// MarshalYAML writes paths mode as a list and other modes as a string.
+func (s Submodules) MarshalYAML() (interface{}, error) {
+	if s.Mode == SubmodulesPaths {
+		return s.Paths, nil
+	}
+	return s.Mode, nil
+}
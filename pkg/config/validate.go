@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 )
@@ -11,7 +12,15 @@ import (
 var pep440Pattern = regexp.MustCompile(`^([<>=!~]+\s*[\d\w.*]+)(,\s*[<>=!~]+\s*[\d\w.*]+)*$`)
 
 // ValidateConfig validates a Config for required fields and correct formats.
-func ValidateConfig(cfg *Config) error {
+// If packageDir is set, patch files are also checked against the package
+// directory: they must exist, parse as unified diffs, and every file under
+// patches/ must be referenced. For a config from LoadConfig, errors are
+// PositionErrors pointing at the offending field.
+func ValidateConfig(cfg *Config, packageDir string) error {
+	return cfg.src.position(validateConfig(cfg, packageDir))
+}
+
+func validateConfig(cfg *Config, packageDir string) error {
 	if cfg.Repo == "" {
 		return fmt.Errorf("repo is required")
 	}
@@ -32,6 +41,30 @@ func ValidateConfig(cfg *Config) error {
 			return fmt.Errorf("version[%d]: duplicate version %q", i, v.Version)
 		}
 		seen[v.Version] = true
+		if err := validateBuildRequires(fmt.Sprintf("version[%d]: ", i), v.BuildRequires, ""); err != nil {
+			return err
+		}
+	}
+
+	for i, v := range cfg.Retired {
+		if v.Tag == "" {
+			return fmt.Errorf("retired[%d]: tag is required", i)
+		}
+		if v.Version == "" {
+			return fmt.Errorf("retired[%d]: version is required", i)
+		}
+		if seen[v.Version] {
+			return fmt.Errorf("retired[%d]: duplicate version %q", i, v.Version)
+		}
+		seen[v.Version] = true
+	}
+
+	if err := validateBuildRequires("", cfg.BuildRequires, cfg.BuildConstraints); err != nil {
+		return err
+	}
+
+	if cfg.TagPattern != "" && strings.Count(cfg.TagPattern, "{version}") != 1 {
+		return fmt.Errorf("tag_pattern: %q must contain {version} exactly once", cfg.TagPattern)
 	}
 
 	for i, o := range cfg.Overrides {
@@ -41,13 +74,121 @@ func ValidateConfig(cfg *Config) error {
 		if !isValidPEP440(o.Match) {
 			return fmt.Errorf("override[%d]: invalid PEP 440 specifier %q", i, o.Match)
 		}
+		if err := validatePatches(fmt.Sprintf("override[%d]: ", i), o.Patches); err != nil {
+			return err
+		}
+		if err := validateBuildRequires(fmt.Sprintf("override[%d]: ", i), o.BuildRequires, o.BuildConstraints); err != nil {
+			return err
+		}
+		if err := validateResources(fmt.Sprintf("override[%d]: ", i), o.Resources); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePatches("", cfg.Patches); err != nil {
+		return err
+	}
+
+	if err := validateResources("", cfg.Resources); err != nil {
+		return err
+	}
+
+	if err := validateRust(cfg.Rust, cfg.SystemDeps); err != nil {
+		return err
+	}
+
+	if packageDir != "" {
+		if err := validatePatchFiles(cfg, packageDir); err != nil {
+			return err
+		}
 	}
 
+	switch cfg.Submodules.Mode {
+	case "", SubmodulesNone, SubmodulesRecursive:
+	case SubmodulesPaths:
+		if len(cfg.Submodules.Paths) == 0 {
+			return fmt.Errorf("submodules: empty list of paths (use %q to skip submodules)", SubmodulesNone)
+		}
+		for i, p := range cfg.Submodules.Paths {
+			if p == "" {
+				return fmt.Errorf("submodules[%d]: path is required", i)
+			}
+		}
+	default:
+		return fmt.Errorf("submodules: invalid mode %q (want %q, %q or a list of paths)",
+			cfg.Submodules.Mode, SubmodulesRecursive, SubmodulesNone)
+	}
+
+	return nil
+}
+
+// ConfigWarnings returns problems with a config that don't make it
+// invalid, such as listing more versions than version_count, each as
+// "field: message".
+func ConfigWarnings(cfg *Config) []string {
+	var warnings []string
+	count := cfg.VersionCount
+	if count <= 0 {
+		count = DefaultVersionCount
+	}
+	if len(cfg.Versions) > count {
+		warnings = append(warnings, fmt.Sprintf("versions: %d versions listed, more than version_count (%d); older versions should be retired",
+			len(cfg.Versions), count))
+	}
+	return warnings
+}
+
+// validatePatches validates patch entries. prefix is prepended to errors.
+func validatePatches(prefix string, patches []Patch) error {
+	for i, p := range patches {
+		if p.Path == "" {
+			return fmt.Errorf("%spatch[%d]: path is required", prefix, i)
+		}
+		if !isPatchPath(p.Path) {
+			return fmt.Errorf("%spatch[%d]: %q must be a relative path inside %s/", prefix, i, p.Path, PatchesDir)
+		}
+		switch p.Strategy {
+		case "", PatchStrict, PatchThreeWay, PatchFuzz:
+		default:
+			return fmt.Errorf("%spatch[%d]: invalid strategy %q", prefix, i, p.Strategy)
+		}
+		if p.Fuzz < 0 {
+			return fmt.Errorf("%spatch[%d]: fuzz must not be negative", prefix, i)
+		}
+		if p.Fuzz != 0 && p.Strategy != PatchFuzz {
+			return fmt.Errorf("%spatch[%d]: fuzz requires strategy %q", prefix, i, PatchFuzz)
+		}
+		if p.Match != "" && !isValidPEP440(p.Match) {
+			return fmt.Errorf("%spatch[%d]: invalid PEP 440 specifier %q", prefix, i, p.Match)
+		}
+	}
+	return nil
+}
+
+// validateBuildRequires validates build requirement constraints and the
+// constraints file path. prefix is prepended to errors.
+func validateBuildRequires(prefix string, reqs []string, constraints string) error {
+	for i, r := range reqs {
+		if !isValidRequirement(r) {
+			return fmt.Errorf("%sbuild_requires[%d]: invalid requirement %q", prefix, i, r)
+		}
+	}
+	if constraints != "" {
+		clean := path.Clean(constraints)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%sbuild_constraints: %q must be a relative path inside the package directory", prefix, constraints)
+		}
+	}
 	return nil
 }
 
-// ValidateSkips validates a Skips for required fields.
+// ValidateSkips validates a Skips for required fields. For skips from
+// LoadSkips, errors are PositionErrors pointing at the offending skip.
 func ValidateSkips(skips *Skips) error {
+	return skips.src.position(validateSkips(skips))
+}
+
+func validateSkips(skips *Skips) error {
 	for i, s := range skips.Skips {
 		if s.Version == "" {
 			return fmt.Errorf("skip[%d]: version is required", i)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -21,6 +22,42 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid tag pattern",
+			cfg: &Config{
+				Repo:       "https://github.com/test/pkg",
+				Versions:   []Version{{Tag: "release-1.0.0", Version: "1.0.0"}},
+				TagPattern: "release-{version}",
+			},
+			wantErr: false,
+		},
+		{
+			name: "tag pattern without placeholder",
+			cfg: &Config{
+				Repo:       "https://github.com/test/pkg",
+				Versions:   []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+				TagPattern: "v",
+			},
+			wantErr: true,
+		},
+		{
+			name: "retired version also active",
+			cfg: &Config{
+				Repo:     "https://github.com/test/pkg",
+				Versions: []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+				Retired:  []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retired missing tag",
+			cfg: &Config{
+				Repo:     "https://github.com/test/pkg",
+				Versions: []Version{{Tag: "v1.0.0", Version: "1.0.0"}},
+				Retired:  []Version{{Version: "0.9.0"}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing repo",
 			cfg: &Config{
@@ -95,6 +132,135 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "recursive submodules",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Submodules: Submodules{Mode: SubmodulesRecursive},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid submodules mode",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Submodules: Submodules{Mode: "all"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty submodule path",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Submodules: Submodules{Mode: SubmodulesPaths, Paths: []string{""}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty submodule path list",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Submodules: Submodules{Mode: SubmodulesPaths},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid patch options",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Patches: []Patch{
+					{Path: "patches/a.patch", Strategy: PatchFuzz, Fuzz: 3, Match: "<2.0"},
+					{Path: "patches/b.patch", Strategy: PatchThreeWay},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid patch strategy",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Patches: []Patch{{Path: "patches/a.patch", Strategy: "magic"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fuzz without fuzz strategy",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Patches: []Patch{{Path: "patches/a.patch", Fuzz: 2}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid override patch match",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Overrides: []Override{
+					{Match: ">=1.0", Patches: []Patch{{Path: "patches/a.patch", Match: "latest"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid build requires",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0", BuildRequires: []string{"setuptools==69.0.3"}},
+				},
+				BuildRequires:    []string{"setuptools<70", "cython>=0.29,<3"},
+				BuildConstraints: "constraints/build.txt",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid build requires",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				Overrides: []Override{
+					{Match: "<2.0", BuildRequires: []string{"setuptools latest"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "build constraints outside package",
+			cfg: &Config{
+				Repo: "https://github.com/test/pkg",
+				Versions: []Version{
+					{Tag: "v1.0.0", Version: "1.0.0"},
+				},
+				BuildConstraints: "../shared/constraints.txt",
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty override match",
 			cfg: &Config{
@@ -112,7 +278,7 @@ func TestValidateConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateConfig(tt.cfg)
+			err := ValidateConfig(tt.cfg, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -311,3 +477,31 @@ func TestCompareVersions(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigWarnings(t *testing.T) {
+	versions := func(n int) []Version {
+		var vs []Version
+		for i := 0; i < n; i++ {
+			vs = append(vs, Version{Tag: fmt.Sprintf("v1.%d", i), Version: fmt.Sprintf("1.%d", i)})
+		}
+		return vs
+	}
+
+	tests := []struct {
+		name string
+		cfg  *Config
+		want int
+	}{
+		{"within version_count", &Config{VersionCount: 3, Versions: versions(3)}, 0},
+		{"exceeds version_count", &Config{VersionCount: 3, Versions: versions(4)}, 1},
+		{"exceeds default", &Config{Versions: versions(DefaultVersionCount + 1)}, 1},
+		{"negative version_count", &Config{VersionCount: -1, Versions: versions(DefaultVersionCount)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConfigWarnings(tt.cfg); len(got) != tt.want {
+				t.Errorf("ConfigWarnings() = %v, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Package configfmt formats package configs (config.yaml and skips.yaml)
+// into a canonical form and lints them for redundant settings.
+package configfmt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// File names of the formattable package files.
+const (
+	ConfigFile = "config.yaml"
+	SkipsFile  = "skips.yaml"
+)
+
+// indent is the indentation of canonical files.
+const indent = 2
+
+// FormatConfig returns a config.yaml in canonical form: fields in the
+// order of config.Config, versions newest first, sorted and deduplicated
+// system_deps, sorted env and config_settings keys (the last of duplicate
+// keys wins), block style and minimal quoting. Comments are preserved.
+func FormatConfig(data []byte) ([]byte, error) {
+	return format(data, reflect.TypeOf(config.Config{}), formatConfigField)
+}
+
+// FormatSkips returns a skips.yaml in canonical form: fields in the order
+// of config.Skip and Python versions sorted oldest first.
+func FormatSkips(data []byte) ([]byte, error) {
+	return format(data, reflect.TypeOf(config.Skips{}), formatSkipsField)
+}
+
+// Format formats a package file by name (config.yaml or skips.yaml).
+func Format(path string, data []byte) ([]byte, error) {
+	switch filepath.Base(path) {
+	case ConfigFile:
+		return FormatConfig(data)
+	case SkipsFile:
+		return FormatSkips(data)
+	default:
+		return nil, fmt.Errorf("%s: not a package config file", path)
+	}
+}
+
+// FormatFile formats a package file in place and reports whether it
+// wasn't canonical. With check, the file is only compared, not rewritten.
+func FormatFile(path string, check bool) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	formatted, err := Format(path, data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+	if !check {
+		if err := os.WriteFile(path, formatted, 0644); err != nil {
+			return true, fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	return true, nil
+}
+
+// fieldFunc applies field-specific formatting to the value of a mapping
+// key, after its own keys are ordered.
+type fieldFunc func(key string, value *yaml.Node)
+
+func format(data []byte, t reflect.Type, field fieldFunc) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	if doc.Kind == 0 {
+		// Empty file
+		return data, nil
+	}
+
+	// A comment at the top of the file stays there when keys move
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode && len(root.Content) > 0 {
+		doc.HeadComment = joinComments(doc.HeadComment, root.Content[0].HeadComment)
+		root.Content[0].HeadComment = ""
+	}
+
+	normalize(&doc, t, field)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// normalize formats a node whose Go type is t.
+func normalize(n *yaml.Node, t reflect.Type, field fieldFunc) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			normalize(c, t, field)
+		}
+		return
+	case yaml.ScalarNode:
+		// Quote only where needed, always with double quotes; keep block
+		// scalars for scripts
+		if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			n.Style = 0
+			if needsQuotes(n) {
+				n.Style = yaml.DoubleQuotedStyle
+			}
+		}
+		return
+	case yaml.AliasNode:
+		return
+	}
+	n.Style &^= yaml.FlowStyle
+
+	switch {
+	case n.Kind == yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Slice {
+			elem = t.Elem()
+		}
+		for _, c := range n.Content {
+			normalize(c, elem, field)
+		}
+	case t != nil && t.Kind() == reflect.Struct:
+		order, types := config.StructFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			normalize(n.Content[i], nil, field)
+			normalize(n.Content[i+1], types[key], field)
+			field(key, n.Content[i+1])
+		}
+		sortPairs(n, order)
+	case t != nil && t.Kind() == reflect.Map:
+		dedupePairs(n)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			normalize(n.Content[i], nil, field)
+			normalize(n.Content[i+1], t.Elem(), field)
+		}
+		sortPairs(n, nil)
+	default:
+		for _, c := range n.Content {
+			normalize(c, nil, field)
+		}
+	}
+}
+
+// needsQuotes reports whether a scalar can't be written plain.
+func needsQuotes(n *yaml.Node) bool {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: n.Tag, Value: n.Value})
+	return err == nil && len(out) > 0 && (out[0] == '\'' || out[0] == '"')
+}
+
+// sortPairs sorts the key/value pairs of a mapping node by their position
+// in order, then by key. Keys not in order go last, in their original
+// order. With a nil order, keys are sorted alphabetically.
+func sortPairs(n *yaml.Node, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, k := range order {
+		rank[k] = i
+	}
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, pair{n.Content[i], n.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if order == nil {
+			return pairs[i].key.Value < pairs[j].key.Value
+		}
+		ri, oki := rank[pairs[i].key.Value]
+		rj, okj := rank[pairs[j].key.Value]
+		switch {
+		case oki && okj:
+			return ri < rj
+		default:
+			return oki && !okj
+		}
+	})
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p.key, p.value)
+	}
+}
+
+// dedupePairs drops all but the last of duplicate keys in a mapping.
+func dedupePairs(n *yaml.Node) {
+	last := make(map[string]int)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		last[n.Content[i].Value] = i
+	}
+	content := n.Content[:0]
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if last[n.Content[i].Value] == i {
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+	}
+	n.Content = content
+}
+
+// formatConfigField applies the config.yaml field rules.
+func formatConfigField(key string, value *yaml.Node) {
+	switch key {
+	case "versions", "retired":
+		sortSequence(value, func(a, b *yaml.Node) bool {
+			return versions.Compare(scalarValue(a, "version"), scalarValue(b, "version")) > 0
+		})
+	case "system_deps":
+		sortSequence(value, func(a, b *yaml.Node) bool { return a.Value < b.Value })
+		dedupeSequence(value)
+	case "patches":
+		// Patches without options are written as their path, as SaveConfig does
+		for i, p := range value.Content {
+			if p.Kind == yaml.MappingNode && len(p.Content) == 2 && p.Content[0].Value == "path" {
+				path := p.Content[1]
+				path.HeadComment = joinComments(p.HeadComment, p.Content[0].HeadComment, path.HeadComment)
+				path.LineComment = joinComments(p.Content[0].LineComment, path.LineComment)
+				value.Content[i] = path
+			}
+		}
+	}
+}
+
+// formatSkipsField applies the skips.yaml field rules.
+func formatSkipsField(key string, value *yaml.Node) {
+	if key == "python" {
+		sortSequence(value, func(a, b *yaml.Node) bool { return versions.Compare(a.Value, b.Value) < 0 })
+		dedupeSequence(value)
+	}
+}
+
+// sortSequence stably sorts the items of a sequence node.
+func sortSequence(n *yaml.Node, less func(a, b *yaml.Node) bool) {
+	if n.Kind != yaml.SequenceNode {
+		return
+	}
+	sort.SliceStable(n.Content, func(i, j int) bool { return less(n.Content[i], n.Content[j]) })
+}
+
+// dedupeSequence drops repeated scalars from a sorted sequence node.
+func dedupeSequence(n *yaml.Node) {
+	if n.Kind != yaml.SequenceNode {
+		return
+	}
+	content := n.Content[:0]
+	for _, c := range n.Content {
+		if len(content) > 0 && c.Kind == yaml.ScalarNode && c.Value == content[len(content)-1].Value {
+			continue
+		}
+		content = append(content, c)
+	}
+	n.Content = content
+}
+
+// scalarValue returns the scalar value of key in a mapping node, or "".
+func scalarValue(n *yaml.Node, key string) string {
+	if v := config.MappingValue(n, key); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+func joinComments(comments ...string) string {
+	var parts []string
+	for _, c := range comments {
+		if c != "" {
+			parts = append(parts, c)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
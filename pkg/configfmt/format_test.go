@@ -0,0 +1,139 @@
+package configfmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatConfig(t *testing.T) {
+	in := `# packages/demo/config.yaml
+env: {CFLAGS: "-O2", A: '1', CFLAGS: "-O3"}
+repo: https://github.com/example/demo
+version_count: 10  # optional
+
+versions:
+  - version: 1.9.0
+    tag: v1.9.0
+  # newest
+  - tag: v2.0.0rc1
+    version: 2.0.0rc1
+  - {tag: v1.10.0, version: "1.10.0"}
+
+system_deps:
+  - zlib-dev
+  - openblas-dev
+  - zlib-dev
+
+patches:
+  - path: patches/fix.patch  # simple
+  - match: ">=1.10"
+    path: patches/compat.patch
+
+script: |
+  python setup.py bdist_wheel
+
+overrides:
+  - system_deps: [openblas-dev=0.3.26]
+    match: '>=2.0'
+`
+	want := `# packages/demo/config.yaml
+
+repo: https://github.com/example/demo
+version_count: 10 # optional
+versions:
+  # newest
+  - tag: v2.0.0rc1
+    version: 2.0.0rc1
+  - tag: v1.10.0
+    version: 1.10.0
+  - tag: v1.9.0
+    version: 1.9.0
+system_deps:
+  - openblas-dev
+  - zlib-dev
+env:
+  A: "1"
+  CFLAGS: -O3
+patches:
+  - patches/fix.patch # simple
+  - path: patches/compat.patch
+    match: ">=1.10"
+script: |
+  python setup.py bdist_wheel
+overrides:
+  - match: ">=2.0"
+    system_deps:
+      - openblas-dev=0.3.26
+`
+
+	got, err := FormatConfig([]byte(in))
+	if err != nil {
+		t.Fatalf("FormatConfig() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatConfig() =\n%s\nwant:\n%s", got, want)
+	}
+
+	// Canonical output is stable
+	again, err := FormatConfig(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(got) {
+		t.Errorf("FormatConfig() not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatSkips(t *testing.T) {
+	in := `skips:
+  - reason: no wheel for 3.9
+    python: ["3.9", "3.12", "3.10", "3.9"]
+    version: "1.0.0"
+`
+	want := `skips:
+  - version: 1.0.0
+    python:
+      - "3.9"
+      - "3.10"
+      - "3.12"
+    reason: no wheel for 3.9
+`
+	got, err := FormatSkips([]byte(in))
+	if err != nil {
+		t.Fatalf("FormatSkips() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatSkips() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	in := "versions: []\nrepo: https://github.com/example/demo\n"
+	if err := os.WriteFile(path, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := FormatFile(path, true)
+	if err != nil || !changed {
+		t.Fatalf("FormatFile(check) = %v, %v; want changed", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != in {
+		t.Errorf("check mode rewrote the file:\n%s", data)
+	}
+
+	if changed, err := FormatFile(path, false); err != nil || !changed {
+		t.Fatalf("FormatFile() = %v, %v; want changed", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "repo: https://github.com/example/demo\nversions: []\n" {
+		t.Errorf("formatted file:\n%s", data)
+	}
+	if changed, err := FormatFile(path, true); err != nil || changed {
+		t.Errorf("FormatFile(check) on canonical file = %v, %v", changed, err)
+	}
+
+	if _, err := FormatFile(filepath.Join(t.TempDir(), "other.yaml"), true); err == nil {
+		t.Error("FormatFile() on a missing file succeeded")
+	}
+}
@@ -0,0 +1,201 @@
+package configfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/builder/pep517"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// Finding is a lint finding in a config.
+type Finding struct {
+	// Field is the config field the finding is about (e.g., "override[1].env.CFLAGS").
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Field + ": " + f.Message
+}
+
+// Lint returns the config's validation warnings (config.ConfigWarnings)
+// and findings for settings that have no effect: empty overrides,
+// overrides that match no version or only repeat the base config, env
+// values repeated from the base, unknown build backends, and unpinned
+// system_deps already installed in the base image (baseImage lists its
+// APK packages, e.g. from DockerfilePackages).
+func Lint(cfg *config.Config, baseImage []string) []Finding {
+	var findings []Finding
+	add := func(field, format string, args ...interface{}) {
+		findings = append(findings, Finding{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, w := range config.ConfigWarnings(cfg) {
+		field, message, _ := strings.Cut(w, ": ")
+		add(field, "%s", message)
+	}
+
+	if cfg.Backend != "" && !pep517.IsBackend(cfg.Backend) {
+		add("backend", "unknown build backend %q has no defaults", cfg.Backend)
+	}
+
+	inImage := make(map[string]bool, len(baseImage))
+	for _, p := range baseImage {
+		inImage[p] = true
+	}
+	for _, dep := range cfg.SystemDeps {
+		if inImage[dep] {
+			add("system_deps", "%s is already in the base image", dep)
+		}
+	}
+
+	for i, o := range cfg.Overrides {
+		field := fmt.Sprintf("override[%d]", i)
+		if overrideEmpty(o) {
+			add(field, "empty override (sets nothing besides match)")
+			continue
+		}
+		if !matchesAnyVersion(cfg, o.Match) {
+			add(field, "%q matches no version", o.Match)
+			continue
+		}
+		if overrideRedundant(cfg, o) {
+			add(field, "redundant override (only repeats the base config)")
+			continue
+		}
+
+		base := make(map[string]bool, len(cfg.SystemDeps))
+		for _, dep := range cfg.SystemDeps {
+			base[dep] = true
+		}
+		for _, dep := range o.SystemDeps {
+			switch {
+			case base[dep]:
+				add(field+".system_deps", "%s is already in the base system_deps", dep)
+			case inImage[dep]:
+				add(field+".system_deps", "%s is already in the base image", dep)
+			}
+		}
+		for _, k := range sortedKeys(o.Env) {
+			if v, ok := cfg.Env[k]; ok && v == o.Env[k] {
+				add(field+".env."+k, "same value as the base env")
+			}
+		}
+	}
+	return findings
+}
+
+// overrideEmpty reports whether an override sets nothing but its match.
+func overrideEmpty(o config.Override) bool {
+	return len(o.SystemDeps) == 0 && len(o.Env) == 0 && len(o.Patches) == 0 &&
+		o.Script == "" && len(o.ConfigSettings) == 0 && len(o.BuildRequires) == 0 &&
+		o.BuildConstraints == "" && o.Resources.IsZero()
+}
+
+// overrideRedundant reports whether everything an override sets is
+// already set the same way in the base config.
+func overrideRedundant(cfg *config.Config, o config.Override) bool {
+	return len(o.Patches) == 0 &&
+		subset(o.SystemDeps, cfg.SystemDeps) &&
+		subset(o.BuildRequires, cfg.BuildRequires) &&
+		submap(o.Env, cfg.Env) &&
+		submap(o.ConfigSettings, cfg.ConfigSettings) &&
+		(o.Script == "" || o.Script == cfg.Script) &&
+		(o.BuildConstraints == "" || o.BuildConstraints == cfg.BuildConstraints) &&
+		reflect.DeepEqual(cfg.Resources.Merge(o.Resources), cfg.Resources)
+}
+
+// matchesAnyVersion reports whether a specifier matches a configured
+// version. Retired versions count, since their overrides document how
+// they were built.
+func matchesAnyVersion(cfg *config.Config, spec string) bool {
+	for _, vs := range [][]config.Version{cfg.Versions, cfg.Retired} {
+		for _, v := range vs {
+			if ok, err := config.MatchesVersion(v.Version, spec); err != nil || ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func subset(items, of []string) bool {
+	set := make(map[string]bool, len(of))
+	for _, s := range of {
+		set[s] = true
+	}
+	for _, s := range items {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
+
+func submap(m, of map[string]string) bool {
+	for k, v := range m {
+		if w, ok := of[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DockerfilePackages returns the APK packages a Dockerfile installs with
+// "apk add".
+func DockerfilePackages(data []byte) []string {
+	var pkgs []string
+	var cmd strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if cont, ok := strings.CutSuffix(line, "\\"); ok {
+			cmd.WriteString(cont + " ")
+			continue
+		}
+		cmd.WriteString(line)
+		pkgs = append(pkgs, apkAddPackages(cmd.String())...)
+		cmd.Reset()
+	}
+	return pkgs
+}
+
+// apkAddPackages returns the packages of the "apk add" commands in a
+// Dockerfile instruction.
+func apkAddPackages(instruction string) []string {
+	fields := strings.Fields(instruction)
+	var pkgs []string
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] != "apk" || fields[i+1] != "add" {
+			continue
+		}
+		for _, f := range fields[i+2:] {
+			if f == "&&" || f == ";" || f == "||" {
+				break
+			}
+			if !strings.HasPrefix(f, "-") {
+				pkgs = append(pkgs, f)
+			}
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,79 @@
+package configfmt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestLint(t *testing.T) {
+	cfg := &config.Config{
+		Repo: "https://github.com/example/demo",
+		Versions: []config.Version{
+			{Tag: "v2.0.0", Version: "2.0.0"},
+			{Tag: "v1.0.0", Version: "1.0.0"},
+		},
+		SystemDeps: []string{"openblas-dev", "zlib-dev", "openssl-dev=3.1.0-r0"},
+		Env:        map[string]string{"CFLAGS": "-O2"},
+		Overrides: []config.Override{
+			{Match: ">=2.0"},
+			{Match: "<0.5", Env: map[string]string{"LEGACY": "1"}},
+			{Match: ">=1.0", SystemDeps: []string{"openblas-dev"}, Env: map[string]string{"CFLAGS": "-O2"}},
+			{Match: "<2.0", SystemDeps: []string{"openblas-dev", "libffi-dev", "gfortran"}, Env: map[string]string{"CFLAGS": "-O2", "LEGACY": "1"}},
+		},
+	}
+	baseImage := []string{"build-base", "zlib-dev", "libffi-dev", "openssl-dev"}
+
+	var got []string
+	for _, f := range Lint(cfg, baseImage) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"system_deps: zlib-dev is already in the base image",
+		"override[0]: empty override (sets nothing besides match)",
+		`override[1]: "<0.5" matches no version`,
+		"override[2]: redundant override (only repeats the base config)",
+		"override[3].system_deps: openblas-dev is already in the base system_deps",
+		"override[3].system_deps: libffi-dev is already in the base image",
+		"override[3].env.CFLAGS: same value as the base env",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() =\n%q\nwant:\n%q", got, want)
+	}
+
+	if findings := Lint(&config.Config{Repo: cfg.Repo, Versions: cfg.Versions}, baseImage); len(findings) != 0 {
+		t.Errorf("Lint() on a clean config = %v", findings)
+	}
+
+	// Validation warnings are findings too
+	findings := Lint(&config.Config{Repo: cfg.Repo, Versions: cfg.Versions, VersionCount: 1}, baseImage)
+	if len(findings) != 1 || findings[0].Field != "versions" {
+		t.Errorf("Lint() with too many versions = %v, want a versions finding", findings)
+	}
+
+	if findings := Lint(&config.Config{Repo: cfg.Repo, Versions: cfg.Versions, Backend: "maturin"}, baseImage); len(findings) != 0 {
+		t.Errorf("Lint() with a known backend = %v", findings)
+	}
+	findings = Lint(&config.Config{Repo: cfg.Repo, Versions: cfg.Versions, Backend: "mesonpy"}, baseImage)
+	if len(findings) != 1 || findings[0].Field != "backend" {
+		t.Errorf("Lint() with an unknown backend = %v, want a backend finding", findings)
+	}
+}
+
+func TestDockerfilePackages(t *testing.T) {
+	dockerfile := `FROM cgr.dev/chainguard/wolfi-base:latest
+
+# Base build tools
+RUN apk add --no-cache \
+    build-base \
+    git
+
+RUN apk update && apk add --no-cache zlib-dev && rm -rf /var/cache/apk
+RUN npm install -g something
+`
+	want := []string{"build-base", "git", "zlib-dev"}
+	if got := DockerfilePackages([]byte(dockerfile)); !reflect.DeepEqual(got, want) {
+		t.Errorf("DockerfilePackages() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+// Package gc recovers packages from crashed or abandoned agents: it deletes
+// claims that haven't been renewed within their TTL and makes sure packages
+// that were never built are back in queue.txt.
+package gc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/claims"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// DefaultTTL is how long a claim may go without renewal before it expires.
+const DefaultTTL = 4 * time.Hour
+
+// DefaultTTLs are the TTLs for each claim type.
+var DefaultTTLs = map[string]time.Duration{
+	config.ClaimTypeBuild:   DefaultTTL,
+	config.ClaimTypeVersion: DefaultTTL,
+	config.ClaimTypeFixer:   DefaultTTL,
+}
+
+// Expired is a deleted (or, in a dry run, deletable) claim.
+type Expired struct {
+	Package string
+	Claim   config.Claim
+
+	// Age is how long ago the claim was last renewed.
+	Age time.Duration
+}
+
+// Report summarizes a collection run.
+type Report struct {
+	// DryRun is set if nothing was changed.
+	DryRun bool
+
+	// Active is the number of claims that were kept.
+	Active int
+
+	// Expired are the claims past their TTL, sorted by package.
+	Expired []Expired
+
+	// Requeued are the expired packages added back to queue.txt.
+	Requeued []string
+
+	// Built are the expired packages that already have a config on main.
+	Built []string
+}
+
+// Collector garbage collects stale claims.
+type Collector struct {
+	// Claims manages the claims branch.
+	Claims *claims.Manager
+
+	// Queue updates queue.txt on main. If nil, packages are not requeued.
+	Queue *Requeuer
+
+	// TTLs overrides the TTL for claim types (default: DefaultTTLs).
+	// Claim types without a TTL use DefaultTTL.
+	TTLs map[string]time.Duration
+
+	// DryRun reports what would be collected without changing anything.
+	DryRun bool
+
+	// Now returns the current time (default: time.Now).
+	Now func() time.Time
+}
+
+// TTL returns the TTL for a claim type.
+func (c *Collector) TTL(claimType string) time.Duration {
+	if ttl, ok := c.TTLs[claimType]; ok {
+		return ttl
+	}
+	if ttl, ok := DefaultTTLs[claimType]; ok {
+		return ttl
+	}
+	return DefaultTTL
+}
+
+// Run requeues the packages of expired claims if they haven't been built,
+// then deletes the claims in a single commit. Claims are only deleted once
+// their packages are requeued, so a run that fails to requeue leaves them
+// for the next run.
+func (c *Collector) Run(ctx context.Context) (*Report, error) {
+	now := time.Now()
+	if c.Now != nil {
+		now = c.Now()
+	}
+	report := &Report{DryRun: c.DryRun}
+
+	expired := func(pkg string, claim *config.Claim) bool {
+		return now.Sub(claim.LastSeen()) > c.TTL(claim.Type)
+	}
+
+	all, err := c.Claims.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing claims: %w", err)
+	}
+	var pkgs []string
+	for pkg, claim := range all {
+		if expired(pkg, claim) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+
+	if c.Queue != nil && len(pkgs) > 0 {
+		requeued, built, err := c.Queue.Requeue(ctx, pkgs, c.DryRun)
+		report.Requeued, report.Built = requeued, built
+		if err != nil {
+			report.Active = len(all)
+			return report, fmt.Errorf("requeuing packages: %w", err)
+		}
+	}
+
+	deleted := make(map[string]*config.Claim, len(pkgs))
+	if c.DryRun {
+		for _, pkg := range pkgs {
+			deleted[pkg] = all[pkg]
+		}
+		report.Active = len(all) - len(deleted)
+	} else {
+		// Claims that expired since the list are left for the next run,
+		// since their packages weren't requeued
+		requeued := make(map[string]bool, len(pkgs))
+		for _, pkg := range pkgs {
+			requeued[pkg] = true
+		}
+		deleted, err = c.Claims.Expire(func(pkg string, claim *config.Claim) bool {
+			return requeued[pkg] && expired(pkg, claim)
+		})
+		if err != nil {
+			return report, fmt.Errorf("expiring claims: %w", err)
+		}
+		all, err := c.Claims.List()
+		if err != nil {
+			return report, fmt.Errorf("listing claims: %w", err)
+		}
+		report.Active = len(all)
+	}
+
+	for pkg, claim := range deleted {
+		report.Expired = append(report.Expired, Expired{Package: pkg, Claim: *claim, Age: now.Sub(claim.LastSeen())})
+	}
+	sort.Slice(report.Expired, func(i, j int) bool {
+		return report.Expired[i].Package < report.Expired[j].Package
+	})
+	return report, nil
+}
+
+// Write prints the report.
+func (r *Report) Write(w io.Writer) {
+	verb := "Deleted"
+	if r.DryRun {
+		verb = "Would delete"
+	}
+	fmt.Fprintf(w, "%s %d expired claims (%d active)\n", verb, len(r.Expired), r.Active)
+	for _, e := range r.Expired {
+		fmt.Fprintf(w, "  %-30s %-8s %-24s last seen %s ago (token %d)\n",
+			e.Package, e.Claim.Type, e.Claim.Agent, e.Age.Round(time.Minute), e.Claim.Token)
+	}
+
+	verb = "Requeued"
+	if r.DryRun {
+		verb = "Would requeue"
+	}
+	if len(r.Requeued) > 0 {
+		fmt.Fprintf(w, "%s %d packages\n", verb, len(r.Requeued))
+		for _, pkg := range r.Requeued {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+	if len(r.Built) > 0 {
+		fmt.Fprintf(w, "Already built (not requeued): %d packages\n", len(r.Built))
+		for _, pkg := range r.Built {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+}
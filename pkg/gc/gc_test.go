@@ -0,0 +1,296 @@
+package gc
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/claims"
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+var now = time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newRemote creates a bare repository whose main branch has a built
+// package (six) and a queue with flask in it.
+func newRemote(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	git(t, root, "init", "--bare", "-q", "-b", "main", remote)
+
+	work := filepath.Join(root, "work")
+	git(t, root, "init", "-q", "-b", "main", work)
+	if err := os.MkdirAll(filepath.Join(work, "packages", "six"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"packages/six/config.yaml": "repo: https://github.com/benjaminp/six\n",
+		"queue.txt":                "# packages left to build\nflask\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git(t, work, "add", ".")
+	git(t, work, "commit", "-q", "-m", "initial")
+	git(t, work, "push", "-q", remote, "main")
+	return remote
+}
+
+// claim acquires a claim last renewed age before now.
+func claim(t *testing.T, remote, pkg, claimType string, age time.Duration) {
+	t.Helper()
+	m := claims.New(remote, filepath.Join(t.TempDir(), "claims"), "agent-"+pkg)
+	m.Now = func() time.Time { return now.Add(-age) }
+	if _, err := m.Acquire(pkg, claimType); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mainQueue(t *testing.T, remote string) string {
+	t.Helper()
+	return git(t, remote, "show", "main:queue.txt")
+}
+
+func newCollector(t *testing.T, remote string, dryRun bool) *Collector {
+	return &Collector{
+		Claims: claims.New(remote, filepath.Join(t.TempDir(), "gc"), "gc"),
+		Queue:  &Requeuer{Remote: remote},
+		DryRun: dryRun,
+		Now:    func() time.Time { return now },
+	}
+}
+
+func setupClaims(t *testing.T, remote string) {
+	claim(t, remote, "numpy", config.ClaimTypeBuild, 5*time.Hour)  // expired, requeued
+	claim(t, remote, "requests", config.ClaimTypeBuild, time.Hour) // active
+	claim(t, remote, "six", config.ClaimTypeVersion, 5*time.Hour)  // expired, already built
+	claim(t, remote, "flask", config.ClaimTypeFixer, 5*time.Hour)  // expired, already queued
+	claim(t, remote, "attrs", config.ClaimTypeFixer, 3*time.Hour)  // active
+}
+
+func TestRun(t *testing.T) {
+	remote := newRemote(t)
+	setupClaims(t, remote)
+
+	report, err := newCollector(t, remote, false).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var expired []string
+	for _, e := range report.Expired {
+		expired = append(expired, e.Package)
+	}
+	if want := []string{"flask", "numpy", "six"}; !reflect.DeepEqual(expired, want) {
+		t.Errorf("Expired = %v, want %v", expired, want)
+	}
+	if report.Active != 2 {
+		t.Errorf("Active = %d, want 2", report.Active)
+	}
+	if want := []string{"numpy"}; !reflect.DeepEqual(report.Requeued, want) {
+		t.Errorf("Requeued = %v, want %v", report.Requeued, want)
+	}
+	if want := []string{"six"}; !reflect.DeepEqual(report.Built, want) {
+		t.Errorf("Built = %v, want %v", report.Built, want)
+	}
+
+	left, err := claims.New(remote, filepath.Join(t.TempDir(), "check"), "check").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || left["requests"] == nil || left["attrs"] == nil {
+		t.Errorf("claims left = %v, want requests and attrs", left)
+	}
+
+	if got, want := mainQueue(t, remote), "# packages left to build\nflask\nnumpy"; got != want {
+		t.Errorf("queue.txt = %q, want %q", got, want)
+	}
+
+	var out bytes.Buffer
+	report.Write(&out)
+	for _, want := range []string{"Deleted 3 expired claims (2 active)", "numpy", "Requeued 1 packages", "Already built"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// A second run finds nothing to do
+	report, err = newCollector(t, remote, false).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Expired) != 0 || report.Active != 2 {
+		t.Errorf("second run = %+v, want no expired claims", report)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	remote := newRemote(t)
+	setupClaims(t, remote)
+	claimsHead := git(t, remote, "rev-parse", "claims")
+	mainHead := git(t, remote, "rev-parse", "main")
+
+	report, err := newCollector(t, remote, true).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Expired) != 3 || report.Active != 2 {
+		t.Errorf("report = %+v, want 3 expired and 2 active", report)
+	}
+	if want := []string{"numpy"}; !reflect.DeepEqual(report.Requeued, want) {
+		t.Errorf("Requeued = %v, want %v", report.Requeued, want)
+	}
+
+	if got := git(t, remote, "rev-parse", "claims"); got != claimsHead {
+		t.Error("dry run changed the claims branch")
+	}
+	if got := git(t, remote, "rev-parse", "main"); got != mainHead {
+		t.Error("dry run changed main")
+	}
+
+	var out bytes.Buffer
+	report.Write(&out)
+	if !strings.Contains(out.String(), "Would delete 3 expired claims") || !strings.Contains(out.String(), "Would requeue") {
+		t.Errorf("dry run report:\n%s", out.String())
+	}
+}
+
+func TestRunRequeueFails(t *testing.T) {
+	remote := newRemote(t)
+	setupClaims(t, remote)
+
+	c := newCollector(t, remote, false)
+	c.Queue = &Requeuer{Remote: filepath.Join(t.TempDir(), "missing.git")}
+	if _, err := c.Run(context.Background()); err == nil {
+		t.Fatal("Run() should fail when the queue can't be updated")
+	}
+
+	left, err := claims.New(remote, filepath.Join(t.TempDir(), "check"), "check").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 5 {
+		t.Errorf("claims left = %v, want all 5 kept", left)
+	}
+
+	// A rerun recovers the packages
+	report, err := newCollector(t, remote, false).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Expired) != 3 {
+		t.Errorf("Expired = %+v, want 3", report.Expired)
+	}
+	if want := []string{"numpy"}; !reflect.DeepEqual(report.Requeued, want) {
+		t.Errorf("Requeued = %v, want %v", report.Requeued, want)
+	}
+	if got, want := mainQueue(t, remote), "# packages left to build\nflask\nnumpy"; got != want {
+		t.Errorf("queue.txt = %q, want %q", got, want)
+	}
+}
+
+func TestRequeuePR(t *testing.T) {
+	remote := newRemote(t)
+	mainHead := git(t, remote, "rev-parse", "main")
+
+	var prBranch, prTitle string
+	r := &Requeuer{
+		Remote: remote,
+		PR:     true,
+		CreatePR: func(ctx context.Context, dir, branch, title, body string) error {
+			prBranch, prTitle = branch, title
+			return nil
+		},
+	}
+	requeued, built, err := r.Requeue(context.Background(), []string{"numpy", "six", "flask"}, false)
+	if err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	if !reflect.DeepEqual(requeued, []string{"numpy"}) || !reflect.DeepEqual(built, []string{"six"}) {
+		t.Errorf("Requeue() = %v, %v, want [numpy], [six]", requeued, built)
+	}
+
+	if got := git(t, remote, "rev-parse", "main"); got != mainHead {
+		t.Error("PR mode pushed to main")
+	}
+	if !strings.HasPrefix(prBranch, "gc/requeue-") || prTitle == "" {
+		t.Errorf("CreatePR called with branch %q, title %q", prBranch, prTitle)
+	}
+	if got := git(t, remote, "show", prBranch+":queue.txt"); !strings.HasSuffix(got, "flask\nnumpy") {
+		t.Errorf("queue.txt on %s = %q", prBranch, got)
+	}
+}
+
+func TestRequeuePushRejected(t *testing.T) {
+	remote := newRemote(t)
+	r := &Requeuer{Remote: remote}
+
+	// Another commit lands on main between the clone and the push
+	work := filepath.Join(t.TempDir(), "other")
+	git(t, "", "clone", "-q", remote, work)
+	if err := os.WriteFile(filepath.Join(work, "README.md"), []byte("hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", ".")
+	git(t, work, "commit", "-q", "-m", "concurrent")
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	git(t, "", "clone", "-q", "--depth", "1", remote, dir)
+	if _, _, err := r.apply(dir, []string{"numpy"}); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "commit", "-q", "-m", "requeue")
+	git(t, work, "push", "-q", "origin", "main")
+
+	if err := r.push(context.Background(), dir); err != errRejected {
+		t.Errorf("push() after main moved = %v, want errRejected", err)
+	}
+
+	// Requeue starts from the new head and keeps the concurrent commit
+	requeued, _, err := r.Requeue(context.Background(), []string{"numpy"}, false)
+	if err != nil || len(requeued) != 1 {
+		t.Fatalf("Requeue() = %v, %v", requeued, err)
+	}
+	if got := git(t, remote, "show", "main:README.md"); got != "hi" {
+		t.Errorf("concurrent commit lost: README.md = %q", got)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := &Collector{TTLs: map[string]time.Duration{config.ClaimTypeBuild: time.Hour}}
+	tests := []struct {
+		claimType string
+		want      time.Duration
+	}{
+		{config.ClaimTypeBuild, time.Hour},
+		{config.ClaimTypeVersion, DefaultTTLs[config.ClaimTypeVersion]},
+		{"", DefaultTTL},
+		{"unknown", DefaultTTL},
+	}
+	for _, tt := range tests {
+		if got := c.TTL(tt.claimType); got != tt.want {
+			t.Errorf("TTL(%q) = %v, want %v", tt.claimType, got, tt.want)
+		}
+	}
+}
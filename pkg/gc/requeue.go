@@ -0,0 +1,197 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/queue"
+)
+
+// DefaultMainBranch is the branch holding packages/ and queue.txt.
+const DefaultMainBranch = "main"
+
+// maxPushAttempts bounds retries when main moves during a direct push.
+const maxPushAttempts = 5
+
+// errRejected is returned when a push loses a race with another update.
+var errRejected = errors.New("push rejected: branch moved")
+
+// PRFunc opens a pull request from branch in the clone at dir.
+type PRFunc func(ctx context.Context, dir, branch, title, body string) error
+
+// Requeuer adds packages back to queue.txt on the main branch, either by
+// committing directly or by opening a pull request.
+type Requeuer struct {
+	// Remote is the repository URL.
+	Remote string
+
+	// Branch is the main branch (default: DefaultMainBranch).
+	Branch string
+
+	// Author is the commit author name (default: "superwheelie-gc").
+	Author string
+
+	// PR pushes a new branch and opens a pull request instead of pushing
+	// to Branch directly.
+	PR bool
+
+	// CreatePR opens the pull request (default: gh pr create).
+	CreatePR PRFunc
+}
+
+// Requeue ensures that each package without a config on main is in
+// queue.txt. It returns the packages that were added and the packages
+// that are already built. With dryRun, nothing is pushed.
+func (r *Requeuer) Requeue(ctx context.Context, pkgs []string, dryRun bool) (requeued, built []string, err error) {
+	dir, err := os.MkdirTemp("", "superwheelie-gc-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := r.git(ctx, "", "clone", "-q", "--depth", "1", "--single-branch", "--branch", r.branch(), r.Remote, dir); err != nil {
+		return nil, nil, err
+	}
+
+	for attempt := 0; attempt < maxPushAttempts; attempt++ {
+		requeued, built, err = r.apply(dir, pkgs)
+		if err != nil || len(requeued) == 0 || dryRun {
+			return requeued, built, err
+		}
+
+		title := fmt.Sprintf("Requeue %d packages with expired claims", len(requeued))
+		body := "Expired claims were garbage collected for:\n\n- " + strings.Join(requeued, "\n- ") + "\n"
+		if _, err := r.git(ctx, dir, "commit", "-q", "-m", title, "-m", body, "--", queueFile); err != nil {
+			return nil, built, err
+		}
+
+		if r.PR {
+			branch := fmt.Sprintf("gc/requeue-%d", time.Now().Unix())
+			if _, err := r.git(ctx, dir, "push", "-q", "origin", "HEAD:refs/heads/"+branch); err != nil {
+				return nil, built, err
+			}
+			createPR := r.CreatePR
+			if createPR == nil {
+				createPR = ghCreatePR(r.branch())
+			}
+			if err := createPR(ctx, dir, branch, title, body); err != nil {
+				return nil, built, fmt.Errorf("creating pull request: %w", err)
+			}
+			return requeued, built, nil
+		}
+
+		err = r.push(ctx, dir)
+		if err == nil {
+			return requeued, built, nil
+		}
+		if !errors.Is(err, errRejected) {
+			return nil, built, err
+		}
+		// main moved: start over from the new head
+		if _, err := r.git(ctx, dir, "fetch", "-q", "--depth", "1", "origin", r.branch()); err != nil {
+			return nil, built, err
+		}
+		if _, err := r.git(ctx, dir, "reset", "-q", "--hard", "FETCH_HEAD"); err != nil {
+			return nil, built, err
+		}
+	}
+	return nil, built, fmt.Errorf("pushing to %s: too many concurrent updates", r.branch())
+}
+
+// queueFile is the queue path relative to the repository root.
+const queueFile = "queue.txt"
+
+// apply adds unbuilt packages to queue.txt in the clone at dir.
+func (r *Requeuer) apply(dir string, pkgs []string) (requeued, built []string, err error) {
+	names, err := queue.PackageNames(filepath.Join(dir, "packages"))
+	if err != nil {
+		return nil, nil, err
+	}
+	queuePath := filepath.Join(dir, queueFile)
+	q, err := queue.Load(queuePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if _, ok := names[pkg]; ok {
+			built = append(built, pkg)
+			continue
+		}
+		if q.Add(pkg, queue.DefaultPriority) {
+			requeued = append(requeued, pkg)
+		}
+	}
+	if len(requeued) == 0 {
+		return nil, built, nil
+	}
+	if err := q.Save(queuePath); err != nil {
+		return nil, nil, err
+	}
+	if _, err := r.git(context.Background(), dir, "add", queueFile); err != nil {
+		return nil, nil, err
+	}
+	return requeued, built, nil
+}
+
+// push fast-forwards the main branch. It returns errRejected if the branch
+// moved since it was fetched.
+func (r *Requeuer) push(ctx context.Context, dir string) error {
+	out, err := r.git(ctx, dir, "push", "--porcelain", "origin", "HEAD:refs/heads/"+r.branch())
+	if err == nil {
+		return nil
+	}
+	for _, marker := range []string{"[rejected]", "non-fast-forward", "fetch first", "cannot lock ref"} {
+		if strings.Contains(out, marker) {
+			return errRejected
+		}
+	}
+	return err
+}
+
+// git runs a git command in dir and returns its combined output.
+func (r *Requeuer) git(ctx context.Context, dir string, args ...string) (string, error) {
+	author := r.Author
+	if author == "" {
+		author = "superwheelie-gc"
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+author,
+		"GIT_AUTHOR_EMAIL="+author+"@superwheelie",
+		"GIT_COMMITTER_NAME="+author,
+		"GIT_COMMITTER_EMAIL="+author+"@superwheelie",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s: %w\n%s", args[0], err, out)
+	}
+	return string(out), nil
+}
+
+func (r *Requeuer) branch() string {
+	if r.Branch != "" {
+		return r.Branch
+	}
+	return DefaultMainBranch
+}
+
+// ghCreatePR returns a PRFunc that opens a pull request against base with
+// the GitHub CLI.
+func ghCreatePR(base string) PRFunc {
+	return func(ctx context.Context, dir, branch, title, body string) error {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "create", "--base", base, "--head", branch, "--title", title, "--body", body)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("gh pr create: %w\n%s", err, out)
+		}
+		return nil
+	}
+}
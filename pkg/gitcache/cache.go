@@ -0,0 +1,297 @@
+// Package gitcache provides a shared cache of bare mirror repositories so that
+// multiple builds of the same package reuse a single clone.
+package gitcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long to wait for another agent to release a mirror.
+const DefaultLockTimeout = 30 * time.Minute
+
+// lastUsedFile is touched inside a mirror each time it is used.
+const lastUsedFile = "superwheelie-last-used"
+
+// Cache manages bare mirror repositories keyed by repository URL.
+type Cache struct {
+	// Dir is the directory containing the mirror repositories.
+	Dir string
+
+	// LockTimeout is how long to wait to acquire a mirror lock (default: DefaultLockTimeout).
+	LockTimeout time.Duration
+}
+
+// Entry describes a mirror in the cache.
+type Entry struct {
+	// Path is the path to the bare mirror repository.
+	Path string
+
+	// Size is the on-disk size of the mirror in bytes.
+	Size int64
+
+	// LastUsed is when the mirror was last fetched or checked out.
+	LastUsed time.Time
+}
+
+// New creates a new Cache rooted at dir.
+func New(dir string) *Cache {
+	return &Cache{
+		Dir:         dir,
+		LockTimeout: DefaultLockTimeout,
+	}
+}
+
+// MirrorPath returns the path of the mirror for a repository URL.
+func (c *Cache) MirrorPath(url string) string {
+	return filepath.Join(c.Dir, mirrorName(url))
+}
+
+// Mirror ensures an up-to-date mirror of url exists and returns its path.
+// A missing mirror is cloned; an existing one is fetched.
+func (c *Cache) Mirror(url string) (string, error) {
+	path := c.MirrorPath(url)
+
+	unlock, err := c.lock(path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if err := c.update(url, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// AddWorktree creates a detached worktree of the mirror for url at dest,
+// checked out at ref. The mirror is created or refreshed first.
+// dest must not exist or be an empty directory.
+func (c *Cache) AddWorktree(url, dest, ref string) error {
+	path := c.MirrorPath(url)
+
+	unlock, err := c.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := c.update(url, path); err != nil {
+		return err
+	}
+
+	// Drop registrations for worktrees whose directories were deleted.
+	if output, err := git(path, "worktree", "prune"); err != nil {
+		return fmt.Errorf("pruning worktrees: %w\n%s", err, output)
+	}
+
+	if output, err := git(path, "worktree", "add", "--detach", "--force", dest, ref); err != nil {
+		return fmt.Errorf("adding worktree for %s at %s: %w\n%s", url, ref, err, output)
+	}
+	return nil
+}
+
+// RemoveWorktree removes a worktree previously created with AddWorktree.
+func (c *Cache) RemoveWorktree(url, dest string) error {
+	path := c.MirrorPath(url)
+
+	unlock, err := c.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if output, err := git(path, "worktree", "remove", "--force", dest); err != nil {
+		return fmt.Errorf("removing worktree %s: %w\n%s", dest, err, output)
+	}
+	return nil
+}
+
+// Entries returns the mirrors in the cache.
+func (c *Cache) Entries() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading cache directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, de := range dirEntries {
+		if !de.IsDir() || !strings.HasSuffix(de.Name(), ".git") {
+			continue
+		}
+		path := filepath.Join(c.Dir, de.Name())
+		size, err := dirSize(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Path:     path,
+			Size:     size,
+			LastUsed: lastUsed(path),
+		})
+	}
+	return entries, nil
+}
+
+// Evict removes mirrors not used within maxAge, then removes the least
+// recently used mirrors until the cache is no larger than maxSize bytes.
+// A zero maxAge or maxSize disables that limit. Mirrors that are locked
+// by another agent or still have worktrees checked out are left alone.
+// Returns the paths that were removed.
+func (c *Cache) Evict(maxSize int64, maxAge time.Duration) ([]string, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []string
+	now := time.Now()
+	for _, e := range entries {
+		expired := maxAge > 0 && now.Sub(e.LastUsed) > maxAge
+		oversize := maxSize > 0 && total > maxSize
+		if !expired && !oversize {
+			continue
+		}
+
+		unlock, err := tryLock(e.Path)
+		if err != nil {
+			// In use elsewhere
+			continue
+		}
+		live, err := liveWorktrees(e.Path)
+		if err != nil || live > 0 {
+			// Builds still check out from this mirror
+			unlock()
+			continue
+		}
+		err = os.RemoveAll(e.Path)
+		unlock()
+		if err != nil {
+			return removed, fmt.Errorf("removing mirror %s: %w", e.Path, err)
+		}
+
+		total -= e.Size
+		removed = append(removed, e.Path)
+	}
+
+	return removed, nil
+}
+
+// update clones or fetches the mirror at path. The caller must hold the lock.
+func (c *Cache) update(url, path string) error {
+	if _, err := os.Stat(filepath.Join(path, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			return fmt.Errorf("creating cache directory: %w", err)
+		}
+		// Remove any partial clone left by a crashed agent
+		os.RemoveAll(path)
+		if output, err := git("", "clone", "--mirror", url, path); err != nil {
+			return fmt.Errorf("cloning mirror of %s: %w\n%s", url, err, output)
+		}
+	} else {
+		if output, err := git(path, "fetch", "--prune", "--tags", "origin"); err != nil {
+			return fmt.Errorf("fetching mirror of %s: %w\n%s", url, err, output)
+		}
+	}
+
+	touch(path)
+	return nil
+}
+
+// liveWorktrees prunes the registrations of deleted worktrees of the mirror
+// at path and returns how many worktrees remain. The caller must hold the
+// lock.
+func liveWorktrees(path string) (int, error) {
+	if output, err := git(path, "worktree", "prune"); err != nil {
+		return 0, fmt.Errorf("pruning worktrees: %w\n%s", err, output)
+	}
+	output, err := git(path, "worktree", "list", "--porcelain")
+	if err != nil {
+		return 0, fmt.Errorf("listing worktrees: %w\n%s", err, output)
+	}
+
+	// The first entry is the mirror itself
+	live := -1
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "worktree ") {
+			live++
+		}
+	}
+	return max(live, 0), nil
+}
+
+// mirrorName returns a stable directory name for a repository URL.
+func mirrorName(url string) string {
+	normalized := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	sum := sha256.Sum256([]byte(normalized))
+
+	base := filepath.Base(normalized)
+	if base == "." || base == "/" {
+		base = "repo"
+	}
+	return fmt.Sprintf("%s-%s.git", base, hex.EncodeToString(sum[:])[:16])
+}
+
+// git runs a git command in dir and returns its combined output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
+
+// touch records that the mirror at path was just used.
+func touch(path string) {
+	marker := filepath.Join(path, lastUsedFile)
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); err != nil {
+		os.WriteFile(marker, nil, 0644)
+	}
+}
+
+// lastUsed returns when the mirror at path was last used.
+func lastUsed(path string) time.Time {
+	for _, p := range []string{filepath.Join(path, lastUsedFile), path} {
+		if info, err := os.Stat(p); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Time{}
+}
+
+// dirSize returns the total size of regular files under path.
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("measuring %s: %w", path, err)
+	}
+	return size, nil
+}
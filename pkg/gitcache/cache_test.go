@@ -0,0 +1,268 @@
+package gitcache
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// initRepo creates a local repository with one commit per tag.
+func initRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "upstream")
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run("init", "-q")
+	for _, tag := range tags {
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(tag), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "VERSION")
+		run("commit", "-q", "-m", tag)
+		run("tag", tag)
+	}
+	return "file://" + dir
+}
+
+func TestMirrorName(t *testing.T) {
+	a := mirrorName("https://github.com/numpy/numpy")
+	b := mirrorName("https://github.com/numpy/numpy.git")
+	c := mirrorName("https://github.com/numpy/numpy/")
+	if a != b || a != c {
+		t.Errorf("mirrorName not normalized: %q, %q, %q", a, b, c)
+	}
+	if !strings.HasPrefix(a, "numpy-") || !strings.HasSuffix(a, ".git") {
+		t.Errorf("mirrorName = %q, want numpy-<hash>.git", a)
+	}
+	if mirrorName("https://github.com/other/numpy") == a {
+		t.Error("different URLs should have different mirror names")
+	}
+}
+
+func TestMirror(t *testing.T) {
+	url := initRepo(t, "v1.0.0")
+	c := New(t.TempDir())
+
+	path, err := c.Mirror(url)
+	if err != nil {
+		t.Fatalf("Mirror() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "HEAD")); err != nil {
+		t.Errorf("mirror not created: %v", err)
+	}
+
+	// Second call fetches into the existing mirror
+	if _, err := c.Mirror(url); err != nil {
+		t.Fatalf("Mirror() refresh failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Error("lock file should be released")
+	}
+}
+
+func TestMirrorConcurrent(t *testing.T) {
+	url := initRepo(t, "v1.0.0")
+	c := New(t.TempDir())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Mirror(url); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent Mirror() failed: %v", err)
+	}
+}
+
+func TestAddWorktree(t *testing.T) {
+	url := initRepo(t, "v1.0.0", "v2.0.0")
+	c := New(t.TempDir())
+
+	dest := filepath.Join(t.TempDir(), "src")
+	if err := c.AddWorktree(url, dest, "v1.0.0"); err != nil {
+		t.Fatalf("AddWorktree() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "v1.0.0" {
+		t.Errorf("VERSION = %q, want %q", data, "v1.0.0")
+	}
+
+	if err := c.RemoveWorktree(url, dest); err != nil {
+		t.Fatalf("RemoveWorktree() failed: %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("worktree directory should be removed")
+	}
+}
+
+func TestEvict(t *testing.T) {
+	oldURL := initRepo(t, "v1.0.0")
+	newURL := initRepo(t, "v1.0.0")
+	c := New(t.TempDir())
+
+	oldPath, err := c.Mirror(oldURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPath, err := c.Mirror(newURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(oldPath, lastUsedFile), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.Evict(0, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Evict() failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != oldPath {
+		t.Errorf("removed = %v, want [%s]", removed, oldPath)
+	}
+
+	// A size limit of one byte evicts everything that isn't locked
+	unlock, err := tryLock(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err = c.Evict(1, 0)
+	unlock()
+	if err != nil {
+		t.Fatalf("Evict() failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("locked mirror should not be evicted, removed = %v", removed)
+	}
+
+	removed, err = c.Evict(1, 0)
+	if err != nil {
+		t.Fatalf("Evict() failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != newPath {
+		t.Errorf("removed = %v, want [%s]", removed, newPath)
+	}
+}
+
+func TestEvictLiveWorktree(t *testing.T) {
+	url := initRepo(t, "v1.0.0")
+	c := New(t.TempDir())
+
+	dest := filepath.Join(t.TempDir(), "src")
+	if err := c.AddWorktree(url, dest, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	path := c.MirrorPath(url)
+
+	removed, err := c.Evict(1, 0)
+	if err != nil {
+		t.Fatalf("Evict() failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("mirror with a live worktree should not be evicted, removed = %v", removed)
+	}
+
+	// Once the worktree is gone, its registration is pruned and the mirror evicted
+	if err := os.RemoveAll(dest); err != nil {
+		t.Fatal(err)
+	}
+	removed, err = c.Evict(1, 0)
+	if err != nil {
+		t.Fatalf("Evict() failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != path {
+		t.Errorf("removed = %v, want [%s]", removed, path)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	c := New(t.TempDir())
+	c.LockTimeout = 200 * time.Millisecond
+	path := filepath.Join(c.Dir, "pkg.git")
+
+	unlock, err := c.lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	// A held lock is never broken, however old its file is
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		u, err := c.lock(path)
+		if err == nil {
+			u()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("lock() acquired a held lock")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock() did not return")
+	}
+}
+
+func TestLockLeftover(t *testing.T) {
+	c := New(t.TempDir())
+	c.LockTimeout = 200 * time.Millisecond
+	path := filepath.Join(c.Dir, "pkg.git")
+
+	// A lock file left by a crashed agent isn't locked
+	if err := os.WriteFile(path+".lock", []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := c.lock(path)
+	if err != nil {
+		t.Fatalf("lock() with a leftover lock file: %v", err)
+	}
+	if _, err := tryLock(path); !errors.Is(err, errLocked) {
+		t.Errorf("tryLock() on a held lock = %v, want errLocked", err)
+	}
+	unlock()
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Error("lock file should be removed on release")
+	}
+	unlock, err = c.lock(path)
+	if err != nil {
+		t.Fatalf("lock() after release: %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,86 @@
+package gitcache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockPollInterval is how often to retry acquiring a held lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// errLocked is returned by tryLock when the lock is held by someone else.
+var errLocked = errors.New("mirror is locked")
+
+// lock acquires the lock for the mirror at path, waiting up to LockTimeout.
+// The lock is an flock(2) on a lock file, which the kernel releases if the
+// holder crashes, so there are no stale locks to break. The returned
+// function releases the lock.
+func (c *Cache) lock(path string) (func(), error) {
+	timeout := c.LockTimeout
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, err := tryLock(path)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock on %s", path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// tryLock acquires the lock for the mirror at path without waiting.
+func tryLock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("creating lock file: %w", err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, errLocked
+			}
+			return nil, fmt.Errorf("locking %s: %w", lockPath, err)
+		}
+
+		// The previous holder removes the file on release, so the lock
+		// may be on a file that's no longer at lockPath
+		if sameFile(f, lockPath) {
+			f.Truncate(0)
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			return func() {
+				os.Remove(lockPath)
+				f.Close()
+			}, nil
+		}
+		f.Close()
+	}
+}
+
+// sameFile reports whether the open file f is the one at path.
+func sameFile(f *os.File, path string) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(held, current)
+}
@@ -0,0 +1,216 @@
+// Package matrix compares the build matrix of two package configurations,
+// so reviewers and CI can see which (version, python) cells a change affects.
+package matrix
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dlorenc/superwheelie/pkg/builder"
+	"github.com/dlorenc/superwheelie/pkg/config"
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// Change kinds for a cell.
+const (
+	// ChangeAdded is a cell of a newly added version.
+	ChangeAdded = "added"
+
+	// ChangeRemoved is a cell of a removed version.
+	ChangeRemoved = "removed"
+
+	// ChangeTag means the version now builds from a different tag.
+	ChangeTag = "tag"
+
+	// ChangeConfig means the effective build config changed.
+	ChangeConfig = "config"
+
+	// ChangeSkipAdded means the cell is newly skipped.
+	ChangeSkipAdded = "skip-added"
+
+	// ChangeSkipRemoved means the cell is no longer skipped.
+	ChangeSkipRemoved = "skip-removed"
+)
+
+// Side is one side of a comparison.
+type Side struct {
+	// Config is the package config, or nil if the package doesn't exist.
+	Config *config.Config
+
+	// Skips are the package's known failures (optional).
+	Skips *config.Skips
+
+	// PackageDir is the directory patches are read from.
+	PackageDir string
+
+	// RequiresPython is the Requires-Python of each version, if known
+	// (e.g., from builder.SourceRequiresPython or PyPI). Cells it
+	// excludes are not applicable.
+	RequiresPython map[string]string
+}
+
+// CellDiff describes how one cell changed.
+type CellDiff struct {
+	Version string
+	Python  string
+
+	// Changes are the kinds of change, in a fixed order.
+	Changes []string
+
+	// BaseTag and HeadTag are set for ChangeTag.
+	BaseTag string
+	HeadTag string
+
+	// Skip is the head skip for ChangeSkipAdded, or the base skip for
+	// ChangeSkipRemoved.
+	Skip *config.Skip
+
+	// Skipped indicates the cell is skipped in head.
+	Skipped bool
+
+	// NotApplicable indicates head's Requires-Python for the version
+	// excludes the Python version, so the cell is never built.
+	NotApplicable bool
+}
+
+// Has reports whether the cell has a change of the given kind.
+func (c CellDiff) Has(kind string) bool {
+	for _, k := range c.Changes {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff is the set of changed cells between two configurations.
+type Diff struct {
+	// Cells are the changed cells, ordered by version and Python.
+	Cells []CellDiff
+}
+
+// Compare returns the cells that differ between base and head for the
+// given Python versions (default: builder.SupportedPythonVersions).
+func Compare(base, head Side, pythonVersions []string) (*Diff, error) {
+	if pythonVersions == nil {
+		pythonVersions = builder.SupportedPythonVersions
+	}
+
+	baseVersions := versionsOf(base.Config)
+	headVersions := versionsOf(head.Config)
+
+	all := make(map[string]bool)
+	for v := range baseVersions {
+		all[v] = true
+	}
+	for v := range headVersions {
+		all[v] = true
+	}
+	sorted := make([]string, 0, len(all))
+	for v := range all {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return versions.Compare(sorted[i], sorted[j]) > 0
+	})
+
+	diff := &Diff{}
+	for _, key := range sorted {
+		bv, inBase := baseVersions[key]
+		hv, inHead := headVersions[key]
+		// Cells are reported under head's spelling of the version
+		v := hv.Version
+		if !inHead {
+			v = bv.Version
+		}
+
+		configChanged := false
+		if inBase && inHead {
+			baseHash, err := builder.ConfigHash(base.Config, base.PackageDir, bv.Version)
+			if err != nil {
+				return nil, fmt.Errorf("base %s: %w", bv.Version, err)
+			}
+			headHash, err := builder.ConfigHash(head.Config, head.PackageDir, hv.Version)
+			if err != nil {
+				return nil, fmt.Errorf("head %s: %w", hv.Version, err)
+			}
+			configChanged = baseHash != headHash
+		}
+
+		for _, py := range pythonVersions {
+			cell := CellDiff{Version: v, Python: py}
+			switch {
+			case !inBase:
+				cell.Changes = append(cell.Changes, ChangeAdded)
+			case !inHead:
+				cell.Changes = append(cell.Changes, ChangeRemoved)
+			default:
+				if bv.Tag != hv.Tag {
+					cell.Changes = append(cell.Changes, ChangeTag)
+					cell.BaseTag, cell.HeadTag = bv.Tag, hv.Tag
+				}
+				if configChanged {
+					cell.Changes = append(cell.Changes, ChangeConfig)
+				}
+			}
+
+			baseSkip := base.Skips.Find(bv.Version, py)
+			headSkip := head.Skips.Find(hv.Version, py)
+			if !inBase {
+				baseSkip = nil
+			}
+			if !inHead {
+				headSkip = nil
+			}
+			cell.Skipped = headSkip != nil
+			cell.NotApplicable = inHead && !builder.PythonApplicable(py, head.RequiresPython[hv.Version])
+			switch {
+			case headSkip != nil && baseSkip == nil:
+				cell.Changes = append(cell.Changes, ChangeSkipAdded)
+				cell.Skip = headSkip
+			case baseSkip != nil && headSkip == nil && inHead:
+				cell.Changes = append(cell.Changes, ChangeSkipRemoved)
+				cell.Skip = baseSkip
+			}
+
+			if len(cell.Changes) > 0 {
+				diff.Cells = append(diff.Cells, cell)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// Affected returns the cells that need building for head: new, retagged,
+// reconfigured or unskipped cells that aren't skipped or not applicable
+// in head.
+func (d *Diff) Affected() []CellDiff {
+	var cells []CellDiff
+	for _, c := range d.Cells {
+		if c.Skipped || c.NotApplicable || c.Has(ChangeRemoved) {
+			continue
+		}
+		if c.Has(ChangeAdded) || c.Has(ChangeTag) || c.Has(ChangeConfig) || c.Has(ChangeSkipRemoved) {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// versionsOf indexes a config's versions by normalized version, so
+// "2.0-RC1" in one config matches "2.0rc1" in the other. Versions that don't parse
+// are indexed as written.
+func versionsOf(cfg *config.Config) map[string]config.Version {
+	byVersion := make(map[string]config.Version)
+	if cfg == nil {
+		return byVersion
+	}
+	for _, v := range cfg.Versions {
+		key, err := versions.Normalize(v.Version)
+		if err != nil {
+			key = v.Version
+		}
+		byVersion[key] = v
+	}
+	return byVersion
+}
@@ -0,0 +1,197 @@
+package matrix
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+var testPythons = []string{"3.12", "3.13"}
+
+func baseConfig() *config.Config {
+	return &config.Config{
+		Repo: "https://github.com/numpy/numpy",
+		Versions: []config.Version{
+			{Tag: "v2.1.0", Version: "2.1.0"},
+			{Tag: "v2.0.0", Version: "2.0.0"},
+			{Tag: "v1.26.0", Version: "1.26.0"},
+		},
+		Env: map[string]string{"NPY_BLAS_ORDER": "openblas"},
+		Overrides: []config.Override{
+			{Match: "<2.0", Env: map[string]string{"NPY_DISABLE_SVML": "1"}},
+		},
+	}
+}
+
+// cells returns "version/python:changes" for each cell.
+func cells(d *Diff) []string {
+	var out []string
+	for _, c := range d.Cells {
+		s := c.Version + "/" + c.Python + ":"
+		for i, k := range c.Changes {
+			if i > 0 {
+				s += ","
+			}
+			s += k
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	d, err := Compare(Side{Config: baseConfig()}, Side{Config: baseConfig()}, testPythons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Cells) != 0 {
+		t.Errorf("Cells = %v, want none", cells(d))
+	}
+}
+
+func TestCompareOverrideChange(t *testing.T) {
+	head := baseConfig()
+	head.Overrides[0].Env["NPY_DISABLE_SVML"] = "0"
+
+	d, err := Compare(Side{Config: baseConfig()}, Side{Config: head}, testPythons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1.26.0/3.12:config", "1.26.0/3.13:config"}
+	if got := cells(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells = %v, want %v", got, want)
+	}
+	if len(d.Affected()) != 2 {
+		t.Errorf("Affected() = %d cells, want 2", len(d.Affected()))
+	}
+}
+
+func TestCompareVersionsAndSkips(t *testing.T) {
+	base := baseConfig()
+	baseSkips := &config.Skips{Skips: []config.Skip{
+		{Version: "2.0.0", Python: []string{"3.13"}, Reason: "no 3.13 support"},
+	}}
+
+	head := baseConfig()
+	// Add a version, drop one, retag another
+	head.Versions = []config.Version{
+		{Tag: "v2.2.0", Version: "2.2.0"},
+		{Tag: "v2.1.0", Version: "2.1.0"},
+		{Tag: "v2.0.0-fixed", Version: "2.0.0"},
+	}
+	headSkips := &config.Skips{Skips: []config.Skip{
+		{Version: ">=2.2", Python: []string{"3.12"}, Reason: "needs newer meson"},
+	}}
+
+	d, err := Compare(Side{Config: base, Skips: baseSkips}, Side{Config: head, Skips: headSkips}, testPythons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2.2.0/3.12:added,skip-added",
+		"2.2.0/3.13:added",
+		"2.0.0/3.12:tag",
+		"2.0.0/3.13:tag,skip-removed",
+		"1.26.0/3.12:removed",
+		"1.26.0/3.13:removed",
+	}
+	if got := cells(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells = %v, want %v", got, want)
+	}
+
+	var affected []string
+	for _, c := range d.Affected() {
+		affected = append(affected, c.Version+"/"+c.Python)
+	}
+	wantAffected := []string{"2.2.0/3.13", "2.0.0/3.12", "2.0.0/3.13"}
+	if !reflect.DeepEqual(affected, wantAffected) {
+		t.Errorf("Affected() = %v, want %v", affected, wantAffected)
+	}
+}
+
+func TestCompareNewPackage(t *testing.T) {
+	d, err := Compare(Side{}, Side{Config: baseConfig()}, []string{"3.12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2.1.0/3.12:added", "2.0.0/3.12:added", "1.26.0/3.12:added"}
+	if got := cells(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells = %v, want %v", got, want)
+	}
+}
+
+func TestCompareRequiresPython(t *testing.T) {
+	head := Side{
+		Config:         baseConfig(),
+		RequiresPython: map[string]string{"1.26.0": ">=3.9,<3.13", "2.1.0": ">=3.10"},
+	}
+	d, err := Compare(Side{}, head, testPythons)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notApplicable []string
+	for _, c := range d.Cells {
+		if c.NotApplicable {
+			notApplicable = append(notApplicable, c.Version+"/"+c.Python)
+		}
+	}
+	if want := []string{"1.26.0/3.13"}; !reflect.DeepEqual(notApplicable, want) {
+		t.Errorf("not applicable = %v, want %v", notApplicable, want)
+	}
+	if got := len(d.Affected()); got != 5 {
+		t.Errorf("Affected() = %d cells, want 5", got)
+	}
+}
+
+func TestCompareMissingPatch(t *testing.T) {
+	head := baseConfig()
+	head.Patches = []config.Patch{{Path: "patches/missing.patch"}}
+	if _, err := Compare(Side{Config: baseConfig()}, Side{Config: head, PackageDir: t.TempDir()}, testPythons); err == nil {
+		t.Error("Compare() expected error for missing patch")
+	}
+}
+
+func TestCompareVersionOrder(t *testing.T) {
+	head := baseConfig()
+	head.Versions = append([]config.Version{
+		{Tag: "v2.2.0rc1", Version: "2.2.0rc1"},
+		{Tag: "v2.2.0", Version: "2.2.0"},
+	}, head.Versions...)
+
+	d, err := Compare(Side{Config: baseConfig()}, Side{Config: head}, []string{"3.13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pre-releases sort before their final release
+	want := []string{"2.2.0/3.13:added", "2.2.0rc1/3.13:added"}
+	if got := cells(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells = %v, want %v", got, want)
+	}
+}
+
+func TestCompareNormalizedVersions(t *testing.T) {
+	base := baseConfig()
+	base.Versions = append([]config.Version{{Tag: "v2.2.0rc1", Version: "2.2.0-RC1"}}, base.Versions...)
+	head := baseConfig()
+	head.Versions = append([]config.Version{{Tag: "v2.2.0rc1", Version: "2.2.0rc1"}}, head.Versions...)
+
+	d, err := Compare(Side{Config: base}, Side{Config: head}, testPythons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Cells) != 0 {
+		t.Errorf("Cells = %v, want none for a respelled version", cells(d))
+	}
+
+	head.Versions[0].Tag = "2.2.0rc1"
+	d, err = Compare(Side{Config: base}, Side{Config: head}, []string{"3.13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2.2.0rc1/3.13:tag"}
+	if got := cells(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells = %v, want %v", got, want)
+	}
+}
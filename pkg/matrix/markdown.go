@@ -0,0 +1,107 @@
+package matrix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the diff as a Markdown table for a PR comment. Cells of
+// a version with identical changes share a row.
+func (d *Diff) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("### Build matrix changes\n\n")
+	if len(d.Cells) == 0 {
+		sb.WriteString("No cells affected.\n")
+		return sb.String()
+	}
+
+	affected := make(map[string]bool)
+	for _, c := range d.Affected() {
+		affected[c.Version+"/"+c.Python] = true
+	}
+
+	removed, skipped, notApplicable := 0, 0, 0
+	for _, c := range d.Cells {
+		if c.Has(ChangeRemoved) {
+			removed++
+		}
+		if c.Has(ChangeSkipAdded) {
+			skipped++
+		}
+		if c.NotApplicable {
+			notApplicable++
+		}
+	}
+	fmt.Fprintf(&sb, "**%s to build**, %s removed, %s newly skipped",
+		plural(len(affected), "cell"), plural(removed, "cell"), plural(skipped, "cell"))
+	if notApplicable > 0 {
+		fmt.Fprintf(&sb, ", %s not applicable (requires-python)", plural(notApplicable, "cell"))
+	}
+	sb.WriteString(".\n\n")
+
+	sb.WriteString("| Version | Python | Changes | Build |\n")
+	sb.WriteString("|---------|--------|---------|-------|\n")
+
+	type row struct {
+		version, changes, build string
+		pythons                 []string
+	}
+	var rows []*row
+	for _, c := range d.Cells {
+		build := "no"
+		switch {
+		case affected[c.Version+"/"+c.Python]:
+			build = "yes"
+		case c.NotApplicable:
+			build = "n/a"
+		}
+		changes := describe(c)
+
+		last := len(rows) - 1
+		if last >= 0 && rows[last].version == c.Version && rows[last].changes == changes && rows[last].build == build {
+			rows[last].pythons = append(rows[last].pythons, c.Python)
+			continue
+		}
+		rows = append(rows, &row{version: c.Version, changes: changes, build: build, pythons: []string{c.Python}})
+	}
+
+	for _, r := range rows {
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", r.version, strings.Join(r.pythons, ", "), r.changes, r.build)
+	}
+	return sb.String()
+}
+
+// describe returns a human-readable summary of a cell's changes.
+func describe(c CellDiff) string {
+	var parts []string
+	for _, kind := range c.Changes {
+		switch kind {
+		case ChangeAdded:
+			parts = append(parts, "new version")
+		case ChangeRemoved:
+			parts = append(parts, "version removed")
+		case ChangeTag:
+			parts = append(parts, fmt.Sprintf("tag `%s` → `%s`", c.BaseTag, c.HeadTag))
+		case ChangeConfig:
+			parts = append(parts, "config changed")
+		case ChangeSkipAdded:
+			parts = append(parts, "skipped: "+escapeCell(c.Skip.Reason))
+		case ChangeSkipRemoved:
+			parts = append(parts, "unskipped (was: "+escapeCell(c.Skip.Reason)+")")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// escapeCell makes text safe inside a Markdown table cell.
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,54 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func TestMarkdown(t *testing.T) {
+	d := &Diff{Cells: []CellDiff{
+		{Version: "2.2.0", Python: "3.12", Changes: []string{ChangeAdded}},
+		{Version: "2.2.0", Python: "3.13", Changes: []string{ChangeAdded}},
+		{Version: "2.1.0", Python: "3.13", Changes: []string{ChangeConfig, ChangeSkipAdded},
+			Skip: &config.Skip{Reason: "needs a | b"}, Skipped: true},
+		{Version: "2.0.0", Python: "3.12", Changes: []string{ChangeTag}, BaseTag: "v2.0.0", HeadTag: "v2.0.0-fixed"},
+		{Version: "1.0.0", Python: "3.12", Changes: []string{ChangeRemoved}},
+	}}
+
+	want := "### Build matrix changes\n\n" +
+		"**3 cells to build**, 1 cell removed, 1 cell newly skipped.\n\n" +
+		"| Version | Python | Changes | Build |\n" +
+		"|---------|--------|---------|-------|\n" +
+		"| 2.2.0 | 3.12, 3.13 | new version | yes |\n" +
+		"| 2.1.0 | 3.13 | config changed; skipped: needs a \\| b | no |\n" +
+		"| 2.0.0 | 3.12 | tag `v2.0.0` → `v2.0.0-fixed` | yes |\n" +
+		"| 1.0.0 | 3.12 | version removed | no |\n"
+	if got := d.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownNotApplicable(t *testing.T) {
+	d := &Diff{Cells: []CellDiff{
+		{Version: "1.0.0", Python: "3.12", Changes: []string{ChangeAdded}},
+		{Version: "1.0.0", Python: "3.13", Changes: []string{ChangeAdded}, NotApplicable: true},
+	}}
+
+	want := "### Build matrix changes\n\n" +
+		"**1 cell to build**, 0 cells removed, 0 cells newly skipped, 1 cell not applicable (requires-python).\n\n" +
+		"| Version | Python | Changes | Build |\n" +
+		"|---------|--------|---------|-------|\n" +
+		"| 1.0.0 | 3.12 | new version | yes |\n" +
+		"| 1.0.0 | 3.13 | new version | n/a |\n"
+	if got := d.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownEmpty(t *testing.T) {
+	want := "### Build matrix changes\n\nNo cells affected.\n"
+	if got := (&Diff{}).Markdown(); got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+// Package pypi is a client for the PyPI JSON and simple APIs. Responses are
+// cached on disk, and the client can read from a directory of fixtures
+// instead of the network.
+package pypi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// DefaultBaseURL is the PyPI index.
+const DefaultBaseURL = "https://pypi.org"
+
+// DefaultCacheTTL is how long cached responses are used without refetching.
+const DefaultCacheTTL = time.Hour
+
+// simpleAccept requests the JSON form of the simple API (PEP 691).
+const simpleAccept = "application/vnd.pypi.simple.v1+json"
+
+// ErrNotFound is returned when a project doesn't exist.
+var ErrNotFound = errors.New("project not found")
+
+// Client fetches project metadata from PyPI.
+type Client struct {
+	// BaseURL is the index URL (default: DefaultBaseURL).
+	BaseURL string
+
+	// HTTPClient is used for requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+
+	// CacheDir caches responses on disk. If empty, nothing is cached.
+	// A cache directory can be used as a FixtureDir.
+	CacheDir string
+
+	// CacheTTL is how long cached responses are fresh (default: DefaultCacheTTL).
+	// Stale responses are still used if the index can't be reached.
+	CacheTTL time.Duration
+
+	// FixtureDir, if set, serves responses from files instead of the
+	// network, laid out like CacheDir: {name}.json for the JSON API and
+	// simple/{name}.json for the simple API.
+	FixtureDir string
+}
+
+// Project returns the JSON API metadata for a project (/pypi/{name}/json).
+func (c *Client) Project(ctx context.Context, name string) (*Project, error) {
+	name = config.NormalizeName(name)
+	data, err := c.get(ctx, "/pypi/"+url.PathEscape(name)+"/json", "application/json", name+".json")
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s metadata: %w", name, err)
+	}
+	var p Project
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s metadata: %w", name, err)
+	}
+	return &p, nil
+}
+
+// Simple returns the simple API file listing for a project (/simple/{name}/).
+func (c *Client) Simple(ctx context.Context, name string) (*SimpleIndex, error) {
+	name = config.NormalizeName(name)
+	data, err := c.get(ctx, "/simple/"+url.PathEscape(name)+"/", simpleAccept, filepath.Join("simple", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s simple index: %w", name, err)
+	}
+	var idx SimpleIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parsing %s simple index: %w", name, err)
+	}
+	return &idx, nil
+}
+
+// get returns the response body for path, using fixtures or the cache
+// when available. file is the response's path in the cache and fixture
+// directories.
+func (c *Client) get(ctx context.Context, path, accept, file string) ([]byte, error) {
+	if c.FixtureDir != "" {
+		data, err := os.ReadFile(filepath.Join(c.FixtureDir, file))
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return data, err
+	}
+
+	var cached []byte
+	if c.CacheDir != "" {
+		cachePath := filepath.Join(c.CacheDir, file)
+		if info, err := os.Stat(cachePath); err == nil {
+			cached, _ = os.ReadFile(cachePath)
+			if cached != nil && time.Since(info.ModTime()) < c.cacheTTL() {
+				return cached, nil
+			}
+		}
+	}
+
+	data, err := c.fetch(ctx, path, accept)
+	if err != nil {
+		if cached != nil && !errors.Is(err, ErrNotFound) {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if c.CacheDir != "" {
+		if err := writeCache(filepath.Join(c.CacheDir, file), data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// fetch performs a GET request against the index.
+func (c *Client) fetch(ctx context.Context, path, accept string) ([]byte, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "superwheelie")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) cacheTTL() time.Duration {
+	if c.CacheTTL != 0 {
+		return c.CacheTTL
+	}
+	return DefaultCacheTTL
+}
+
+// writeCache writes a cache entry atomically.
+func writeCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pypi-*")
+	if err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package pypi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newServer serves the testdata fixtures like PyPI and counts requests.
+func newServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		var file string
+		switch r.URL.Path {
+		case "/pypi/requests/json":
+			file = "requests.json"
+		case "/simple/requests/":
+			if r.Header.Get("Accept") != simpleAccept {
+				http.Error(w, "want JSON", http.StatusNotAcceptable)
+				return
+			}
+			file = filepath.Join("simple", "requests.json")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", file))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestClientProject(t *testing.T) {
+	srv, _ := newServer(t)
+	c := &Client{BaseURL: srv.URL}
+
+	p, err := c.Project(context.Background(), "Requests")
+	if err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if p.Info.Name != "requests" || p.Info.Version != "2.32.3" {
+		t.Errorf("Info = %+v", p.Info)
+	}
+
+	if _, err := c.Project(context.Background(), "does-not-exist"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Project() for missing project error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientSimple(t *testing.T) {
+	srv, _ := newServer(t)
+	c := &Client{BaseURL: srv.URL}
+
+	idx, err := c.Simple(context.Background(), "requests")
+	if err != nil {
+		t.Fatalf("Simple() error = %v", err)
+	}
+	if len(idx.Files) != 3 || len(idx.Versions) != 3 {
+		t.Fatalf("Simple() = %+v", idx)
+	}
+	if idx.Files[0].Yanked.Yanked {
+		t.Error("file 0 should not be yanked")
+	}
+	if y := idx.Files[1].Yanked; !y.Yanked || y.Reason == "" {
+		t.Errorf("file 1 Yanked = %+v, want yanked with reason", y)
+	}
+	if idx.Files[2].RequiresPython != ">=3.8" {
+		t.Errorf("RequiresPython = %q", idx.Files[2].RequiresPython)
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	srv, hits := newServer(t)
+	cacheDir := t.TempDir()
+	c := &Client{BaseURL: srv.URL, CacheDir: cacheDir}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Project(ctx, "requests"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server hit %d times, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "requests.json")); err != nil {
+		t.Errorf("response not cached: %v", err)
+	}
+
+	// Stale entries are refetched
+	old := time.Now().Add(-2 * DefaultCacheTTL)
+	if err := os.Chtimes(filepath.Join(cacheDir, "requests.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Project(ctx, "requests"); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("server hit %d times after expiry, want 2", got)
+	}
+
+	// ...but used if the index is unreachable
+	if err := os.Chtimes(filepath.Join(cacheDir, "requests.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	p, err := c.Project(ctx, "requests")
+	if err != nil {
+		t.Fatalf("Project() with index down error = %v, want stale cache", err)
+	}
+	if p.Info.Name != "requests" {
+		t.Errorf("stale Info = %+v", p.Info)
+	}
+}
+
+func TestClientFixtures(t *testing.T) {
+	c := &Client{FixtureDir: "testdata", BaseURL: "http://127.0.0.1:0"}
+	ctx := context.Background()
+
+	p, err := c.Project(ctx, "requests")
+	if err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if p.Info.Version != "2.32.3" {
+		t.Errorf("Version = %q", p.Info.Version)
+	}
+	if _, err := c.Simple(ctx, "requests"); err != nil {
+		t.Errorf("Simple() error = %v", err)
+	}
+	if _, err := c.Project(ctx, "numpy"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Project() for missing fixture error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,206 @@
+package pypi
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/versions"
+)
+
+// Package types of release files.
+const (
+	PackageTypeSdist = "sdist"
+	PackageTypeWheel = "bdist_wheel"
+)
+
+// Project is the JSON API response for a project.
+type Project struct {
+	Info     Info              `json:"info"`
+	Releases map[string][]File `json:"releases"`
+
+	// URLs are the files of the latest release.
+	URLs []File `json:"urls"`
+}
+
+// Info is the project metadata of the latest release.
+type Info struct {
+	Name           string            `json:"name"`
+	Version        string            `json:"version"`
+	Summary        string            `json:"summary"`
+	HomePage       string            `json:"home_page"`
+	ProjectURLs    map[string]string `json:"project_urls"`
+	RequiresPython string            `json:"requires_python"`
+}
+
+// File is a release file.
+type File struct {
+	Filename       string  `json:"filename"`
+	URL            string  `json:"url"`
+	PackageType    string  `json:"packagetype"`
+	RequiresPython string  `json:"requires_python"`
+	Digests        Digests `json:"digests"`
+	Yanked         bool    `json:"yanked"`
+}
+
+// Digests are the hashes of a release file.
+type Digests struct {
+	SHA256 string `json:"sha256"`
+}
+
+// Versions returns the release versions with at least one file that isn't
+// yanked, newest first in PEP 440 order.
+func (p *Project) Versions() []string {
+	var vs []string
+	for v, files := range p.Releases {
+		for _, f := range files {
+			if !f.Yanked {
+				vs = append(vs, v)
+				break
+			}
+		}
+	}
+	sort.Slice(vs, func(i, j int) bool { return versions.Compare(vs[i], vs[j]) > 0 })
+	return vs
+}
+
+// RequiresPython returns the Requires-Python of a release, or "" if it
+// doesn't declare one.
+func (p *Project) RequiresPython(version string) string {
+	for _, f := range p.Releases[version] {
+		if f.RequiresPython != "" {
+			return f.RequiresPython
+		}
+	}
+	if version == p.Info.Version {
+		return p.Info.RequiresPython
+	}
+	return ""
+}
+
+// Sdist returns the source distribution of a release, or nil.
+func (p *Project) Sdist(version string) *File {
+	for i, f := range p.Releases[version] {
+		if f.PackageType == PackageTypeSdist && !f.Yanked {
+			return &p.Releases[version][i]
+		}
+	}
+	return nil
+}
+
+// Wheels returns the wheels of a release.
+func (p *Project) Wheels(version string) []File {
+	var wheels []File
+	for _, f := range p.Releases[version] {
+		if f.PackageType == PackageTypeWheel && !f.Yanked {
+			wheels = append(wheels, f)
+		}
+	}
+	return wheels
+}
+
+// sourceKeys are project_urls labels that usually point at the source
+// repository, most specific first.
+var sourceKeys = []string{
+	"source", "source code", "sourcecode", "source-code", "repository", "code",
+	"github", "gitlab", "git", "homepage", "home", "home page",
+}
+
+// repoHosts are forges whose URLs are {host}/{owner}/{repo}.
+var repoHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	"codeberg.org":  true,
+}
+
+// SourceRepos returns candidate source repository URLs from the project
+// URLs and home page, most likely first.
+func (p *Project) SourceRepos() []string {
+	labels := make(map[string][]string)
+	var other []string
+	for label, u := range p.Info.ProjectURLs {
+		key := strings.ToLower(strings.TrimSpace(label))
+		labels[key] = append(labels[key], u)
+		other = append(other, u)
+	}
+	sort.Strings(other)
+
+	var ordered []string
+	for _, key := range sourceKeys {
+		sort.Strings(labels[key])
+		ordered = append(ordered, labels[key]...)
+	}
+	ordered = append(ordered, p.Info.HomePage)
+	ordered = append(ordered, other...)
+
+	seen := make(map[string]bool)
+	var repos []string
+	for _, u := range ordered {
+		repo, ok := RepoURL(u)
+		if !ok || seen[repo] {
+			continue
+		}
+		seen[repo] = true
+		repos = append(repos, repo)
+	}
+	return repos
+}
+
+// RepoURL returns the clone URL of a repository link, trimming paths into
+// the repository (e.g., "/tree/main", "/issues"). It returns false if the
+// URL isn't on a known forge and doesn't end in ".git".
+func RepoURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	host := strings.ToLower(strings.TrimPrefix(u.Host, "www."))
+	parts := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	if repoHosts[host] {
+		if len(parts) < 2 {
+			return "", false
+		}
+		repo := strings.TrimSuffix(parts[1], ".git")
+		return "https://" + host + "/" + parts[0] + "/" + repo, true
+	}
+	if strings.HasSuffix(u.Path, ".git") {
+		return "https://" + host + strings.TrimSuffix(u.Path, "/"), true
+	}
+	return "", false
+}
+
+// SimpleIndex is the simple API (PEP 691) response for a project.
+type SimpleIndex struct {
+	Name     string       `json:"name"`
+	Files    []SimpleFile `json:"files"`
+	Versions []string     `json:"versions"`
+}
+
+// SimpleFile is a file in the simple index.
+type SimpleFile struct {
+	Filename       string            `json:"filename"`
+	URL            string            `json:"url"`
+	Hashes         map[string]string `json:"hashes"`
+	RequiresPython string            `json:"requires-python"`
+	Yanked         Yanked            `json:"yanked"`
+}
+
+// Yanked is the yank status of a file, which the simple API reports as
+// false or as the reason it was yanked.
+type Yanked struct {
+	Yanked bool
+	Reason string
+}
+
+// UnmarshalJSON accepts a boolean or a reason string.
+func (y *Yanked) UnmarshalJSON(data []byte) error {
+	var reason string
+	if err := json.Unmarshal(data, &reason); err == nil {
+		*y = Yanked{Yanked: true, Reason: reason}
+		return nil
+	}
+	return json.Unmarshal(data, &y.Yanked)
+}
@@ -0,0 +1,98 @@
+package pypi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func loadFixture(t *testing.T) *Project {
+	t.Helper()
+	p, err := (&Client{FixtureDir: "testdata"}).Project(context.Background(), "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProjectVersions(t *testing.T) {
+	p := loadFixture(t)
+
+	// 2.32.0 is yanked and 3.0.0a1 has no files
+	if got, want := p.Versions(), []string{"2.32.3", "2.31.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions() = %v, want %v", got, want)
+	}
+}
+
+func TestProjectFiles(t *testing.T) {
+	p := loadFixture(t)
+
+	if got := p.RequiresPython("2.31.0"); got != ">=3.7" {
+		t.Errorf("RequiresPython(2.31.0) = %q, want >=3.7", got)
+	}
+	if got := p.RequiresPython("9.9"); got != "" {
+		t.Errorf("RequiresPython(9.9) = %q, want empty", got)
+	}
+
+	sdist := p.Sdist("2.32.3")
+	if sdist == nil || sdist.Filename != "requests-2.32.3.tar.gz" || sdist.Digests.SHA256 == "" {
+		t.Errorf("Sdist(2.32.3) = %+v", sdist)
+	}
+	if sdist := p.Sdist("2.32.0"); sdist != nil {
+		t.Errorf("Sdist(2.32.0) = %+v, want nil for yanked file", sdist)
+	}
+
+	wheels := p.Wheels("2.31.0")
+	if len(wheels) != 1 || wheels[0].Filename != "requests-2.31.0-py3-none-any.whl" {
+		t.Errorf("Wheels(2.31.0) = %+v", wheels)
+	}
+}
+
+func TestSourceRepos(t *testing.T) {
+	p := loadFixture(t)
+	if got, want := p.SourceRepos(), []string{"https://github.com/psf/requests"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceRepos() = %v, want %v", got, want)
+	}
+
+	p = &Project{Info: Info{
+		HomePage: "https://github.com/Org/Home",
+		ProjectURLs: map[string]string{
+			"Bug Tracker": "https://github.com/org/tracker/issues",
+			"Repository":  "https://gitlab.com/org/repo.git",
+			"Docs":        "https://example.readthedocs.io",
+		},
+	}}
+	want := []string{
+		"https://gitlab.com/org/repo",
+		"https://github.com/Org/Home",
+		"https://github.com/org/tracker",
+	}
+	if got := p.SourceRepos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceRepos() = %v, want %v", got, want)
+	}
+}
+
+func TestRepoURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"https://github.com/psf/requests", "https://github.com/psf/requests", true},
+		{"http://www.github.com/psf/requests.git", "https://github.com/psf/requests", true},
+		{"https://github.com/numpy/numpy/tree/main/numpy", "https://github.com/numpy/numpy", true},
+		{"https://gitlab.com/group/project/-/issues", "https://gitlab.com/group/project", true},
+		{"https://git.example.org/pub/project.git", "https://git.example.org/pub/project.git", true},
+		{"https://github.com/psf", "", false},
+		{"https://requests.readthedocs.io", "", false},
+		{"not a url", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := RepoURL(tt.in)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("RepoURL(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
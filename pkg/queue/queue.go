@@ -0,0 +1,267 @@
+// Package queue reads and writes queue.txt, the list of packages waiting
+// to be built.
+//
+// Each non-comment line holds a package name and optional attributes,
+// e.g. "numpy priority=10  # heavy". Comments and blank lines are
+// preserved when the queue is written back.
+package queue
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// DefaultPriority is the selection weight of entries without a priority.
+const DefaultPriority = 1
+
+// nameRe matches a valid Python package name.
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// Entry is a queued package.
+type Entry struct {
+	// Name is the PEP 503 normalized package name.
+	Name string
+
+	// Priority is the selection weight (default: DefaultPriority).
+	Priority int
+
+	// Comment is the trailing comment, without the leading "#".
+	Comment string
+
+	// Line is the 1-based line number the entry was parsed from, or 0.
+	Line int
+}
+
+// String formats the entry as a queue.txt line.
+func (e Entry) String() string {
+	s := e.Name
+	if e.Priority != DefaultPriority {
+		s += " priority=" + strconv.Itoa(e.Priority)
+	}
+	if e.Comment != "" {
+		s += "  # " + e.Comment
+	}
+	return s
+}
+
+// line is a line of queue.txt: either an entry or verbatim text.
+type line struct {
+	text  string
+	entry *Entry
+}
+
+// Queue is a parsed queue.txt.
+type Queue struct {
+	lines []line
+}
+
+// Parse parses queue.txt content.
+func Parse(data []byte) (*Queue, error) {
+	q := &Queue{}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return q, nil
+	}
+
+	for i, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		content, comment, _ := strings.Cut(raw, "#")
+		fields := strings.Fields(content)
+		if len(fields) == 0 {
+			q.lines = append(q.lines, line{text: raw})
+			continue
+		}
+
+		if !nameRe.MatchString(fields[0]) {
+			return nil, fmt.Errorf("line %d: invalid package name %q", i+1, fields[0])
+		}
+		e := &Entry{
+			Name:     config.NormalizeName(fields[0]),
+			Priority: DefaultPriority,
+			Comment:  strings.TrimSpace(comment),
+			Line:     i + 1,
+		}
+		for _, attr := range fields[1:] {
+			key, value, ok := strings.Cut(attr, "=")
+			if !ok || key != "priority" {
+				return nil, fmt.Errorf("line %d: unknown attribute %q", i+1, attr)
+			}
+			p, err := strconv.Atoi(value)
+			if err != nil || p < 1 {
+				return nil, fmt.Errorf("line %d: priority must be a positive integer, got %q", i+1, value)
+			}
+			e.Priority = p
+		}
+		q.lines = append(q.lines, line{entry: e})
+	}
+	return q, nil
+}
+
+// Load reads a queue file. A missing file is an empty queue.
+func Load(path string) (*Queue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Queue{}, nil
+		}
+		return nil, fmt.Errorf("reading queue: %w", err)
+	}
+	q, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return q, nil
+}
+
+// Save writes the queue atomically.
+func (q *Queue) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".queue-*")
+	if err != nil {
+		return fmt.Errorf("writing queue: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(q.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing queue: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing queue: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("writing queue: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing queue: %w", err)
+	}
+	return nil
+}
+
+// Bytes formats the queue. Entries are written in normalized form;
+// comments and blank lines are kept as they were.
+func (q *Queue) Bytes() []byte {
+	var sb strings.Builder
+	for _, l := range q.lines {
+		if l.entry != nil {
+			sb.WriteString(l.entry.String())
+		} else {
+			sb.WriteString(l.text)
+		}
+		sb.WriteByte('\n')
+	}
+	return []byte(sb.String())
+}
+
+// Entries returns the queued packages in file order.
+func (q *Queue) Entries() []Entry {
+	var entries []Entry
+	for _, l := range q.lines {
+		if l.entry != nil {
+			entries = append(entries, *l.entry)
+		}
+	}
+	return entries
+}
+
+// Find returns the first entry for a package, or nil.
+func (q *Queue) Find(name string) *Entry {
+	name = config.NormalizeName(name)
+	for _, l := range q.lines {
+		if l.entry != nil && l.entry.Name == name {
+			return l.entry
+		}
+	}
+	return nil
+}
+
+// Add appends a package unless it is already queued.
+// Returns false if it was already present.
+func (q *Queue) Add(name string, priority int) bool {
+	if q.Find(name) != nil {
+		return false
+	}
+	if priority < 1 {
+		priority = DefaultPriority
+	}
+	q.lines = append(q.lines, line{entry: &Entry{Name: config.NormalizeName(name), Priority: priority}})
+	return true
+}
+
+// Remove deletes every entry for a package.
+// Returns false if it wasn't queued.
+func (q *Queue) Remove(name string) bool {
+	name = config.NormalizeName(name)
+	removed := false
+	kept := q.lines[:0]
+	for _, l := range q.lines {
+		if l.entry != nil && l.entry.Name == name {
+			removed = true
+			continue
+		}
+		kept = append(kept, l)
+	}
+	q.lines = kept
+	return removed
+}
+
+// Duplicates returns the entries of packages queued more than once,
+// grouped by name in file order.
+func (q *Queue) Duplicates() [][]Entry {
+	byName := make(map[string][]Entry)
+	var order []string
+	for _, e := range q.Entries() {
+		if _, ok := byName[e.Name]; !ok {
+			order = append(order, e.Name)
+		}
+		byName[e.Name] = append(byName[e.Name], e)
+	}
+
+	var dups [][]Entry
+	for _, name := range order {
+		if len(byName[name]) > 1 {
+			dups = append(dups, byName[name])
+		}
+	}
+	return dups
+}
+
+// Pick selects a random entry, weighted by priority, skipping packages for
+// which exclude returns true (e.g., already claimed). A nil r uses the
+// global source. Returns false if no entry is eligible.
+func (q *Queue) Pick(r *rand.Rand, exclude func(name string) bool) (Entry, bool) {
+	var eligible []Entry
+	total := 0
+	seen := make(map[string]bool)
+	for _, e := range q.Entries() {
+		if seen[e.Name] || (exclude != nil && exclude(e.Name)) {
+			continue
+		}
+		seen[e.Name] = true
+		eligible = append(eligible, e)
+		total += e.Priority
+	}
+	if total == 0 {
+		return Entry{}, false
+	}
+
+	var n int
+	if r != nil {
+		n = r.IntN(total)
+	} else {
+		n = rand.IntN(total)
+	}
+	for _, e := range eligible {
+		if n < e.Priority {
+			return e, true
+		}
+		n -= e.Priority
+	}
+	return Entry{}, false
+}
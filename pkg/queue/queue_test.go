@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testQueue = `# Packages waiting to be built
+numpy priority=10  # heavy, popular
+
+Flask
+ruamel.yaml
+requests   # plain
+`
+
+func TestParse(t *testing.T) {
+	q, err := Parse([]byte(testQueue))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Name: "numpy", Priority: 10, Comment: "heavy, popular", Line: 2},
+		{Name: "flask", Priority: 1, Line: 4},
+		{Name: "ruamel-yaml", Priority: 1, Line: 5},
+		{Name: "requests", Priority: 1, Comment: "plain", Line: 6},
+	}
+	if got := q.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %+v, want %+v", got, want)
+	}
+
+	wantBytes := `# Packages waiting to be built
+numpy priority=10  # heavy, popular
+
+flask
+ruamel-yaml
+requests  # plain
+`
+	if got := string(q.Bytes()); got != wantBytes {
+		t.Errorf("Bytes() =\n%s\nwant:\n%s", got, wantBytes)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid name", "numpy\n-bad-\n"},
+		{"unknown attribute", "numpy weight=3\n"},
+		{"bad priority", "numpy priority=high\n"},
+		{"zero priority", "numpy priority=0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Parse() expected error")
+			}
+		})
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	q, err := Parse([]byte(testQueue))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Add("NumPy", 1) {
+		t.Error("Add() of a queued package (different spelling) should return false")
+	}
+	if !q.Add("SciPy", 5) {
+		t.Error("Add() of a new package should return true")
+	}
+	if !q.Remove("Ruamel_YAML") {
+		t.Error("Remove() should match normalized names")
+	}
+	if q.Remove("django") {
+		t.Error("Remove() of an unqueued package should return false")
+	}
+
+	want := `# Packages waiting to be built
+numpy priority=10  # heavy, popular
+
+flask
+requests  # plain
+scipy priority=5
+`
+	if got := string(q.Bytes()); got != want {
+		t.Errorf("Bytes() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.txt")
+
+	q, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	if len(q.Entries()) != 0 {
+		t.Errorf("missing file should be an empty queue")
+	}
+
+	q.Add("numpy", 1)
+	if err := q.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "numpy\n" {
+		t.Errorf("saved %q, want %q", data, "numpy\n")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	q, err := Parse([]byte("numpy\nflask\nNumPy priority=2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups := q.Duplicates()
+	if len(dups) != 1 || len(dups[0]) != 2 || dups[0][0].Line != 1 || dups[0][1].Line != 3 {
+		t.Errorf("Duplicates() = %+v", dups)
+	}
+}
+
+func TestPick(t *testing.T) {
+	q, err := Parse([]byte("numpy priority=8\nflask priority=2\nrequests\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	counts := make(map[string]int)
+	for i := 0; i < 11000; i++ {
+		e, ok := q.Pick(r, nil)
+		if !ok {
+			t.Fatal("Pick() returned nothing")
+		}
+		counts[e.Name]++
+	}
+	// Expected 8000 / 2000 / 1000
+	for name, want := range map[string]int{"numpy": 8000, "flask": 2000, "requests": 1000} {
+		if got := counts[name]; got < want*9/10 || got > want*11/10 {
+			t.Errorf("picked %s %d times, want about %d", name, got, want)
+		}
+	}
+
+	// Excluded packages are never picked
+	claimed := map[string]bool{"numpy": true, "flask": true}
+	for i := 0; i < 100; i++ {
+		e, ok := q.Pick(r, func(name string) bool { return claimed[name] })
+		if !ok || e.Name != "requests" {
+			t.Fatalf("Pick() = %v, %v, want requests", e, ok)
+		}
+	}
+
+	claimed["requests"] = true
+	if _, ok := q.Pick(r, func(name string) bool { return claimed[name] }); ok {
+		t.Error("Pick() with every package excluded should return false")
+	}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// PackageNames returns the normalized names of the packages with a
+// config.yaml in packagesDir, mapped to their directory names.
+func PackageNames(packagesDir string) (map[string]string, error) {
+	dirEntries, err := os.ReadDir(packagesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("reading packages: %w", err)
+	}
+
+	names := make(map[string]string)
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(packagesDir, d.Name(), "config.yaml")); err != nil {
+			continue
+		}
+		names[config.NormalizeName(d.Name())] = d.Name()
+	}
+	return names, nil
+}
+
+// Validate checks the queue against the packages directory. It reports
+// packages queued more than once and queued packages that already have a
+// config in packagesDir.
+func Validate(q *Queue, packagesDir string) error {
+	var errs []error
+	for _, dup := range q.Duplicates() {
+		lines := make([]int, len(dup))
+		for i, e := range dup {
+			lines[i] = e.Line
+		}
+		errs = append(errs, fmt.Errorf("%s: queued more than once (lines %v)", dup[0].Name, lines))
+	}
+
+	packages, err := PackageNames(packagesDir)
+	if err != nil {
+		return err
+	}
+	reported := make(map[string]bool)
+	for _, e := range q.Entries() {
+		if dir, ok := packages[e.Name]; ok && !reported[e.Name] {
+			reported[e.Name] = true
+			errs = append(errs, fmt.Errorf("line %d: %s is queued but already built in packages/%s", e.Line, e.Name, dir))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	packagesDir := t.TempDir()
+	for _, name := range []string{"Flask", "numpy"} {
+		dir := filepath.Join(packagesDir, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("repo: x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory without a config isn't a built package
+	if err := os.MkdirAll(filepath.Join(packagesDir, "scipy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Parse([]byte("scipy\nrequests\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(q, packagesDir); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	q, err = Parse([]byte("flask\nscipy\nrequests\nRequests\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(q, packagesDir)
+	if err == nil {
+		t.Fatal("Validate() expected error")
+	}
+	for _, want := range []string{
+		"requests: queued more than once (lines [3 4])",
+		"line 1: flask is queued but already built in packages/Flask",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error missing %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package results persists build cell outcomes and answers questions about
+// their history, such as the last known good build of a cell or which cells
+// are flaky.
+//
+// The store is a JSON lines file rather than SQLite or bbolt: it needs no
+// cgo or third-party dependency, concurrent agents can append to it without
+// locking, and it can be read and diffed with standard tools. The cost is
+// that it has no index, so every query (History, LastKnownGood, NewlyBroken,
+// Flaky) reads and decodes the whole file. At roughly 400 bytes per record,
+// 100,000 records (a full matrix of 10 versions and 5 Pythons for 2,000
+// packages) is about 40 MB and a query takes well under a second; past a
+// few million records queries get slow enough that the store should be
+// compacted to each cell's recent history or moved to an indexed database
+// behind the same Store methods.
+package results
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/builder"
+)
+
+// Cell identifies one entry of the build matrix. Arch is the platform the
+// cell was built on, so a pure-Python wheel and a failed build of the same
+// cell share a Cell.
+type Cell struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Python  string `json:"python"`
+	Arch    string `json:"arch"`
+}
+
+// String returns the cell as "package==version/python/arch".
+func (c Cell) String() string {
+	return fmt.Sprintf("%s==%s/%s/%s", c.Package, c.Version, c.Python, c.Arch)
+}
+
+// Record is the outcome of building one cell.
+type Record struct {
+	Cell
+
+	// Time is when the build finished.
+	Time time.Time `json:"time"`
+
+	// ConfigHash identifies the build inputs (builder.BuildResult.CellHash).
+	ConfigHash string `json:"config_hash,omitempty"`
+
+	// Success indicates whether the build succeeded.
+	Success bool `json:"success"`
+
+	// FailureClass categorizes a failed build (e.g., builder.FailureNetwork).
+	FailureClass string `json:"failure_class,omitempty"`
+
+	// NotApplicable indicates the version's Requires-Python excludes the
+	// Python version; the cell wasn't built and didn't fail.
+	NotApplicable bool `json:"not_applicable,omitempty"`
+
+	// Backend is the detected build backend (builder.BuildResult.Backend).
+	Backend string `json:"backend,omitempty"`
+
+	// Duration is the wall-clock time of the build.
+	Duration time.Duration `json:"duration"`
+
+	// WheelArch is the architecture from the wheel's platform tag, "any"
+	// for a pure-Python wheel or empty if no wheel was built.
+	WheelArch string `json:"wheel_arch,omitempty"`
+
+	// WheelDigest is the sha256 of the built wheel, as "sha256:<hex>".
+	WheelDigest string `json:"wheel_digest,omitempty"`
+
+	// LogPath is where the build log was stored (e.g., a GCS path).
+	LogPath string `json:"log_path,omitempty"`
+}
+
+// NewRecord creates a record from a build result built on the host.
+func NewRecord(pkg string, r builder.BuildResult) (Record, error) {
+	rec := Record{
+		Cell: Cell{
+			Package: pkg,
+			Version: r.Version,
+			Python:  r.Python,
+			Arch:    HostArch(),
+		},
+		Time:          time.Now().UTC(),
+		ConfigHash:    r.CellHash,
+		Success:       r.Success,
+		FailureClass:  r.FailureClass,
+		NotApplicable: r.NotApplicable,
+		Backend:       r.Backend,
+		Duration:      r.Duration,
+		WheelArch:     wheelArch(r.WheelPath),
+	}
+
+	if r.WheelPath != "" {
+		digest, err := fileDigest(r.WheelPath)
+		if err != nil {
+			return Record{}, err
+		}
+		rec.WheelDigest = digest
+	}
+	return rec, nil
+}
+
+// HostArch returns the wheel architecture name of the host (e.g., "aarch64").
+func HostArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	case "386":
+		return "i686"
+	default:
+		return runtime.GOARCH
+	}
+}
+
+// wheelArch returns the architecture from a wheel's platform tag, or "".
+func wheelArch(path string) string {
+	if path == "" {
+		return ""
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".whl")
+	parts := strings.Split(name, "-")
+	if len(parts) < 5 {
+		return ""
+	}
+	platform := parts[len(parts)-1]
+	for _, arch := range []string{"x86_64", "aarch64", "i686", "ppc64le", "s390x", "armv7l"} {
+		if strings.HasSuffix(platform, "_"+arch) {
+			return arch
+		}
+	}
+	if platform == "any" {
+		return "any"
+	}
+	return ""
+}
+
+// fileDigest returns the sha256 digest of a file as "sha256:<hex>".
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("hashing wheel: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing wheel: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
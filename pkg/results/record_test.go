@@ -0,0 +1,73 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/builder"
+)
+
+func TestWheelArch(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/dist/numpy-1.26.0-cp312-cp312-linux_aarch64.whl", "aarch64"},
+		{"numpy-1.26.0-cp312-cp312-manylinux_2_17_x86_64.whl", "x86_64"},
+		{"pkg-1.0-1-cp311-cp311-musllinux_1_1_aarch64.whl", "aarch64"},
+		{"pkg-1.0-py3-none-any.whl", "any"},
+		{"not-a-wheel.whl", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := wheelArch(tt.path); got != tt.want {
+				t.Errorf("wheelArch(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	wheel := filepath.Join(t.TempDir(), "pkg-1.0-cp312-cp312-linux_aarch64.whl")
+	if err := os.WriteFile(wheel, []byte("wheel"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := NewRecord("pkg", builder.BuildResult{
+		Version:   "1.0",
+		Python:    "3.12",
+		WheelPath: wheel,
+		Success:   true,
+		Duration:  time.Minute,
+		Backend:   "maturin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCell := Cell{Package: "pkg", Version: "1.0", Python: "3.12", Arch: HostArch()}
+	if rec.Cell != wantCell {
+		t.Errorf("Cell = %+v, want %+v", rec.Cell, wantCell)
+	}
+	if rec.WheelArch != "aarch64" {
+		t.Errorf("WheelArch = %q, want aarch64", rec.WheelArch)
+	}
+	if want := "sha256:ba59926159d2aa256eb8739b8da7e2b574b960e1202c6d624cbe981cef996c91"; rec.WheelDigest != want {
+		t.Errorf("WheelDigest = %q, want %q", rec.WheelDigest, want)
+	}
+	if !rec.Success || rec.Duration != time.Minute || rec.Backend != "maturin" {
+		t.Errorf("Record = %+v", rec)
+	}
+
+	rec, err = NewRecord("pkg", builder.BuildResult{Version: "1.0", Python: "3.12", FailureClass: builder.FailureBuild})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Arch != HostArch() || rec.WheelArch != "" || rec.WheelDigest != "" || rec.FailureClass != builder.FailureBuild {
+		t.Errorf("failed Record = %+v", rec)
+	}
+}
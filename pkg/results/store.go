@@ -0,0 +1,237 @@
+package results
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultFlakyWindow is the number of recent builds Flaky inspects per cell.
+const DefaultFlakyWindow = 5
+
+// Store is an append-only results database stored as JSON lines. Each
+// record is written with a single O_APPEND write, so concurrent agents on
+// the same host can share a store without locking.
+type Store struct {
+	// Path is the database file.
+	Path string
+}
+
+// Open returns a store at path, creating its directory if needed.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating results directory: %w", err)
+	}
+	return &Store{Path: path}, nil
+}
+
+// Add appends records to the store.
+func (s *Store) Add(records ...Record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("encoding result: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening results: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing results: %w", err)
+	}
+	return f.Close()
+}
+
+// All returns every record in the order it was added. A truncated final
+// line, left by a crash mid-write, is ignored.
+func (s *Store) All() ([]Record, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening results: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	var pending error
+	for scanner.Scan() {
+		line++
+		if pending != nil {
+			return nil, pending
+		}
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// Only fatal if it isn't the last line
+			pending = fmt.Errorf("results line %d: %w", line, err)
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	return records, nil
+}
+
+// History returns the records for a cell, oldest first.
+func (s *Store) History(cell Cell) ([]Record, error) {
+	records, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	var history []Record
+	for _, r := range records {
+		if r.Cell == cell {
+			history = append(history, r)
+		}
+	}
+	return history, nil
+}
+
+// LastKnownGood returns the most recent successful build of a cell, or nil.
+func (s *Store) LastKnownGood(cell Cell) (*Record, error) {
+	history, err := s.History(cell)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Success {
+			return &history[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// NewlyBroken returns the latest record of each cell of pkg (or all
+// packages if empty) whose latest build failed after the previous build
+// succeeded.
+func (s *Store) NewlyBroken(pkg string) ([]Record, error) {
+	byCell, err := s.byCell(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var broken []Record
+	for _, history := range byCell {
+		n := len(history)
+		if n >= 2 && !history[n-1].Success && history[n-2].Success {
+			broken = append(broken, history[n-1])
+		}
+	}
+	sortRecords(broken)
+	return broken, nil
+}
+
+// FlakyCell is a cell that both passed and failed with the same config.
+type FlakyCell struct {
+	Cell
+
+	// ConfigHash is the configuration the outcomes differed under.
+	ConfigHash string
+
+	// Successes and Failures count the outcomes in the window.
+	Successes int
+	Failures  int
+}
+
+// Flaky returns the cells of pkg (or all packages if empty) whose last
+// window builds (default: DefaultFlakyWindow) include both a success and a
+// failure with the same config hash.
+func (s *Store) Flaky(pkg string, window int) ([]FlakyCell, error) {
+	if window <= 0 {
+		window = DefaultFlakyWindow
+	}
+	byCell, err := s.byCell(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var flaky []FlakyCell
+	for cell, history := range byCell {
+		if len(history) > window {
+			history = history[len(history)-window:]
+		}
+
+		counts := make(map[string]*FlakyCell)
+		var order []string
+		for _, r := range history {
+			c := counts[r.ConfigHash]
+			if c == nil {
+				c = &FlakyCell{Cell: cell, ConfigHash: r.ConfigHash}
+				counts[r.ConfigHash] = c
+				order = append(order, r.ConfigHash)
+			}
+			if r.Success {
+				c.Successes++
+			} else {
+				c.Failures++
+			}
+		}
+		for _, hash := range order {
+			if c := counts[hash]; c.Successes > 0 && c.Failures > 0 {
+				flaky = append(flaky, *c)
+			}
+		}
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		return cellLess(flaky[i].Cell, flaky[j].Cell)
+	})
+	return flaky, nil
+}
+
+// byCell groups records by cell, oldest first, keeping only pkg if set.
+func (s *Store) byCell(pkg string) (map[Cell][]Record, error) {
+	records, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	byCell := make(map[Cell][]Record)
+	for _, r := range records {
+		// Cells that weren't built say nothing about breakage or flakiness
+		if r.NotApplicable {
+			continue
+		}
+		if pkg == "" || r.Package == pkg {
+			byCell[r.Cell] = append(byCell[r.Cell], r)
+		}
+	}
+	return byCell, nil
+}
+
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		return cellLess(records[i].Cell, records[j].Cell)
+	})
+}
+
+func cellLess(a, b Cell) bool {
+	if a.Package != b.Package {
+		return a.Package < b.Package
+	}
+	if a.Version != b.Version {
+		return a.Version < b.Version
+	}
+	if a.Python != b.Python {
+		return a.Python < b.Python
+	}
+	return a.Arch < b.Arch
+}
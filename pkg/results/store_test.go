@@ -0,0 +1,240 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/superwheelie/pkg/builder"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "results", "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func record(pkg, version, python, hash string, success bool) Record {
+	r := Record{
+		Cell:       Cell{Package: pkg, Version: version, Python: python, Arch: "aarch64"},
+		Time:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ConfigHash: hash,
+		Success:    success,
+	}
+	if !success {
+		r.FailureClass = "build"
+	}
+	return r
+}
+
+func TestStoreAddAll(t *testing.T) {
+	s := newTestStore(t)
+
+	records, err := s.All()
+	if err != nil || records != nil {
+		t.Fatalf("All() on empty store = %v, %v", records, err)
+	}
+
+	want := []Record{
+		record("numpy", "1.26.0", "3.12", "abc", true),
+		record("numpy", "1.26.0", "3.11", "abc", false),
+	}
+	if err := s.Add(want[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(want[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("All() returned %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStoreTruncatedLine(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.Add(record("numpy", "1.0", "3.12", "abc", true)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"package":"numpy","vers`)
+	f.Close()
+
+	records, err := s.All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("All() returned %d records, want 1", len(records))
+	}
+
+	// Corruption before the last line is an error
+	if err := os.WriteFile(s.Path, []byte("garbage\n{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.All(); err == nil {
+		t.Error("All() expected error for corrupt line")
+	}
+}
+
+func TestLastKnownGood(t *testing.T) {
+	s := newTestStore(t)
+	good := record("numpy", "1.0", "3.12", "v1", true)
+	good.WheelDigest = "sha256:1"
+	if err := s.Add(
+		record("numpy", "1.0", "3.12", "v0", true),
+		good,
+		record("numpy", "1.0", "3.12", "v2", false),
+		record("numpy", "1.0", "3.11", "v2", false),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.LastKnownGood(good.Cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.ConfigHash != "v1" || got.WheelDigest != "sha256:1" {
+		t.Errorf("LastKnownGood() = %+v, want config v1", got)
+	}
+
+	got, err = s.LastKnownGood(Cell{Package: "numpy", Version: "1.0", Python: "3.11", Arch: "aarch64"})
+	if err != nil || got != nil {
+		t.Errorf("LastKnownGood() for never-built cell = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestNewlyBroken(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.Add(
+		// Broken by the latest build
+		record("numpy", "1.0", "3.12", "a", true),
+		record("numpy", "1.0", "3.12", "b", false),
+		// Always failed
+		record("numpy", "1.0", "3.11", "a", false),
+		record("numpy", "1.0", "3.11", "a", false),
+		// Fixed again
+		record("numpy", "2.0", "3.12", "a", true),
+		record("numpy", "2.0", "3.12", "a", false),
+		record("numpy", "2.0", "3.12", "a", true),
+		// Other package
+		record("scipy", "1.0", "3.12", "a", true),
+		record("scipy", "1.0", "3.12", "a", false),
+		// Not applicable after a success isn't a breakage
+		record("numpy", "3.0", "3.10", "a", true),
+		notApplicable("numpy", "3.0", "3.10"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	broken, err := s.NewlyBroken("numpy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 1 || broken[0].Version != "1.0" || broken[0].Python != "3.12" || broken[0].ConfigHash != "b" {
+		t.Errorf("NewlyBroken(numpy) = %+v", broken)
+	}
+
+	broken, err = s.NewlyBroken("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 2 || broken[1].Package != "scipy" {
+		t.Errorf("NewlyBroken(\"\") = %+v", broken)
+	}
+}
+
+func TestNewlyBrokenPureWheel(t *testing.T) {
+	wheel := filepath.Join(t.TempDir(), "pkg-1.0-py3-none-any.whl")
+	if err := os.WriteFile(wheel, []byte("wheel"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := NewRecord("pkg", builder.BuildResult{Version: "1.0", Python: "3.12", WheelPath: wheel, Success: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := NewRecord("pkg", builder.BuildResult{Version: "1.0", Python: "3.12", FailureClass: builder.FailureBuild})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestStore(t)
+	if err := s.Add(ok, failed); err != nil {
+		t.Fatal(err)
+	}
+	broken, err := s.NewlyBroken("pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 1 || broken[0].Cell != failed.Cell {
+		t.Errorf("NewlyBroken(pkg) = %+v, want the failed pure-Python cell", broken)
+	}
+}
+
+func notApplicable(pkg, version, python string) Record {
+	r := record(pkg, version, python, "a", false)
+	r.NotApplicable = true
+	return r
+}
+
+func TestFlaky(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.Add(
+		// Same config, both outcomes: flaky
+		record("numpy", "1.0", "3.12", "a", true),
+		record("numpy", "1.0", "3.12", "a", false),
+		record("numpy", "1.0", "3.12", "a", true),
+		// Outcome changed with the config: not flaky
+		record("numpy", "1.0", "3.11", "a", false),
+		record("numpy", "1.0", "3.11", "b", true),
+		// Flaky long ago, stable within the window
+		record("numpy", "2.0", "3.12", "a", false),
+		record("numpy", "2.0", "3.12", "a", true),
+		record("numpy", "2.0", "3.12", "a", true),
+		record("numpy", "2.0", "3.12", "a", true),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky, err := s.Flaky("numpy", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flaky) != 1 {
+		t.Fatalf("Flaky() = %+v, want 1 cell", flaky)
+	}
+	want := FlakyCell{
+		Cell:       Cell{Package: "numpy", Version: "1.0", Python: "3.12", Arch: "aarch64"},
+		ConfigHash: "a",
+		Successes:  2,
+		Failures:   1,
+	}
+	if flaky[0] != want {
+		t.Errorf("Flaky()[0] = %+v, want %+v", flaky[0], want)
+	}
+
+	flaky, err = s.Flaky("numpy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flaky) != 2 {
+		t.Errorf("Flaky() with default window = %+v, want 2 cells", flaky)
+	}
+}
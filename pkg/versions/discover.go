@@ -0,0 +1,212 @@
+// Package versions discovers upstream releases from git tags and orders
+// them by PEP 440.
+package versions
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// DefaultTagPatterns are tried in order when a config has no tag_pattern.
+var DefaultTagPatterns = []string{"v{version}", "{version}"}
+
+// Tag is a tag on the remote.
+type Tag struct {
+	Name string
+
+	// Commit is the commit the tag points to (peeled for annotated tags).
+	Commit string
+}
+
+// Release is a tag that maps to a version.
+type Release struct {
+	Tag     string
+	Version Version
+}
+
+// ListTags lists the tags of a remote repository with git ls-remote.
+func ListTags(ctx context.Context, remote string) ([]Tag, error) {
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", "--tags", remote).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("listing tags of %s: %w\n%s", remote, err, ee.Stderr)
+		}
+		return nil, fmt.Errorf("listing tags of %s: %w", remote, err)
+	}
+
+	var tags []Tag
+	for _, ref := range ParseRefs(string(out)) {
+		if name, ok := strings.CutPrefix(ref.Name, "refs/tags/"); ok {
+			tags = append(tags, Tag{Name: name, Commit: ref.Commit})
+		}
+	}
+	return tags, nil
+}
+
+// Ref is a ref listed by git ls-remote.
+type Ref struct {
+	// Name is the full ref name (e.g., "refs/tags/v1.0").
+	Name string
+
+	// Commit is the commit the ref points to (peeled for annotated tags).
+	Commit string
+}
+
+// ParseRefs parses git ls-remote output in order, folding the peeled
+// "^{}" entry of an annotated tag into the tag.
+func ParseRefs(out string) []Ref {
+	var refs []Ref
+	index := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		sha, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		// The peeled entry of an annotated tag is the commit
+		name, peeled := strings.CutSuffix(name, "^{}")
+		if i, ok := index[name]; ok {
+			if peeled {
+				refs[i].Commit = sha
+			}
+			continue
+		}
+		index[name] = len(refs)
+		refs = append(refs, Ref{Name: name, Commit: sha})
+	}
+	return refs
+}
+
+// MatchTag extracts the version from a tag using pattern, where
+// "{version}" stands for the version. It returns false if the tag doesn't
+// match or the version isn't valid PEP 440.
+func MatchTag(tag, pattern string) (Version, bool) {
+	re, err := patternRegexp(pattern)
+	if err != nil {
+		return Version{}, false
+	}
+	m := re.FindStringSubmatch(tag)
+	if m == nil {
+		return Version{}, false
+	}
+	// Patterns own the prefix; "v{version}" shouldn't also match "vv1.0"
+	if strings.HasPrefix(strings.ToLower(m[1]), "v") {
+		return Version{}, false
+	}
+	v, err := Parse(m[1])
+	if err != nil {
+		return Version{}, false
+	}
+	return v, true
+}
+
+// patternRegexp compiles a tag pattern.
+func patternRegexp(pattern string) (*regexp.Regexp, error) {
+	prefix, suffix, ok := strings.Cut(pattern, "{version}")
+	if !ok || strings.Contains(suffix, "{version}") {
+		return nil, fmt.Errorf("tag pattern %q must contain {version} exactly once", pattern)
+	}
+	return regexp.Compile("^" + regexp.QuoteMeta(prefix) + "(.+)" + regexp.QuoteMeta(suffix) + "$")
+}
+
+// Releases maps tags to releases with the config's tag pattern, sorted
+// newest first. Pre-releases are dropped unless the config opts in. If
+// several tags map to the same version, the first pattern (then tag name)
+// wins.
+func Releases(tags []Tag, cfg *config.Config) []Release {
+	patterns := DefaultTagPatterns
+	if cfg.TagPattern != "" {
+		patterns = []string{cfg.TagPattern}
+	}
+
+	sorted := make([]Tag, len(tags))
+	copy(sorted, tags)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var releases []Release
+	for _, pattern := range patterns {
+		for _, tag := range sorted {
+			v, ok := MatchTag(tag.Name, pattern)
+			if !ok || (v.IsPrerelease() && !cfg.Prereleases) {
+				continue
+			}
+			if hasRelease(releases, v) {
+				continue
+			}
+			releases = append(releases, Release{Tag: tag.Name, Version: v})
+		}
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Version.Compare(releases[j].Version) > 0
+	})
+	return releases
+}
+
+// hasRelease reports whether releases has a version equal to v in PEP 440
+// terms (so "1.0" and "1.0.0" are the same release).
+func hasRelease(releases []Release, v Version) bool {
+	for _, r := range releases {
+		if r.Version.Compare(v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Upstream lists the releases of the config's repository, newest first.
+func Upstream(ctx context.Context, cfg *config.Config) ([]Release, error) {
+	tags, err := ListTags(ctx, cfg.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return Releases(tags, cfg), nil
+}
+
+// New returns the releases among the newest VersionCount that aren't
+// already in the config's versions, newest first.
+func New(releases []Release, cfg *config.Config) []config.Version {
+	count := cfg.VersionCount
+	if count <= 0 {
+		count = config.DefaultVersionCount
+	}
+
+	var versions []config.Version
+	for i, r := range releases {
+		if i >= count {
+			break
+		}
+		if Contains(cfg.Versions, r.Version) {
+			continue
+		}
+		versions = append(versions, config.Version{Tag: r.Tag, Version: r.Version.String()})
+	}
+	return versions
+}
+
+// Contains reports whether versions has an entry equal to v in PEP 440
+// terms (so "2.0" matches "2.0.0").
+func Contains(versions []config.Version, v Version) bool {
+	for _, cv := range versions {
+		parsed, err := Parse(cv.Version)
+		if err == nil && parsed.Compare(v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover lists upstream tags and returns the new versions to add to the
+// config.
+func Discover(ctx context.Context, cfg *config.Config) ([]config.Version, error) {
+	releases, err := Upstream(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(releases, cfg), nil
+}
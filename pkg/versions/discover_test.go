@@ -0,0 +1,204 @@
+package versions
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// newTaggedRepo creates a repository with a commit per tag. Tags starting
+// with "annotated:" are created as annotated tags.
+func newTaggedRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "repo")
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	for _, tag := range tags {
+		run("commit", "-q", "--allow-empty", "-m", tag)
+		if name, ok := cutAnnotated(tag); ok {
+			run("tag", "-a", "-m", name, name)
+		} else {
+			run("tag", tag)
+		}
+	}
+	return dir
+}
+
+func cutAnnotated(tag string) (string, bool) {
+	const prefix = "annotated:"
+	if len(tag) > len(prefix) && tag[:len(prefix)] == prefix {
+		return tag[len(prefix):], true
+	}
+	return "", false
+}
+
+func TestListTags(t *testing.T) {
+	repo := newTaggedRepo(t, "v1.0.0", "annotated:v1.1.0")
+
+	tags, err := ListTags(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("ListTags() = %v, want 2 tags", tags)
+	}
+
+	for _, tag := range tags {
+		out, err := exec.Command("git", "-C", repo, "rev-parse", tag.Name+"^{commit}").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := string(out[:40]); tag.Commit != want {
+			t.Errorf("tag %s commit = %s, want %s", tag.Name, tag.Commit, want)
+		}
+	}
+}
+
+func TestMatchTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		pattern string
+		want    string
+		ok      bool
+	}{
+		{"v2.1.0", "v{version}", "2.1.0", true},
+		{"2.1.0", "{version}", "2.1.0", true},
+		{"v2.1.0", "{version}", "", false},
+		{"vv2.1.0", "v{version}", "", false},
+		{"release-2.1.0", "release-{version}", "2.1.0", true},
+		{"numpy-1.26.4", "numpy-{version}", "1.26.4", true},
+		{"scipy-1.0", "numpy-{version}", "", false},
+		{"pkgs/foo/v1.2", "pkgs/foo/v{version}", "1.2", true},
+		{"v1.0-final", "v{version}-final", "1.0", true},
+		{"v1.0rc1", "v{version}", "1.0rc1", true},
+		{"nightly", "{version}", "", false},
+		{"v1.0", "v", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+"/"+tt.pattern, func(t *testing.T) {
+			v, ok := MatchTag(tt.tag, tt.pattern)
+			if ok != tt.ok {
+				t.Fatalf("MatchTag() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && v.String() != tt.want {
+				t.Errorf("MatchTag() = %q, want %q", v.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	repo := newTaggedRepo(t,
+		"v1.0.0", "v1.1.0", "annotated:v1.2.0", "v2.0.0rc1", "1.3.0",
+		"v1.3.0", "v2.0.0", "nightly", "docs-2024",
+	)
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []config.Version
+	}{
+		{
+			name: "default patterns newest first",
+			cfg:  config.Config{VersionCount: 10},
+			want: []config.Version{
+				{Tag: "v2.0.0", Version: "2.0.0"},
+				{Tag: "v1.3.0", Version: "1.3.0"},
+				{Tag: "v1.2.0", Version: "1.2.0"},
+				{Tag: "v1.1.0", Version: "1.1.0"},
+				{Tag: "v1.0.0", Version: "1.0.0"},
+			},
+		},
+		{
+			name: "window of version_count",
+			cfg:  config.Config{VersionCount: 2},
+			want: []config.Version{
+				{Tag: "v2.0.0", Version: "2.0.0"},
+				{Tag: "v1.3.0", Version: "1.3.0"},
+			},
+		},
+		{
+			name: "existing versions are skipped",
+			cfg: config.Config{VersionCount: 3, Versions: []config.Version{
+				{Tag: "v2.0.0", Version: "2.0"},
+				{Tag: "v1.0.0", Version: "1.0.0"},
+			}},
+			want: []config.Version{
+				{Tag: "v1.3.0", Version: "1.3.0"},
+				{Tag: "v1.2.0", Version: "1.2.0"},
+			},
+		},
+		{
+			name: "prereleases",
+			cfg:  config.Config{VersionCount: 2, Prereleases: true},
+			want: []config.Version{
+				{Tag: "v2.0.0", Version: "2.0.0"},
+				{Tag: "v2.0.0rc1", Version: "2.0.0rc1"},
+			},
+		},
+		{
+			name: "explicit pattern",
+			cfg:  config.Config{VersionCount: 10, TagPattern: "{version}"},
+			want: []config.Version{
+				{Tag: "1.3.0", Version: "1.3.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Repo = repo
+			got, err := Discover(context.Background(), &cfg)
+			if err != nil {
+				t.Fatalf("Discover() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleasesDedupe(t *testing.T) {
+	tags := []Tag{{Name: "v1.0.0"}, {Name: "v1.0"}, {Name: "1.0.0.0"}, {Name: "v1.1"}}
+	got := Releases(tags, &config.Config{})
+	var names []string
+	for _, r := range got {
+		names = append(names, r.Tag)
+	}
+	// "1.0", "1.0.0" and "1.0.0.0" are one release; the first pattern, then
+	// tag name, wins
+	if want := []string{"v1.1", "v1.0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Releases() tags = %v, want %v", names, want)
+	}
+}
+
+func TestParseRefs(t *testing.T) {
+	out := "aaa\tHEAD\n" +
+		"bbb\trefs/heads/main\n" +
+		"ccc\trefs/tags/v1.0\n" +
+		"ddd\trefs/tags/v1.0^{}\n" +
+		"eee\trefs/tags/v1.1\n"
+	want := []Ref{
+		{Name: "HEAD", Commit: "aaa"},
+		{Name: "refs/heads/main", Commit: "bbb"},
+		{Name: "refs/tags/v1.0", Commit: "ddd"},
+		{Name: "refs/tags/v1.1", Commit: "eee"},
+	}
+	if got := ParseRefs(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRefs() = %v, want %v", got, want)
+	}
+}
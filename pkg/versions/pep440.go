@@ -0,0 +1,242 @@
+package versions
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pep440Re matches the permissive PEP 440 version syntax that
+// normalization accepts (see "Appendix B: Parsing version strings").
+var pep440Re = regexp.MustCompile(`(?i)^\s*v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?P<pre>[-_.]?(?P<pre_l>alpha|beta|preview|pre|rc|a|b|c)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?P<post>-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?P<dev>[-_.]?dev[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?\s*$`)
+
+// Version is a parsed PEP 440 version.
+type Version struct {
+	Epoch   int
+	Release []int
+
+	// Pre is the pre-release phase ("a", "b" or "rc"), or "" if none.
+	Pre    string
+	PreNum int
+
+	// Post is the post-release number, or -1 if none.
+	Post int
+
+	// Dev is the dev-release number, or -1 if none.
+	Dev int
+
+	// Local is the normalized local version label, or "" if none.
+	Local string
+}
+
+// Parse parses a version string, accepting the spellings PEP 440
+// normalizes (e.g., "v1.0", "1.0-RC1", "1.0.post-2").
+func Parse(s string) (Version, error) {
+	m := pep440Re.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid PEP 440 version %q", s)
+	}
+	group := func(name string) string { return m[pep440Re.SubexpIndex(name)] }
+	num := func(s string) int {
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			// Only digits match, so this is an overflow
+			return math.MaxInt
+		}
+		return n
+	}
+
+	v := Version{Epoch: num(group("epoch")), Post: -1, Dev: -1}
+	for _, part := range strings.Split(group("release"), ".") {
+		v.Release = append(v.Release, num(part))
+	}
+
+	if group("pre") != "" {
+		switch strings.ToLower(group("pre_l")) {
+		case "a", "alpha":
+			v.Pre = "a"
+		case "b", "beta":
+			v.Pre = "b"
+		default:
+			v.Pre = "rc"
+		}
+		v.PreNum = num(group("pre_n"))
+	}
+	if group("post") != "" {
+		v.Post = num(group("post_n1") + group("post_n2"))
+	}
+	if group("dev") != "" {
+		v.Dev = num(group("dev_n"))
+	}
+	if local := group("local"); local != "" {
+		v.Local = strings.ToLower(strings.NewReplacer("-", ".", "_", ".").Replace(local))
+	}
+	return v, nil
+}
+
+// Normalize returns the normalized form of a version string.
+func Normalize(s string) (string, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// String returns the normalized version.
+func (v Version) String() string {
+	var sb strings.Builder
+	if v.Epoch != 0 {
+		fmt.Fprintf(&sb, "%d!", v.Epoch)
+	}
+	for i, n := range v.Release {
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(strconv.Itoa(n))
+	}
+	if v.Pre != "" {
+		fmt.Fprintf(&sb, "%s%d", v.Pre, v.PreNum)
+	}
+	if v.Post >= 0 {
+		fmt.Fprintf(&sb, ".post%d", v.Post)
+	}
+	if v.Dev >= 0 {
+		fmt.Fprintf(&sb, ".dev%d", v.Dev)
+	}
+	if v.Local != "" {
+		sb.WriteString("+" + v.Local)
+	}
+	return sb.String()
+}
+
+// IsPrerelease reports whether v is a pre-release or dev release.
+func (v Version) IsPrerelease() bool {
+	return v.Pre != "" || v.Dev >= 0
+}
+
+// Compare returns -1, 0 or 1 as v sorts before, equal to or after o in
+// PEP 440 order.
+func (v Version) Compare(o Version) int {
+	if c := cmpInt(v.Epoch, o.Epoch); c != 0 {
+		return c
+	}
+	for i := 0; i < len(v.Release) || i < len(o.Release); i++ {
+		if c := cmpInt(part(v.Release, i), part(o.Release, i)); c != 0 {
+			return c
+		}
+	}
+	if c := cmpInt(v.preKey(), o.preKey()); c != 0 {
+		return c
+	}
+	if v.Pre != "" && v.Pre == o.Pre {
+		if c := cmpInt(v.PreNum, o.PreNum); c != 0 {
+			return c
+		}
+	}
+	if c := cmpInt(v.Post, o.Post); c != 0 {
+		return c
+	}
+	if c := cmpInt(v.devKey(), o.devKey()); c != 0 {
+		return c
+	}
+	return compareLocal(v.Local, o.Local)
+}
+
+// compareLocal orders local version labels segment by segment: numeric
+// segments compare as numbers and sort after alphanumeric ones, and a
+// label that is a prefix of another sorts first. No label sorts before any.
+func compareLocal(a, b string) int {
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmpInt(an, bn)
+		case aErr == nil:
+			c = 1
+		case bErr == nil:
+			c = -1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(as), len(bs))
+}
+
+// preKey orders the pre-release phase: a dev release of a final version
+// sorts before its pre-releases, and a final version after them.
+func (v Version) preKey() int {
+	switch {
+	case v.Pre == "a":
+		return 1
+	case v.Pre == "b":
+		return 2
+	case v.Pre == "rc":
+		return 3
+	case v.Dev >= 0 && v.Post < 0:
+		return 0
+	default:
+		return 4
+	}
+}
+
+// devKey sorts dev releases before the version they precede.
+func (v Version) devKey() int {
+	if v.Dev < 0 {
+		return math.MaxInt
+	}
+	return v.Dev
+}
+
+// Compare compares two version strings in PEP 440 order. Unparseable
+// versions sort before valid ones and compare lexically among themselves.
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+func part(release []int, i int) int {
+	if i < len(release) {
+		return release[i]
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
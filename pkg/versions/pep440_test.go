@@ -0,0 +1,110 @@
+package versions
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1.0", "1.0", false},
+		{"v2.1.0", "2.1.0", false},
+		{"1.0-RC1", "1.0rc1", false},
+		{"1.0.alpha.2", "1.0a2", false},
+		{"1.0beta", "1.0b0", false},
+		{"1.0c1", "1.0rc1", false},
+		{"1.0-1", "1.0.post1", false},
+		{"1.0.post-2", "1.0.post2", false},
+		{"1.0rev3", "1.0.post3", false},
+		{"1.0-dev", "1.0.dev0", false},
+		{"1!2.0", "1!2.0", false},
+		{"1.0+Ubuntu-1", "1.0+ubuntu.1", false},
+		{"01.002", "1.2", false},
+		{"release-1.0", "", true},
+		{"1.0.x", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// In ascending PEP 440 order
+	ordered := []string{
+		"1.0.dev0",
+		"1.0a1.dev1",
+		"1.0a1",
+		"1.0a1.post1",
+		"1.0a2",
+		"1.0b1",
+		"1.0rc1",
+		"1.0",
+		"1.0+abc",
+		"1.0+abc.5",
+		"1.0+local",
+		"1.0+2",
+		"1.0+10",
+		"1.0+10.a",
+		"1.0+10.2",
+		"1.0.post1.dev0",
+		"1.0.post1",
+		"1.0.1",
+		"1.10",
+		"2.0",
+		"1!0.1",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := cmpInt(i, j)
+			if got := Compare(ordered[i], ordered[j]); got != want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	if Compare("1.0", "1.0.0") != 0 {
+		t.Error("1.0 and 1.0.0 should compare equal")
+	}
+
+	shuffled := []string{"2.0", "1.0rc1", "1.10", "1.0", "1.9"}
+	sort.Slice(shuffled, func(i, j int) bool { return Compare(shuffled[i], shuffled[j]) < 0 })
+	want := []string{"1.0rc1", "1.0", "1.9", "1.10", "2.0"}
+	for i := range want {
+		if shuffled[i] != want[i] {
+			t.Fatalf("sorted = %v, want %v", shuffled, want)
+		}
+	}
+}
+
+func TestIsPrerelease(t *testing.T) {
+	tests := map[string]bool{
+		"1.0":            false,
+		"1.0.post1":      false,
+		"1.0rc1":         true,
+		"1.0b2":          true,
+		"1.0.dev3":       true,
+		"1.0.post1.dev1": true,
+	}
+	for in, want := range tests {
+		v, err := Parse(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := v.IsPrerelease(); got != want {
+			t.Errorf("IsPrerelease(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package versions
+
+import (
+	"sort"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+// Rotation describes the changes made by Rotate.
+type Rotation struct {
+	// Added are versions that entered the window (new upstream releases or
+	// retired versions brought back by a larger version_count).
+	Added []config.Version
+
+	// Retired are versions moved from versions to retired.
+	Retired []config.Version
+
+	// PrunedSkips are skips that no longer match any active version.
+	PrunedSkips []config.Skip
+
+	// PrunedOverrides are overrides that no longer match any active version.
+	PrunedOverrides []config.Override
+}
+
+// Changed reports whether the rotation changed anything.
+func (r *Rotation) Changed() bool {
+	return len(r.Added)+len(r.Retired)+len(r.PrunedSkips)+len(r.PrunedOverrides) > 0
+}
+
+// candidate is a version considered for the window.
+type candidate struct {
+	version config.Version
+	source  int
+}
+
+// Candidate sources, in order of precedence when versions are equal.
+const (
+	fromActive = iota
+	fromRetired
+	fromUpstream
+)
+
+// Rotate keeps the newest VersionCount versions, by PEP 440 order, among
+// the config's versions, its retired versions and the upstream releases.
+// Versions that fall out of the window move to retired; upstream releases
+// outside the window are ignored. Skips and overrides that no longer match
+// any active version are removed. skips may be nil.
+func Rotate(cfg *config.Config, skips *config.Skips, releases []Release) *Rotation {
+	count := cfg.VersionCount
+	if count <= 0 {
+		count = config.DefaultVersionCount
+	}
+
+	var candidates []candidate
+	add := func(v config.Version, source int) {
+		for _, c := range candidates {
+			if Compare(c.version.Version, v.Version) == 0 {
+				return
+			}
+		}
+		candidates = append(candidates, candidate{version: v, source: source})
+	}
+	for _, v := range cfg.Versions {
+		add(v, fromActive)
+	}
+	for _, v := range cfg.Retired {
+		add(v, fromRetired)
+	}
+	for _, r := range releases {
+		add(config.Version{Tag: r.Tag, Version: r.Version.String()}, fromUpstream)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return Compare(candidates[i].version.Version, candidates[j].version.Version) > 0
+	})
+
+	rot := &Rotation{}
+	var active, retired []config.Version
+	for i, c := range candidates {
+		switch {
+		case i < count:
+			active = append(active, c.version)
+			if c.source != fromActive {
+				rot.Added = append(rot.Added, c.version)
+			}
+		case c.source == fromActive:
+			retired = append(retired, c.version)
+			rot.Retired = append(rot.Retired, c.version)
+		case c.source == fromRetired:
+			retired = append(retired, c.version)
+		}
+	}
+	cfg.Versions = active
+	cfg.Retired = retired
+
+	var overrides []config.Override
+	for _, o := range cfg.Overrides {
+		if matchesAny(active, func(v string) bool {
+			ok, err := config.MatchesVersion(v, o.Match)
+			return err == nil && ok
+		}) {
+			overrides = append(overrides, o)
+		} else {
+			rot.PrunedOverrides = append(rot.PrunedOverrides, o)
+		}
+	}
+	cfg.Overrides = overrides
+
+	if skips != nil {
+		var kept []config.Skip
+		for _, s := range skips.Skips {
+			if matchesAny(active, s.MatchesVersion) {
+				kept = append(kept, s)
+			} else {
+				rot.PrunedSkips = append(rot.PrunedSkips, s)
+			}
+		}
+		skips.Skips = kept
+	}
+	return rot
+}
+
+// matchesAny reports whether match is true for any of versions.
+func matchesAny(versions []config.Version, match func(version string) bool) bool {
+	for _, v := range versions {
+		if match(v.Version) {
+			return true
+		}
+	}
+	return false
+}
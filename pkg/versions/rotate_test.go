@@ -0,0 +1,133 @@
+package versions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/superwheelie/pkg/config"
+)
+
+func releases(t *testing.T, versions ...string) []Release {
+	t.Helper()
+	var rs []Release
+	for _, s := range versions {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs = append(rs, Release{Tag: "v" + s, Version: v})
+	}
+	return rs
+}
+
+func versionList(vs []config.Version) []string {
+	var out []string
+	for _, v := range vs {
+		out = append(out, v.Version)
+	}
+	return out
+}
+
+func TestRotate(t *testing.T) {
+	cfg := &config.Config{
+		VersionCount: 3,
+		Versions: []config.Version{
+			{Tag: "v1.10.0", Version: "1.10.0", BuildRequires: []string{"setuptools==69.0.0"}},
+			{Tag: "v1.9.0", Version: "1.9.0"},
+			{Tag: "v1.8.0", Version: "1.8.0"},
+		},
+		Retired: []config.Version{
+			{Tag: "v1.7.0", Version: "1.7.0"},
+		},
+		Overrides: []config.Override{
+			{Match: ">=1.10", Env: map[string]string{"NEW": "1"}},
+			{Match: "<1.9", Env: map[string]string{"LEGACY": "1"}},
+		},
+	}
+	skips := &config.Skips{Skips: []config.Skip{
+		{Version: "1.10.0", Python: []string{"3.13"}, Reason: "still broken"},
+		{Version: "1.8.0", Python: []string{"3.13"}, Reason: "retired"},
+		{Version: "<1.9", Python: []string{"3.12"}, Reason: "distutils"},
+	}}
+
+	rot := Rotate(cfg, skips, releases(t, "2.0.0", "1.11.0", "1.10.0", "1.9.0", "1.8.0", "1.7.0", "1.0.0"))
+
+	if got, want := versionList(cfg.Versions), []string{"2.0.0", "1.11.0", "1.10.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions = %v, want %v", got, want)
+	}
+	if got := cfg.Versions[2].BuildRequires; len(got) != 1 {
+		t.Errorf("existing version lost its build_requires: %v", cfg.Versions[2])
+	}
+	if got, want := versionList(cfg.Retired), []string{"1.9.0", "1.8.0", "1.7.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Retired = %v, want %v", got, want)
+	}
+
+	if got, want := versionList(rot.Added), []string{"2.0.0", "1.11.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Added = %v, want %v", got, want)
+	}
+	if got, want := versionList(rot.Retired), []string{"1.9.0", "1.8.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rotation.Retired = %v, want %v", got, want)
+	}
+
+	if len(cfg.Overrides) != 1 || cfg.Overrides[0].Match != ">=1.10" {
+		t.Errorf("Overrides = %v, want only >=1.10", cfg.Overrides)
+	}
+	if len(rot.PrunedOverrides) != 1 || rot.PrunedOverrides[0].Match != "<1.9" {
+		t.Errorf("PrunedOverrides = %v", rot.PrunedOverrides)
+	}
+	if len(skips.Skips) != 1 || skips.Skips[0].Version != "1.10.0" {
+		t.Errorf("Skips = %v, want only 1.10.0", skips.Skips)
+	}
+	if len(rot.PrunedSkips) != 2 {
+		t.Errorf("PrunedSkips = %v, want 2", rot.PrunedSkips)
+	}
+	if !rot.Changed() {
+		t.Error("Changed() = false")
+	}
+
+	if err := config.ValidateConfig(&config.Config{Repo: "x", Versions: cfg.Versions, Retired: cfg.Retired}, ""); err != nil {
+		t.Errorf("rotated config is invalid: %v", err)
+	}
+}
+
+func TestRotateRestoresRetired(t *testing.T) {
+	cfg := &config.Config{
+		VersionCount: 3,
+		Versions:     []config.Version{{Tag: "v2.0", Version: "2.0"}, {Tag: "v1.9", Version: "1.9"}},
+		Retired:      []config.Version{{Tag: "v1.8", Version: "1.8", BuildRequires: []string{"cython<3"}}, {Tag: "v1.7", Version: "1.7"}},
+	}
+
+	rot := Rotate(cfg, nil, nil)
+
+	if got, want := versionList(cfg.Versions), []string{"2.0", "1.9", "1.8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions = %v, want %v", got, want)
+	}
+	if cfg.Versions[2].BuildRequires == nil {
+		t.Error("restored version lost its build_requires")
+	}
+	if got, want := versionList(cfg.Retired), []string{"1.7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Retired = %v, want %v", got, want)
+	}
+	if got, want := versionList(rot.Added), []string{"1.8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Added = %v, want %v", got, want)
+	}
+}
+
+func TestRotateUnchanged(t *testing.T) {
+	cfg := &config.Config{
+		VersionCount: 2,
+		Versions:     []config.Version{{Tag: "v2.0.0", Version: "2.0"}, {Tag: "v1.0.0", Version: "1.0"}},
+		Overrides:    []config.Override{{Match: ">=1.0"}},
+	}
+
+	rot := Rotate(cfg, &config.Skips{}, releases(t, "2.0.0", "1.0.0", "0.9.0"))
+	if rot.Changed() {
+		t.Errorf("Rotate() changed an up-to-date config: %+v", rot)
+	}
+	if got, want := versionList(cfg.Versions), []string{"2.0", "1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions = %v, want %v", got, want)
+	}
+	if cfg.Retired != nil {
+		t.Errorf("Retired = %v, want none", cfg.Retired)
+	}
+}
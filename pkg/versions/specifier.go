@@ -0,0 +1,102 @@
+package versions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Match reports whether version satisfies a PEP 440 specifier set such as
+// ">=3.8,!=3.9.*,<4". An empty specifier matches every version.
+func Match(version, spec string) (bool, error) {
+	v, err := Parse(version)
+	if err != nil {
+		return false, err
+	}
+	for _, clause := range strings.Split(spec, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		ok, err := matchClause(v, version, clause)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchClause matches a parsed version against a single specifier.
+func matchClause(v Version, raw, clause string) (bool, error) {
+	var op string
+	for _, prefix := range []string{"===", "~=", "==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(clause, prefix) {
+			op = prefix
+			break
+		}
+	}
+	if op == "" {
+		return false, fmt.Errorf("invalid specifier %q", clause)
+	}
+	target := strings.TrimSpace(clause[len(op):])
+
+	if op == "===" {
+		return raw == target, nil
+	}
+	if op == "==" || op == "!=" {
+		if prefix, ok := strings.CutSuffix(target, ".*"); ok {
+			p, err := Parse(prefix)
+			if err != nil {
+				return false, fmt.Errorf("invalid specifier %q: %w", clause, err)
+			}
+			return hasPrefix(v, p) == (op == "=="), nil
+		}
+	}
+
+	t, err := Parse(target)
+	if err != nil {
+		return false, fmt.Errorf("invalid specifier %q: %w", clause, err)
+	}
+	// Local versions only matter if the specifier names one
+	if t.Local == "" {
+		v.Local = ""
+	}
+	cmp := v.Compare(t)
+
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	default: // ~=
+		if len(t.Release) < 2 {
+			return false, fmt.Errorf("invalid specifier %q: ~= needs at least two release segments", clause)
+		}
+		prefix := Version{Epoch: t.Epoch, Release: t.Release[:len(t.Release)-1], Post: -1, Dev: -1}
+		return cmp >= 0 && hasPrefix(v, prefix), nil
+	}
+}
+
+// hasPrefix reports whether v's release starts with prefix's release, as
+// "==prefix.*" matches. Missing segments of v count as zero.
+func hasPrefix(v, prefix Version) bool {
+	if v.Epoch != prefix.Epoch {
+		return false
+	}
+	for i, n := range prefix.Release {
+		if part(v.Release, i) != n {
+			return false
+		}
+	}
+	return true
+}
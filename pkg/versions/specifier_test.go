@@ -0,0 +1,46 @@
+package versions
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		version string
+		spec    string
+		want    bool
+		wantErr bool
+	}{
+		{"3.12", "", true, false},
+		{"3.12", ">=3.8", true, false},
+		{"3.10", ">=3.11", false, false},
+		{"3.12", "<3.11", false, false},
+		{"3.10", ">=3.8, <3.11", true, false},
+		{"3.9", ">=3.8,!=3.9.*", false, false},
+		{"3.10", ">=3.8,!=3.9.*", true, false},
+		{"3.12", "==3.*", true, false},
+		{"4.0", "==3.*", false, false},
+		{"3.12", "~=3.9", true, false},
+		{"4.0", "~=3.9", false, false},
+		{"3.11.2", "~=3.11.1", true, false},
+		{"3.12.0", "~=3.11.1", false, false},
+		{"3.12", "==3.12.0", true, false},
+		{"3.12", ">3.11", true, false},
+		{"3.11", "<=3.11", true, false},
+		{"1.0+local", "==1.0", true, false},
+		{"1.0", "===1.0", true, false},
+		{"1.0.0", "===1.0", false, false},
+		{"3.12", "3.12", false, true},
+		{"3.12", "~=3", false, true},
+		{"3.12", ">=x", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.spec, func(t *testing.T) {
+			got, err := Match(tt.version, tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Match() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.version, tt.spec, got, tt.want)
+			}
+		})
+	}
+}